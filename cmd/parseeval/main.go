@@ -0,0 +1,306 @@
+// Command parseeval scores a parser backend and prompt version against a
+// golden corpus of transcripts. It reports per-field accuracy, whether the
+// right number of entries came back, and schema validity.
+//
+//	go run ./cmd/parseeval -backend offline
+//	go run ./cmd/parseeval -backend openai -prompt v2 -record
+//	go run ./cmd/parseeval -backend replay -prompt v2
+//
+// Replay files hold live replies and are not checked in: run with -record
+// once per prompt version (it needs an API key), then replay as often as
+// needed. A recording is tied to the prompt's content, so editing a prompt
+// file means recording it again.
+//
+// Each corpus line is {"id", "transcript", "tz"?, "categories"?, "expected":
+// [{field: value}, ...]} with one object per expected entry, in order. Only
+// the fields listed are scored. Dates may be written as "today", "today-1",
+// etc., resolved against the day the reply was produced.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/xeipuuv/gojsonschema"
+
+	"finance-parser-go/internal/ai"
+	"finance-parser-go/internal/config"
+	"finance-parser-go/internal/models"
+	"finance-parser-go/internal/numwords"
+)
+
+type goldenCase struct {
+	ID         string           `json:"id"`
+	Transcript string           `json:"transcript"`
+	TZ         string           `json:"tz"`
+	Categories []string         `json:"categories"`
+	Expected   []map[string]any `json:"expected"`
+}
+
+type tally struct{ correct, total int }
+
+func (t tally) String() string {
+	if t.total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(t.correct)/float64(t.total))
+}
+
+func main() {
+	_ = godotenv.Load(".env")
+	cfg := config.Load()
+
+	corpusPath := flag.String("corpus", "cmd/parseeval/testdata/golden.jsonl", "golden corpus (JSON lines)")
+	backend := flag.String("backend", "offline", "parser backend: openai, compatible, fake, offline or replay")
+	prompt := flag.String("prompt", cfg.PromptVersion, "prompt version ("+strings.Join(ai.PromptVersions(), ", ")+")")
+	replayPath := flag.String("replay", "cmd/parseeval/testdata/recordings.jsonl", "replay file read by -backend replay and written by -record")
+	record := flag.Bool("record", false, "append the live backend's replies to the replay file")
+	schemaPath := flag.String("schema", "schemas/parse_response.schema.json", "parse response schema")
+	timeout := flag.Duration("timeout", time.Duration(cfg.ReqTimeoutSec)*time.Second, "timeout per transcript")
+	verbose := flag.Bool("v", false, "print every mismatch")
+	minAccuracy := flag.Float64("min", 0, "exit 1 when overall field accuracy (0-100) is below this")
+	flag.Parse()
+
+	if !ai.KnownPromptVersion(*prompt) {
+		log.Fatalf("unknown prompt version %q (have %s)", *prompt, strings.Join(ai.PromptVersions(), ", "))
+	}
+	fingerprint := ai.PromptFingerprint(*prompt)
+
+	cases, err := loadCorpus(*corpusPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewReferenceLoader("file://./" + strings.TrimPrefix(*schemaPath, "./")))
+	if err != nil {
+		log.Fatalf("schema: %v", err)
+	}
+
+	var parser ai.Parser
+	var replay *replayParser
+	switch *backend {
+	case "replay":
+		if replay, err = loadReplay(*replayPath, fingerprint); err != nil {
+			log.Fatalf("replay: %v", err)
+		}
+		parser = replay
+	default:
+		cfg.LLMProvider = *backend
+		cfg.OfflineParser = "off" // score the backend alone
+		cfg.PromptVersion = *prompt
+		if parser, err = ai.NewParser(cfg); err != nil {
+			log.Fatal(err)
+		}
+		if *record {
+			rec, err := newRecorder(parser, *backend, fingerprint, *replayPath)
+			if err != nil {
+				log.Fatalf("record: %v", err)
+			}
+			defer rec.Close()
+			parser = rec
+		}
+	}
+
+	fields := map[string]*tally{}
+	var entryCount, schemaValid tally
+	failures := 0
+	for _, gc := range cases {
+		tz := gc.TZ
+		if tz == "" {
+			tz = cfg.TZDefault
+		}
+		loc := loadLocation(tz)
+		profile := ai.Profile{Categories: gc.Categories}
+		if len(profile.Categories) == 0 {
+			for _, cat := range models.DefaultCategories {
+				profile.Categories = append(profile.Categories, cat.Name)
+			}
+		}
+
+		// Spoken amounts are rewritten as digits first, as the server does.
+		transcript := numwords.Normalize(gc.Transcript).Text
+		ctx, cancel := context.WithTimeout(ai.WithPromptVersion(context.Background(), *prompt), *timeout)
+		raw, err := parser.ParseText(ctx, transcript, tz, profile)
+		cancel()
+		today := time.Now().In(loc)
+		if replay != nil {
+			if t, ok := replay.recordedOn(transcript, loc); ok {
+				today = t
+			}
+		}
+
+		var drafts []map[string]any
+		if err == nil {
+			drafts, err = ai.SplitDrafts(raw)
+		}
+		if err != nil {
+			failures++
+			fmt.Fprintf(os.Stderr, "%s: %v\n", gc.ID, err)
+		}
+
+		entryCount.total++
+		if err == nil && len(drafts) == len(gc.Expected) {
+			entryCount.correct++
+		}
+		schemaValid.total++
+		if err == nil {
+			doc, _ := json.Marshal(map[string]any{"entries": drafts})
+			if res, verr := schema.Validate(gojsonschema.NewBytesLoader(doc)); verr == nil && res.Valid() {
+				schemaValid.correct++
+			} else if *verbose && verr == nil {
+				for _, e := range res.Errors() {
+					fmt.Printf("%s: schema: %s\n", gc.ID, e)
+				}
+			}
+		}
+
+		for i, want := range gc.Expected {
+			var got map[string]any
+			if i < len(drafts) {
+				got = drafts[i]
+			}
+			for field, w := range want {
+				t := fields[field]
+				if t == nil {
+					t = &tally{}
+					fields[field] = t
+				}
+				t.total++
+				if got != nil && matches(field, w, got[field], today) {
+					t.correct++
+				} else if *verbose {
+					fmt.Printf("%s #%d %s: want %v, got %v\n", gc.ID, i, field, w, got[field])
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(fields))
+	var overall tally
+	for name, t := range fields {
+		names = append(names, name)
+		overall.correct += t.correct
+		overall.total += t.total
+	}
+	sort.Strings(names)
+
+	fmt.Printf("backend %s, prompt %s, %d transcripts, %d failed\n\n", *backend, fingerprint, len(cases), failures)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "field\tcorrect\ttotal\taccuracy\t")
+	for _, name := range names {
+		t := fields[name]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t\n", name, t.correct, t.total, t)
+	}
+	fmt.Fprintf(w, "all fields\t%d\t%d\t%s\t\n", overall.correct, overall.total, overall)
+	fmt.Fprintf(w, "entry count\t%d\t%d\t%s\t\n", entryCount.correct, entryCount.total, entryCount)
+	fmt.Fprintf(w, "schema valid\t%d\t%d\t%s\t\n", schemaValid.correct, schemaValid.total, schemaValid)
+	w.Flush()
+
+	if overall.total > 0 && 100*float64(overall.correct)/float64(overall.total) < *minAccuracy {
+		os.Exit(1)
+	}
+}
+
+func loadCorpus(path string) ([]goldenCase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cases []goldenCase
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var gc goldenCase
+		if err := json.Unmarshal([]byte(text), &gc); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if gc.ID == "" {
+			gc.ID = "line " + strconv.Itoa(line)
+		}
+		cases = append(cases, gc)
+	}
+	return cases, sc.Err()
+}
+
+// matches compares one expected field with what the parser returned:
+// numbers within a paisa, strings case-insensitively, tags as a set, and
+// dates after resolving "today-N".
+func matches(field string, want, got any, today time.Time) bool {
+	switch w := want.(type) {
+	case nil:
+		return got == nil || got == ""
+	case float64:
+		g, ok := got.(float64)
+		return ok && math.Abs(g-w) < 0.005
+	case bool:
+		g, ok := got.(bool)
+		return ok && g == w
+	case string:
+		if field == "date" {
+			w = resolveDate(w, today)
+		}
+		g, _ := got.(string)
+		return strings.EqualFold(strings.TrimSpace(g), strings.TrimSpace(w))
+	case []any:
+		g, _ := got.([]any)
+		if len(g) != len(w) {
+			return false
+		}
+		seen := map[string]int{}
+		for _, v := range w {
+			seen[strings.ToLower(fmt.Sprint(v))]++
+		}
+		for _, v := range g {
+			k := strings.ToLower(fmt.Sprint(v))
+			if seen[k] == 0 {
+				return false
+			}
+			seen[k]--
+		}
+		return true
+	}
+	wb, _ := json.Marshal(want)
+	gb, _ := json.Marshal(got)
+	return string(wb) == string(gb)
+}
+
+// resolveDate turns "today", "today-1" or "today+2" into YYYY-MM-DD; other
+// values are returned unchanged.
+func resolveDate(s string, today time.Time) string {
+	if !strings.HasPrefix(s, "today") {
+		return s
+	}
+	days := 0
+	if rest := strings.TrimPrefix(s, "today"); rest != "" {
+		n, err := strconv.Atoi(strings.TrimPrefix(rest, "+"))
+		if err != nil {
+			return s
+		}
+		days = n
+	}
+	return today.AddDate(0, 0, days).Format("2006-01-02")
+}
+
+func loadLocation(tz string) *time.Location {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.FixedZone("IST", 5*3600+1800)
+	}
+	return loc
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"finance-parser-go/internal/ai"
+)
+
+// recording is one line of a replay file: the reply a live backend gave for
+// a transcript under one prompt.
+type recording struct {
+	Prompt     string          `json:"prompt"` // ai.PromptFingerprint
+	Transcript string          `json:"transcript"`
+	Backend    string          `json:"backend"`
+	Date       string          `json:"date"` // day the reply was recorded; relative dates resolve against it
+	Response   json.RawMessage `json:"response"`
+}
+
+func recordingKey(prompt, transcript string) string { return prompt + "\x00" + transcript }
+
+// replayParser answers from a replay file instead of calling a model, so a
+// prompt can be re-scored without network access or cost.
+type replayParser struct {
+	prompt     string
+	recordings map[string]recording
+}
+
+// loadReplay reads the recordings made for prompt. Recordings are not
+// checked in: a missing file, or one with nothing recorded under prompt, is
+// an error naming the -record run that produces them.
+func loadReplay(path, prompt string) (*replayParser, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s does not exist; record replies first with -backend openai -prompt %s -record", path, promptVersion(prompt))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p := &replayParser{prompt: prompt, recordings: map[string]recording{}}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var r recording
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if r.Prompt == prompt {
+			p.recordings[recordingKey(r.Prompt, r.Transcript)] = r
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(p.recordings) == 0 {
+		return nil, fmt.Errorf("%s has no recordings for prompt %s (it was added or edited since); record them with -backend openai -prompt %s -record", path, prompt, promptVersion(prompt))
+	}
+	return p, nil
+}
+
+// promptVersion is the version name in a fingerprint ("v1-0a1b2c3d4e5f").
+func promptVersion(fingerprint string) string {
+	return strings.SplitN(fingerprint, "-", 2)[0]
+}
+
+func (p *replayParser) ParseText(ctx context.Context, transcript, tz string, profile ai.Profile) ([]byte, error) {
+	r, ok := p.recordings[recordingKey(p.prompt, transcript)]
+	if !ok {
+		return nil, fmt.Errorf("no recording for prompt %s", p.prompt)
+	}
+	return r.Response, nil
+}
+
+// recordedOn returns the day a transcript's reply was recorded.
+func (p *replayParser) recordedOn(transcript string, loc *time.Location) (time.Time, bool) {
+	r, ok := p.recordings[recordingKey(p.prompt, transcript)]
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation("2006-01-02", r.Date, loc)
+	return t, err == nil
+}
+
+// recorder passes calls through to a live backend and appends each reply
+// to a replay file.
+type recorder struct {
+	ai.Parser
+	backend string
+	prompt  string
+	mu      sync.Mutex
+	out     *os.File
+}
+
+func newRecorder(p ai.Parser, backend, prompt, path string) (*recorder, error) {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &recorder{Parser: p, backend: backend, prompt: prompt, out: out}, nil
+}
+
+func (r *recorder) ParseText(ctx context.Context, transcript, tz string, profile ai.Profile) ([]byte, error) {
+	raw, err := r.Parser.ParseText(ctx, transcript, tz, profile)
+	if err != nil {
+		return nil, err
+	}
+	line, err := json.Marshal(recording{
+		Prompt:     r.prompt,
+		Transcript: transcript,
+		Backend:    r.backend,
+		Date:       time.Now().In(loadLocation(tz)).Format("2006-01-02"),
+		Response:   json.RawMessage(raw),
+	})
+	if err != nil {
+		// Not JSON; score it as is but don't record it.
+		return raw, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.out.Write(append(line, '\n')); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func (r *recorder) Close() error { return r.out.Close() }
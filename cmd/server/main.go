@@ -17,10 +17,15 @@ func main() {
 	_ = godotenv.Load(".env")
 	fmt.Println("DB_NAME:", os.Getenv("DB_NAME"))
 	fmt.Println("DB_USER:", os.Getenv("DB_USER"))
+	cfg := config.Load()
+
 	database.Connect()
-	database.DB.AutoMigrate(&models.Entry{}, &models.User{}, &models.QuickPrompt{}, &models.Account{})
+	database.DB.AutoMigrate(&models.Entry{}, &models.User{}, &models.QuickPrompt{}, &models.Account{},
+		&models.Counterparty{}, &models.Loan{}, &models.LoanRepayment{},
+		&models.Category{}, &models.SubCategory{}, &models.Rule{}, &models.Merchant{}, &models.ImportBatch{}, &models.Report{}, &models.ParseSession{},
+		&models.UsageDay{}, &models.ParseCacheEntry{}, &models.ParseLog{}, &models.ParseFieldResult{}, &models.Migration{})
+	database.BackfillOccurredAt(cfg.TZDefault)
 
-	cfg := config.Load()
 	r := httpserver.NewServer(cfg)
 	log.Printf("listening on :%s", cfg.Port)
 	if err := r.Run(":" + cfg.Port); err != nil {
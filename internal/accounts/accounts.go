@@ -0,0 +1,163 @@
+// Package accounts maps the free-text account_hint of a parsed draft onto
+// one of the user's accounts.
+package accounts
+
+import (
+	"regexp"
+	"strings"
+
+	"finance-parser-go/internal/merchants"
+	"finance-parser-go/internal/models"
+)
+
+// Confidence values returned by Resolve, strongest match first.
+const (
+	ConfidenceLast4    = 0.95 // the hint names the account's last 4 digits
+	ConfidenceName     = 0.9  // the hint is the account's name
+	ConfidencePartial  = 0.8  // the hint contains the account's name or a word only it has
+	ConfidenceProvider = 0.7  // the hint names the bank or issuer of one account
+	ConfidenceMode     = 0.5  // no usable hint; the only account of the mode's type
+	ConfidenceDefault  = 0.3  // no usable hint; the user's default account
+)
+
+// digits4 finds four digits on their own or after a mask, as in "xx5678".
+var digits4 = regexp.MustCompile(`(?i)(?:\b|[x*])(\d{4})\b`)
+
+// modeTypes lists the account types a payment mode can go through.
+var modeTypes = map[string][]string{
+	"Credit Card": {"credit"},
+	"UPI":         {"upi", "bank", "debit"},
+	"Wallets":     {"wallet"},
+}
+
+// Resolve picks the account a draft was paid from or into. It returns nil
+// and 0 when nothing fits or when several accounts fit equally well.
+func Resolve(list []models.Account, hint, mode string) (*models.Account, float64) {
+	if len(list) == 0 {
+		return nil, 0
+	}
+	key := merchants.Key(hint)
+	if key != "" {
+		if a := only(list, func(a *models.Account) bool {
+			last4 := LastFour(a.Identifier)
+			for _, d := range digits4.FindAllStringSubmatch(hint, -1) {
+				if last4 != "" && d[1] == last4 {
+					return true
+				}
+			}
+			return false
+		}); a != nil {
+			return a, ConfidenceLast4
+		}
+		if a := only(list, func(a *models.Account) bool { return merchants.Key(a.Name) == key }); a != nil {
+			return a, ConfidenceName
+		}
+		if a := only(list, func(a *models.Account) bool { return containsWords(key, merchants.Key(a.Name)) }); a != nil {
+			return a, ConfidencePartial
+		}
+		// "millennia card" for "HDFC Millennia": a word only that account's name has.
+		if a := only(list, func(a *models.Account) bool { return distinctiveMatch(key, a) }); a != nil {
+			return a, ConfidencePartial
+		}
+		if a := only(list, func(a *models.Account) bool { return containsWords(key, merchants.Key(a.Provider)) }); a != nil {
+			return a, ConfidenceProvider
+		}
+		// "HDFC card" with both an HDFC debit and credit account: let the mode decide.
+		if types, ok := modeTypes[mode]; ok {
+			if a := only(list, func(a *models.Account) bool {
+				return containsWords(key, merchants.Key(a.Provider)) && hasType(types, a.Type)
+			}); a != nil {
+				return a, ConfidenceProvider
+			}
+		}
+		// The hint names something the user has no account for.
+		return nil, 0
+	}
+
+	types, typed := modeTypes[mode]
+	if typed {
+		if a := only(list, func(a *models.Account) bool { return hasType(types, a.Type) }); a != nil {
+			return a, ConfidenceMode
+		}
+	}
+	if mode == "Cash" {
+		return nil, 0
+	}
+	if a := only(list, func(a *models.Account) bool { return a.IsDefault && (!typed || hasType(types, a.Type)) }); a != nil {
+		return a, ConfidenceDefault
+	}
+	return nil, 0
+}
+
+// only returns the single account matching fn, or nil when none or several do.
+func only(list []models.Account, fn func(*models.Account) bool) *models.Account {
+	var found *models.Account
+	for i := range list {
+		if fn(&list[i]) {
+			if found != nil {
+				return nil
+			}
+			found = &list[i]
+		}
+	}
+	return found
+}
+
+// genericWords say what kind of account it is, not which one.
+var genericWords = map[string]bool{
+	"card": true, "credit": true, "debit": true, "account": true, "bank": true,
+	"wallet": true, "upi": true, "savings": true, "salary": true, "my": true, "the": true,
+}
+
+// distinctiveMatch reports whether the hint contains a word of the account's
+// name that is not its provider or a generic word like "card".
+func distinctiveMatch(key string, a *models.Account) bool {
+	provider := map[string]bool{}
+	for _, w := range strings.Fields(merchants.Key(a.Provider)) {
+		provider[w] = true
+	}
+	for _, w := range strings.Fields(merchants.Key(a.Name)) {
+		if len(w) < 3 || provider[w] || genericWords[w] {
+			continue
+		}
+		if containsWords(key, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsWords reports whether the words of sub appear in key in order.
+func containsWords(key, sub string) bool {
+	if sub == "" {
+		return false
+	}
+	return strings.Contains(" "+key+" ", " "+sub+" ")
+}
+
+// LastFour returns the last 4 digits of a card or account number, or "" for
+// identifiers such as UPI IDs that have none.
+func LastFour(identifier string) string {
+	if strings.Contains(identifier, "@") {
+		return ""
+	}
+	var d []rune
+	for _, r := range identifier {
+		if r >= '0' && r <= '9' {
+			d = append(d, r)
+		}
+	}
+	if len(d) < 4 {
+		return ""
+	}
+	return string(d[len(d)-4:])
+}
+
+func hasType(types []string, t string) bool {
+	for _, x := range types {
+		if strings.EqualFold(x, t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package accounts
+
+import (
+	"testing"
+
+	"finance-parser-go/internal/models"
+)
+
+var testAccounts = []models.Account{
+	{ID: 1, Type: "credit", Name: "HDFC Millennia", Provider: "HDFC", Identifier: "1234"},
+	{ID: 2, Type: "bank", Name: "HDFC Savings", Provider: "HDFC", Identifier: "XXXX5678"},
+	{ID: 3, Type: "credit", Name: "Amazon Pay ICICI", Provider: "ICICI", Identifier: "9876"},
+	{ID: 4, Type: "wallet", Name: "Paytm", Provider: "Paytm", Identifier: "98765@paytm"},
+	{ID: 5, Type: "bank", Name: "Salary", Provider: "SBI", Identifier: "4321", IsDefault: true},
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		hint, mode string
+		id         uint // 0 for no account
+		confidence float64
+	}{
+		// Exact name, whatever the case and spacing.
+		{"HDFC Millennia", "", 1, ConfidenceName},
+		{"  hdfc  millennia ", "Credit Card", 1, ConfidenceName},
+		// Last four digits beat everything else in the hint.
+		{"card ending 9876", "", 3, ConfidenceLast4},
+		{"hdfc xx5678", "", 2, ConfidenceLast4},
+		{"paid from 5678 hdfc millennia", "", 2, ConfidenceLast4},
+		// Partial names and distinctive words.
+		{"my hdfc millennia card", "", 1, ConfidencePartial},
+		{"millennia card", "", 1, ConfidencePartial},
+		{"amazon pay card", "", 3, ConfidencePartial},
+		// Provider alone, and provider narrowed by mode.
+		{"sbi", "", 5, ConfidenceProvider},
+		{"hdfc card", "Credit Card", 1, ConfidenceProvider},
+		{"hdfc", "UPI", 2, ConfidenceProvider},
+		// Ambiguous or unknown hints match nothing.
+		{"hdfc", "", 0, 0},
+		{"hdfc", "Cash", 0, 0},
+		{"kotak card", "Credit Card", 0, 0},
+		// No hint: the only account of the mode's type, then the default.
+		{"", "Wallets", 4, ConfidenceMode},
+		{"", "UPI", 5, ConfidenceDefault},
+		{"", "", 5, ConfidenceDefault},
+		{"", "Credit Card", 0, 0}, // two credit cards and the default is a bank account
+		{"", "Cash", 0, 0},
+	}
+	for _, tt := range tests {
+		a, conf := Resolve(testAccounts, tt.hint, tt.mode)
+		var id uint
+		if a != nil {
+			id = a.ID
+		}
+		if id != tt.id || conf != tt.confidence {
+			t.Errorf("Resolve(%q, %q) = account %d, %v; want %d, %v", tt.hint, tt.mode, id, conf, tt.id, tt.confidence)
+		}
+	}
+
+	if a, conf := Resolve(nil, "hdfc", ""); a != nil || conf != 0 {
+		t.Errorf("Resolve with no accounts = %v, %v", a, conf)
+	}
+}
+
+func TestLastFour(t *testing.T) {
+	tests := map[string]string{
+		"1234":             "1234",
+		"XXXX XXXX 5678":   "5678",
+		"50100012341234":   "1234",
+		"98765@paytm":      "",
+		"12":               "",
+		"":                 "",
+		"card no. 4321-99": "2199",
+	}
+	for in, want := range tests {
+		if got := LastFour(in); got != want {
+			t.Errorf("LastFour(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
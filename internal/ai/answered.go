@@ -0,0 +1,31 @@
+package ai
+
+import "context"
+
+// Backends that can answer in place of the configured model.
+const (
+	BackendFastPath = "offline-fastpath" // a confident offline parse, model not called
+	BackendFallback = "offline-fallback" // the model failed; the offline parser answered
+	BackendMerge    = "offline-merge"    // an answer merged by rules, see Clarify
+)
+
+// AnsweredBy records which backend produced a reply when it was not the
+// configured model. Attach one to a context with WithAnsweredBy; it stays
+// empty when the model answered.
+type AnsweredBy struct {
+	Backend string
+}
+
+type answeredByKey struct{}
+
+func WithAnsweredBy(ctx context.Context, a *AnsweredBy) context.Context {
+	return context.WithValue(ctx, answeredByKey{}, a)
+}
+
+// answeredBy records backend on the context's AnsweredBy, if any. The first
+// one recorded is kept when a request makes several calls.
+func answeredBy(ctx context.Context, backend string) {
+	if a, _ := ctx.Value(answeredByKey{}).(*AnsweredBy); a != nil && a.Backend == "" {
+		a.Backend = backend
+	}
+}
@@ -0,0 +1,37 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type failingParser struct{}
+
+func (failingParser) ParseText(ctx context.Context, transcript, tz string, profile Profile) ([]byte, error) {
+	return nil, errors.New("model unavailable")
+}
+
+func TestFallbackParserAnsweredBy(t *testing.T) {
+	tests := []struct {
+		name   string
+		parser *FallbackParser
+		want   string
+	}{
+		{"model answered", &FallbackParser{Primary: &FakeClient{}, Offline: &OfflineParser{}}, ""},
+		{"fallback", &FallbackParser{Primary: failingParser{}, Offline: &OfflineParser{}}, BackendFallback},
+		{"fast path", &FallbackParser{Primary: failingParser{}, Offline: &OfflineParser{}, FastPath: true}, BackendFastPath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a AnsweredBy
+			ctx := WithAnsweredBy(context.Background(), &a)
+			if _, err := tt.parser.ParseText(ctx, "paid 450 for lunch at Subway via UPI today", "Asia/Kolkata", Profile{Categories: []string{"Food"}}); err != nil {
+				t.Fatal(err)
+			}
+			if a.Backend != tt.want {
+				t.Errorf("answered by %q, want %q", a.Backend, tt.want)
+			}
+		})
+	}
+}
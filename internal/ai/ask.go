@@ -0,0 +1,391 @@
+package ai
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance-parser-go/internal/merchants"
+)
+
+//go:embed ask_prompt.txt
+var askPromptText string
+
+// Query is the structured form of a question about the ledger, as produced
+// by Translate and checked against schemas/ask_query.schema.json. It only
+// names Entry fields and fixed operations; the server turns it into SQL.
+type Query struct {
+	Type       string   `json:"type,omitempty"` // expense, income or "" for both
+	Categories []string `json:"categories,omitempty"`
+	Merchants  []string `json:"merchants,omitempty"`
+	Modes      []string `json:"modes,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Text       string   `json:"text,omitempty"` // matched against title, merchant and notes
+	StartDate  string   `json:"start_date,omitempty"`
+	EndDate    string   `json:"end_date,omitempty"` // inclusive
+	MinAmount  *float64 `json:"min_amount,omitempty"`
+	MaxAmount  *float64 `json:"max_amount,omitempty"`
+	Metric     string   `json:"metric"`             // sum, count, average, max, min
+	GroupBy    string   `json:"group_by,omitempty"` // category, merchant, mode, month
+	Sort       string   `json:"sort,omitempty"`     // date_desc, date_asc, amount_desc, amount_asc
+	Limit      int      `json:"limit,omitempty"`
+}
+
+const (
+	QueryDefaultLimit = 50
+	QueryMaxLimit     = 200
+)
+
+// Asker is implemented by parsers that can translate a question into a
+// Query. The reply is the Query as JSON.
+type Asker interface {
+	TranslateQuestion(ctx context.Context, question, tz string, profile Profile) ([]byte, error)
+}
+
+// Translate turns a question into Query JSON with p when it is an Asker,
+// and with the offline rules otherwise or when p fails.
+func Translate(ctx context.Context, p Parser, question, tz string, profile Profile) ([]byte, error) {
+	if a, ok := p.(Asker); ok {
+		if out, err := a.TranslateQuestion(ctx, question, tz, profile); err == nil {
+			return out, nil
+		}
+	}
+	return json.Marshal(OfflineQuery(question, time.Now().In(userLocation(tz)), profile))
+}
+
+func (c *OpenAIClient) TranslateQuestion(ctx context.Context, question, tz string, profile Profile) ([]byte, error) {
+	if err := c.checkKey(); err != nil {
+		return nil, err
+	}
+
+	return c.chat(ctx, c.model, []map[string]any{
+		{"role": "system", "content": askPromptText},
+		{"role": "user", "content": userMessage(question, tz, profile)},
+	})
+}
+
+func (f *FallbackParser) TranslateQuestion(ctx context.Context, question, tz string, profile Profile) ([]byte, error) {
+	if a, ok := f.Primary.(Asker); ok {
+		return a.TranslateQuestion(ctx, question, tz, profile)
+	}
+	return nil, fmt.Errorf("primary parser cannot translate questions")
+}
+
+// Normalize fills defaults and checks what the schema can't: dates are real
+// and ordered, and categories use the user's spelling. Unknown categories
+// are an error rather than silently matching nothing. A total with no type
+// is a total of expenses; adding income to it would mean nothing.
+func (q *Query) Normalize(categories []string) error {
+	q.Type = strings.ToLower(q.Type)
+	if q.Metric == "" {
+		q.Metric = "sum"
+	}
+	if q.Metric == "sum" && q.Type == "" {
+		q.Type = "expense"
+	}
+	if q.Sort == "" {
+		q.Sort = "date_desc"
+	}
+	if q.Limit <= 0 {
+		q.Limit = QueryDefaultLimit
+	}
+	if q.Limit > QueryMaxLimit {
+		q.Limit = QueryMaxLimit
+	}
+
+	var start, end time.Time
+	var err error
+	if q.StartDate != "" {
+		if start, err = time.Parse("2006-01-02", q.StartDate); err != nil {
+			return fmt.Errorf("invalid start_date %q", q.StartDate)
+		}
+	}
+	if q.EndDate != "" {
+		if end, err = time.Parse("2006-01-02", q.EndDate); err != nil {
+			return fmt.Errorf("invalid end_date %q", q.EndDate)
+		}
+	}
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		return fmt.Errorf("end_date is before start_date")
+	}
+	if q.MinAmount != nil && q.MaxAmount != nil && *q.MaxAmount < *q.MinAmount {
+		return fmt.Errorf("max_amount is below min_amount")
+	}
+
+	for i, c := range q.Categories {
+		match := ""
+		for _, name := range categories {
+			if strings.EqualFold(strings.TrimSpace(c), name) {
+				match = name
+			}
+		}
+		if match == "" {
+			return fmt.Errorf("unknown category %q", c)
+		}
+		q.Categories[i] = match
+	}
+	return nil
+}
+
+// Explain describes the filters in words, e.g. "Total of expenses in Food
+// from 1 Mar 2026 to 31 Mar 2026".
+func (q *Query) Explain() string {
+	metric := map[string]string{
+		"sum": "Total of", "count": "Number of", "average": "Average of",
+		"max": "Largest of", "min": "Smallest of",
+	}[q.Metric]
+	what := "all transactions"
+	switch q.Type {
+	case "expense":
+		what = "expenses"
+	case "income":
+		what = "income"
+	}
+	parts := []string{metric + " " + what}
+
+	if len(q.Categories) > 0 {
+		parts = append(parts, "in "+joinOr(q.Categories))
+	}
+	if len(q.Merchants) > 0 {
+		parts = append(parts, "at "+joinOr(q.Merchants))
+	}
+	if len(q.Modes) > 0 {
+		parts = append(parts, "paid by "+joinOr(q.Modes))
+	}
+	if len(q.Tags) > 0 {
+		parts = append(parts, "tagged "+joinOr(q.Tags))
+	}
+	if q.Text != "" {
+		parts = append(parts, fmt.Sprintf("mentioning %q", q.Text))
+	}
+	switch {
+	case q.MinAmount != nil && q.MaxAmount != nil:
+		parts = append(parts, fmt.Sprintf("between %s and %s", amountWords(*q.MinAmount), amountWords(*q.MaxAmount)))
+	case q.MinAmount != nil:
+		parts = append(parts, "of at least "+amountWords(*q.MinAmount))
+	case q.MaxAmount != nil:
+		parts = append(parts, "of at most "+amountWords(*q.MaxAmount))
+	}
+	switch {
+	case q.StartDate != "" && q.EndDate != "":
+		parts = append(parts, "from "+dateWords(q.StartDate)+" to "+dateWords(q.EndDate))
+	case q.StartDate != "":
+		parts = append(parts, "since "+dateWords(q.StartDate))
+	case q.EndDate != "":
+		parts = append(parts, "up to "+dateWords(q.EndDate))
+	default:
+		parts = append(parts, "over all time")
+	}
+	if q.GroupBy != "" {
+		parts = append(parts, "grouped by "+q.GroupBy)
+	}
+	return strings.Join(parts, " ")
+}
+
+func joinOr(list []string) string {
+	if len(list) <= 1 {
+		return strings.Join(list, "")
+	}
+	return strings.Join(list[:len(list)-1], ", ") + " or " + list[len(list)-1]
+}
+
+func amountWords(v float64) string {
+	return "₹" + strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func dateWords(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.Format("2 Jan 2006")
+}
+
+var (
+	askCount   = regexp.MustCompile(`(?i)\b(how many|count|number of|times)\b`)
+	askAverage = regexp.MustCompile(`(?i)\b(average|avg|mean)\b`)
+	askMax     = regexp.MustCompile(`(?i)\b(biggest|largest|highest|most expensive|max(?:imum)?)\b`)
+	askMin     = regexp.MustCompile(`(?i)\b(smallest|lowest|cheapest|min(?:imum)?)\b`)
+	askIncome  = regexp.MustCompile(`(?i)\b(earn(?:ed)?|income|received|receive|got paid|salary|credited|refunds?)\b`)
+	askExpense = regexp.MustCompile(`(?i)\b(spend|spent|spending|paid|pay|expenses?|cost|bought|buy|rides?|orders?)\b`)
+
+	askOver    = regexp.MustCompile(`(?i)\b(?:over|above|more than|greater than|at least)\s*(?:₹|rs\.?\s*)?(\d[\d,]*(?:\.\d+)?)(k)?\b`)
+	askUnder   = regexp.MustCompile(`(?i)\b(?:under|below|less than|at most)\s*(?:₹|rs\.?\s*)?(\d[\d,]*(?:\.\d+)?)(k)?\b`)
+	askBetween = regexp.MustCompile(`(?i)\bbetween\s*(?:₹|rs\.?\s*)?(\d[\d,]*(?:\.\d+)?)(k)?\s+and\s+(?:₹|rs\.?\s*)?(\d[\d,]*(?:\.\d+)?)(k)?\b`)
+
+	askLastDays  = regexp.MustCompile(`(?i)\b(?:last|past)\s+(\d+)\s+days\b`)
+	askMonthName = regexp.MustCompile(`(?i)\b(?:in|during|for|of)\s+` + offlineMonthName + `(?:\s+(\d{4})\b)?`)
+	askGroupBy   = regexp.MustCompile(`(?i)\b(?:by|per|each|every)\s+(category|categories|merchant|merchants|shop|store|mode|payment mode|month)\b|\bmonthly\b`)
+)
+
+// OfflineQuery reads the common question shapes with rules: a metric
+// ("how much", "how many", "average"), a period ("in March", "last month",
+// "last 7 days"), amount bounds ("over 300"), and the user's categories,
+// merchants and payment modes named in the question.
+func OfflineQuery(question string, now time.Time, profile Profile) Query {
+	lower := strings.ToLower(question)
+	q := Query{Metric: "sum"}
+
+	switch {
+	case askCount.MatchString(lower):
+		q.Metric = "count"
+	case askAverage.MatchString(lower):
+		q.Metric = "average"
+	case askMax.MatchString(lower):
+		q.Metric = "max"
+	case askMin.MatchString(lower):
+		q.Metric = "min"
+	}
+	switch {
+	case askIncome.MatchString(lower):
+		q.Type = "income"
+	case askExpense.MatchString(lower) || q.Metric == "sum":
+		q.Type = "expense"
+	}
+
+	// Amounts first, so their digits aren't mistaken for anything else.
+	if m := askBetween.FindStringSubmatch(lower); m != nil {
+		lo, hi := askAmount(m[1], m[2]), askAmount(m[3], m[4])
+		q.MinAmount, q.MaxAmount = &lo, &hi
+	} else {
+		if m := askOver.FindStringSubmatch(lower); m != nil {
+			v := askAmount(m[1], m[2])
+			q.MinAmount = &v
+		}
+		if m := askUnder.FindStringSubmatch(lower); m != nil {
+			v := askAmount(m[1], m[2])
+			q.MaxAmount = &v
+		}
+	}
+
+	q.StartDate, q.EndDate = askPeriod(lower, now)
+
+	for _, c := range profile.Categories {
+		if offlineContainsAny(lower, []string{strings.ToLower(c)}) {
+			q.Categories = append(q.Categories, c)
+		}
+	}
+	if len(q.Categories) == 0 {
+		for _, kc := range offlineCategories {
+			if c := offlinePickCategory(kc.category, profile.Categories); c != "" && offlineContainsAny(lower, kc.words) {
+				q.Categories = append(q.Categories, c)
+				break
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	addMerchant := func(name string) {
+		key := merchants.Key(name)
+		if key != "" && !seen[key] && offlineContainsAny(lower, []string{key}) {
+			seen[key] = true
+			q.Merchants = append(q.Merchants, name)
+		}
+	}
+	for _, m := range profile.Merchants {
+		addMerchant(m.Name)
+	}
+	for _, m := range merchants.Builtin {
+		addMerchant(m.Name)
+	}
+	// "Uber rides" is about the merchant, not the Travel category.
+	if len(q.Merchants) > 0 {
+		q.Categories = nil
+	}
+
+	for _, m := range offlineModes {
+		if m.network == "" && offlineContainsAny(lower, m.words) {
+			q.Modes = append(q.Modes, m.mode)
+			break
+		}
+	}
+
+	if m := askGroupBy.FindStringSubmatch(lower); m != nil {
+		switch {
+		case m[1] == "" || m[1] == "month":
+			q.GroupBy = "month"
+		case strings.HasPrefix(m[1], "categor"):
+			q.GroupBy = "category"
+		case strings.Contains(m[1], "mode"):
+			q.GroupBy = "mode"
+		default:
+			q.GroupBy = "merchant"
+		}
+	}
+
+	if q.Metric == "max" {
+		q.Sort = "amount_desc"
+	}
+	if q.Metric == "min" {
+		q.Sort = "amount_asc"
+	}
+	return q
+}
+
+func askAmount(num, k string) float64 {
+	v, _ := strconv.ParseFloat(strings.ReplaceAll(num, ",", ""), 64)
+	if k != "" {
+		v *= 1000
+	}
+	return v
+}
+
+// askPeriod resolves the period named in the question to inclusive dates.
+func askPeriod(lower string, now time.Time) (string, string) {
+	day := func(t time.Time) string { return t.Format("2006-01-02") }
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	switch {
+	case strings.Contains(lower, "today"):
+		return day(today), day(today)
+	case strings.Contains(lower, "yesterday"):
+		y := today.AddDate(0, 0, -1)
+		return day(y), day(y)
+	case strings.Contains(lower, "this week"):
+		offset := (int(today.Weekday()) + 6) % 7 // weeks start on Monday
+		return day(today.AddDate(0, 0, -offset)), day(today)
+	case strings.Contains(lower, "last week"):
+		offset := (int(today.Weekday()) + 6) % 7
+		start := today.AddDate(0, 0, -offset-7)
+		return day(start), day(start.AddDate(0, 0, 6))
+	case strings.Contains(lower, "this month"):
+		return day(monthStart), day(today)
+	case strings.Contains(lower, "last month"):
+		start := monthStart.AddDate(0, -1, 0)
+		return day(start), day(monthStart.AddDate(0, 0, -1))
+	case strings.Contains(lower, "this year"):
+		return day(time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())), day(today)
+	case strings.Contains(lower, "last year"):
+		return fmt.Sprintf("%d-01-01", now.Year()-1), fmt.Sprintf("%d-12-31", now.Year()-1)
+	}
+	if m := askLastDays.FindStringSubmatch(lower); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return day(today.AddDate(0, 0, -n+1)), day(today)
+	}
+	if m := askMonthName.FindStringSubmatch(lower); m != nil {
+		year := now.Year()
+		month := offlineMonths[m[1][:3]]
+		if m[2] != "" {
+			year, _ = strconv.Atoi(m[2])
+		} else if month > now.Month() {
+			year-- // "in November" asked in March means last November
+		}
+		start := time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+		return day(start), day(start.AddDate(0, 1, -1))
+	}
+	return "", ""
+}
+
+func (p *OfflineParser) TranslateQuestion(ctx context.Context, question, tz string, profile Profile) ([]byte, error) {
+	return json.Marshal(OfflineQuery(question, time.Now().In(userLocation(tz)), profile))
+}
+
+func (f *FakeClient) TranslateQuestion(ctx context.Context, question, tz string, profile Profile) ([]byte, error) {
+	return json.Marshal(OfflineQuery(question, time.Now().In(userLocation(tz)), profile))
+}
@@ -0,0 +1,46 @@
+package ai
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOfflineQueryMonthName(t *testing.T) {
+	now := time.Date(2024, time.August, 20, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		question   string
+		start, end string
+	}{
+		{"how much did I spend in march", "2024-03-01", "2024-03-31"},
+		{"food spending in Sept 2023", "2023-09-01", "2023-09-30"},
+		{"how much for december", "2023-12-01", "2023-12-31"},
+		// Words that only start like a month are not one.
+		{"how much did I spend on junk food", "", ""},
+		{"how much for mayonnaise", "", ""},
+		{"how much at the market", "", ""},
+	}
+	for _, tt := range tests {
+		q := OfflineQuery(tt.question, now, Profile{})
+		if q.StartDate != tt.start || q.EndDate != tt.end {
+			t.Errorf("%q: period %s..%s, want %s..%s", tt.question, q.StartDate, q.EndDate, tt.start, tt.end)
+		}
+	}
+}
+
+func TestNormalizeSumDefaultsToExpense(t *testing.T) {
+	q := Query{}
+	if err := q.Normalize(nil); err != nil {
+		t.Fatal(err)
+	}
+	if q.Metric != "sum" || q.Type != "expense" {
+		t.Errorf("metric %q type %q, want sum of expense", q.Metric, q.Type)
+	}
+
+	q = Query{Metric: "count"}
+	if err := q.Normalize(nil); err != nil {
+		t.Fatal(err)
+	}
+	if q.Type != "" {
+		t.Errorf("count type %q, want both types", q.Type)
+	}
+}
@@ -0,0 +1,103 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Clarifier is implemented by parsers that can revise a draft given the
+// user's answer to its clarification questions.
+type Clarifier interface {
+	Clarify(ctx context.Context, transcript string, prior []byte, answer, tz string, profile Profile) ([]byte, error)
+}
+
+func clarifyMessage(answer string) string {
+	return fmt.Sprintf("Answer to your questions: %s\n"+
+		"Reply with the updated entry in the same {\"entries\": [...]} format. Apply the answer, keep everything else as it was, "+
+		"and drop needs_confirmation and clarifications for the fields the answer settles.", answer)
+}
+
+// Clarify applies the user's answer to one draft. Parsers implementing
+// Clarifier are asked first; otherwise, or when that fails, the answer is
+// read with the offline parser and merged into the fields awaiting
+// confirmation.
+func Clarify(ctx context.Context, p Parser, transcript string, prior map[string]any, answer, tz string, profile Profile) (map[string]any, error) {
+	if c, ok := p.(Clarifier); ok {
+		priorJSON, err := wrapDrafts(prior)
+		if err != nil {
+			return nil, err
+		}
+		if out, err := c.Clarify(ctx, transcript, priorJSON, answer, tz, profile); err == nil {
+			if drafts, err := SplitDrafts(out); err == nil {
+				updated := drafts[0]
+				// The draft keeps describing the same words of the transcript.
+				updated["source_text"] = prior["source_text"]
+				return updated, nil
+			}
+		}
+	}
+	answeredBy(ctx, BackendMerge)
+	return mergeAnswer(prior, answer, tz, profile.Categories), nil
+}
+
+// Clarify on a FallbackParser goes to the primary parser when it can.
+func (f *FallbackParser) Clarify(ctx context.Context, transcript string, prior []byte, answer, tz string, profile Profile) ([]byte, error) {
+	if c, ok := f.Primary.(Clarifier); ok {
+		return c.Clarify(ctx, transcript, prior, answer, tz, profile)
+	}
+	return nil, fmt.Errorf("primary parser cannot clarify")
+}
+
+// mergeAnswer reads the answer with the offline parser and copies over the
+// fields that were awaiting confirmation and that the answer states outright.
+// A defaulted date or type in the answer is not treated as an answer.
+func mergeAnswer(prior map[string]any, answer, tz string, categories []string) map[string]any {
+	updated := map[string]any{}
+	for k, v := range prior {
+		updated[k] = v
+	}
+
+	parsed, _ := (&OfflineParser{}).Parse(answer, time.Now().In(userLocation(tz)), categories)
+	conf, _ := parsed["confidence"].(map[string]any)
+	needs, _ := prior["needs_confirmation"].(map[string]any)
+
+	remaining := map[string]any{}
+	for field, v := range needs {
+		if pending, _ := v.(bool); !pending {
+			continue
+		}
+		value, known := parsed[field]
+		c, stated := conf[field]
+		if (field == "date" || field == "type") && c != offlineConfidence {
+			stated = false
+		}
+		if !known || value == nil || !stated {
+			remaining[field] = true
+			continue
+		}
+		updated[field] = value
+		if field == "mode" && parsed["card_network"] != nil {
+			updated["card_network"] = parsed["card_network"]
+		}
+	}
+
+	if len(remaining) == 0 {
+		delete(updated, "needs_confirmation")
+		delete(updated, "clarifications")
+	} else {
+		updated["needs_confirmation"] = remaining
+	}
+	return updated
+}
+
+// Pending reports whether a draft still has fields awaiting confirmation.
+func Pending(draft map[string]any) bool {
+	needs, _ := draft["needs_confirmation"].(map[string]any)
+	for _, v := range needs {
+		if b, _ := v.(bool); b {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SplitDrafts reads a Parser reply into individual drafts. The expected
+// shape is {"entries": [...]}, but a bare array or a single draft object is
+// accepted too, since models don't always follow the wrapper.
+func SplitDrafts(raw []byte) ([]map[string]any, error) {
+	var wrapped struct {
+		Entries []map[string]any `json:"entries"`
+	}
+	if err := json.Unmarshal(raw, &wrapped); err == nil && wrapped.Entries != nil {
+		if len(wrapped.Entries) == 0 {
+			return nil, fmt.Errorf("no entries in parse response")
+		}
+		return wrapped.Entries, nil
+	}
+
+	var list []map[string]any
+	if err := json.Unmarshal(raw, &list); err == nil {
+		if len(list) == 0 {
+			return nil, fmt.Errorf("no entries in parse response")
+		}
+		return list, nil
+	}
+
+	var single map[string]any
+	if err := json.Unmarshal(raw, &single); err != nil {
+		return nil, fmt.Errorf("invalid parse response: %w", err)
+	}
+	return []map[string]any{single}, nil
+}
+
+// wrapDrafts is the inverse of SplitDrafts for parsers built in this package.
+func wrapDrafts(drafts ...map[string]any) ([]byte, error) {
+	return json.Marshal(map[string]any{"entries": drafts})
+}
@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Provider failures, matched with errors.Is so handlers can pick a status
+// without parsing messages.
+var (
+	ErrNotConfigured = errors.New("provider not configured") // missing or rejected API key
+	ErrRateLimited   = errors.New("provider rate limited")
+	ErrUnavailable   = errors.New("provider unavailable") // 5xx, connection failure or open circuit
+	ErrTimeout       = errors.New("provider timed out")
+	ErrBadResponse   = errors.New("provider sent an unusable response")
+)
+
+// ProviderError is a failed call to a model provider. It matches its Kind
+// with errors.Is.
+type ProviderError struct {
+	Op         string        // "llm" or "whisper"
+	Kind       error         // one of the Err values above
+	Status     int           // HTTP status; 0 when no reply came back
+	Body       string        // start of the reply body
+	RetryAfter time.Duration // from Retry-After, or until the circuit closes
+	Err        error         // transport error, if any
+}
+
+func (e *ProviderError) Error() string {
+	switch {
+	case e.Status != 0:
+		return fmt.Sprintf("%s error: %d %s", e.Op, e.Status, e.Body)
+	case e.Err != nil:
+		return fmt.Sprintf("%s error: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("%s error: %v", e.Op, e.Kind)
+}
+
+func (e *ProviderError) Is(target error) bool { return target == e.Kind }
+
+func (e *ProviderError) Unwrap() error { return e.Err }
+
+// retryable reports whether trying again could give a different answer.
+func (e *ProviderError) retryable() bool {
+	return e.Kind == ErrRateLimited || e.Kind == ErrUnavailable || e.Kind == ErrTimeout
+}
+
+// RetryAfter is how long the provider asked callers to wait after err, or
+// 0 when it didn't say.
+func RetryAfter(err error) time.Duration {
+	var pe *ProviderError
+	if errors.As(err, &pe) {
+		return pe.RetryAfter
+	}
+	return 0
+}
+
+// statusKind classifies an HTTP error status.
+func statusKind(status int) error {
+	switch {
+	case status == 401 || status == 403:
+		return ErrNotConfigured
+	case status == 408:
+		return ErrTimeout
+	case status == 429:
+		return ErrRateLimited
+	case status >= 500:
+		return ErrUnavailable
+	}
+	return ErrBadResponse
+}
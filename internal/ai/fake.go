@@ -0,0 +1,82 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FakeClient is a deterministic Parser and Transcriber for tests and local
+// development without network access. It never calls out; the same input
+// always produces the same draft (apart from today's date).
+type FakeClient struct{}
+
+var (
+	fakeAmount = regexp.MustCompile(`\d[\d,]*(?:\.\d+)?`)
+	fakeIncome = regexp.MustCompile(`(?i)\b(received|salary|credited|got|refund|income)\b`)
+)
+
+// Transcribe treats UTF-8 "audio" as the spoken text so tests can post plain
+// text files; anything else yields a fixed transcript.
+func (f *FakeClient) Transcribe(ctx context.Context, filename string, audio []byte, language string) (string, error) {
+	if len(audio) == 0 {
+		return "", fmt.Errorf("empty audio")
+	}
+	if utf8.Valid(audio) {
+		return strings.TrimSpace(string(audio)), nil
+	}
+	return "spent 100 rupees", nil
+}
+
+// ParseText fills amount, type, mode and category with simple keyword
+// matches on the transcript.
+func (f *FakeClient) ParseText(ctx context.Context, transcript, tz string, profile Profile) ([]byte, error) {
+	text := strings.TrimSpace(transcript)
+	if text == "" {
+		return nil, fmt.Errorf("empty transcript")
+	}
+	lower := strings.ToLower(text)
+
+	draft := map[string]any{
+		"stage":       "draft",
+		"type":        "expense",
+		"title":       text,
+		"amount":      nil,
+		"currency":    "INR",
+		"mode":        nil,
+		"category":    nil,
+		"date":        time.Now().In(userLocation(tz)).Format("2006-01-02"),
+		"tags":        []string{},
+		"source_text": text,
+	}
+	if r := []rune(text); len(r) > 40 {
+		draft["title"] = strings.TrimSpace(string(r[:40]))
+	}
+	if m := fakeAmount.FindString(text); m != "" {
+		if v, err := strconv.ParseFloat(strings.ReplaceAll(m, ",", ""), 64); err == nil {
+			draft["amount"] = v
+		}
+	}
+	if fakeIncome.MatchString(text) {
+		draft["type"] = "income"
+	}
+	switch {
+	case strings.Contains(lower, "upi") || strings.Contains(lower, "gpay") || strings.Contains(lower, "paytm"):
+		draft["mode"] = "UPI"
+	case strings.Contains(lower, "card"):
+		draft["mode"] = "Credit Card"
+	case strings.Contains(lower, "cash"):
+		draft["mode"] = "Cash"
+	}
+	for _, c := range profile.Categories {
+		if c != "" && strings.Contains(lower, strings.ToLower(c)) {
+			draft["category"] = c
+			break
+		}
+	}
+	return wrapDrafts(draft)
+}
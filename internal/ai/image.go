@@ -0,0 +1,147 @@
+package ai
+
+import (
+	"context"
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"finance-parser-go/internal/config"
+)
+
+//go:embed image_prompt.txt
+var imagePromptText string
+
+// ImageParser reads a receipt photo or payment screenshot into drafts, in
+// the same {"entries": [...]} form as Parser, with line_items and tax filled
+// when the image shows them.
+type ImageParser interface {
+	ParseImage(ctx context.Context, image []byte, mimeType, tz string, profile Profile) ([]byte, error)
+}
+
+// NewImageParser returns the ImageParser selected by VISION_PROVIDER, or nil
+// when image parsing is turned off ("none", or the offline parser, which
+// cannot read images).
+func NewImageParser(cfg *config.Config) (ImageParser, error) {
+	switch strings.ToLower(cfg.VisionProvider) {
+	case "", "openai":
+		return NewOpenAIClient(cfg), nil
+	case "compatible", "ollama", "llamacpp":
+		return NewCompatibleClient(cfg), nil
+	case "fake":
+		return &FakeClient{}, nil
+	case "none", "offline":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown VISION_PROVIDER %q", cfg.VisionProvider)
+}
+
+// ParseImage sends the image inline as a data URL to the vision model.
+func (c *OpenAIClient) ParseImage(ctx context.Context, image []byte, mimeType, tz string, profile Profile) ([]byte, error) {
+	if err := c.checkKey(); err != nil {
+		return nil, err
+	}
+
+	dataURL := "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(image)
+	return c.chat(ctx, c.visionModel, []map[string]any{
+		{"role": "system", "content": c.systemPrompt(ctx) + "\n" + imagePromptText},
+		{"role": "user", "content": []map[string]any{
+			{"type": "text", "text": userMessage("(see image)", tz, profile)},
+			{"type": "image_url", "image_url": map[string]string{"url": dataURL}},
+		}},
+	})
+}
+
+var (
+	fakeItemLine  = regexp.MustCompile(`^(.+?)\s+(?:(\d+)\s*x\s+)?(?:₹|rs\.?)?\s*(\d[\d,]*(?:\.\d+)?)$`)
+	fakeTotalLine = regexp.MustCompile(`(?i)^(?:(?:grand|net|bill|order|invoice)\s+)?total\b|^(?:amount|net|total)\s+(?:due|payable|paid)\b|^(?:net\s+amount|to\s+pay|balance\s+due)\b`)
+	// Subtotals repeat the items and round-offs carry no sign; neither is an
+	// item nor the total.
+	fakeSubtotalLine = regexp.MustCompile(`(?i)^(?:sub\s*-?\s*total|item\s+total|total\s+(?:before|excl)|round(?:ing)?[\s-]*off)`)
+	fakeTaxLine      = regexp.MustCompile(`(?i)^(?:tax(?:es)?|gst|cgst|sgst|igst|utgst|vat|cess|service\s+(?:tax|charge))\b`)
+	fakeDiscountLine = regexp.MustCompile(`(?i)^(?:discount|coupon|promo|offer|savings?|you\s+saved)\b`)
+)
+
+// ParseImage treats a UTF-8 "image" as the text of a receipt, so tests can
+// post a plain-text file: the first line is the merchant, "total" lines set
+// the amount, "tax"/"gst" lines add up to the tax, subtotal and discount
+// lines are left out of the items and other "name [qty x] amount" lines
+// become line items. Binary images get a draft asking for the amount.
+func (f *FakeClient) ParseImage(ctx context.Context, image []byte, mimeType, tz string, profile Profile) ([]byte, error) {
+	if len(image) == 0 {
+		return nil, fmt.Errorf("empty image")
+	}
+	draft := map[string]any{
+		"stage":       "draft",
+		"type":        "expense",
+		"title":       "Receipt",
+		"amount":      nil,
+		"currency":    "INR",
+		"mode":        nil,
+		"category":    nil,
+		"date":        time.Now().In(userLocation(tz)).Format("2006-01-02"),
+		"tags":        []string{},
+		"line_items":  []map[string]any{},
+		"tax":         nil,
+		"source_text": "receipt image",
+	}
+	if !utf8.Valid(image) {
+		draft["needs_confirmation"] = map[string]any{"amount": true}
+		draft["clarifications"] = []string{"How much was it?"}
+		return wrapDrafts(draft)
+	}
+
+	var items []map[string]any
+	var sum, discount float64
+	lines := strings.Split(strings.TrimSpace(string(image)), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if i == 0 {
+			draft["merchant"], draft["title"] = line, line
+			continue
+		}
+		m := fakeItemLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(m[3], ",", ""), 64)
+		if err != nil {
+			continue
+		}
+		switch {
+		case fakeSubtotalLine.MatchString(line):
+		case fakeTotalLine.MatchString(line):
+			draft["amount"] = amount
+		case fakeTaxLine.MatchString(line):
+			// CGST and SGST are printed separately.
+			tax, _ := draft["tax"].(float64)
+			draft["tax"] = tax + amount
+		case fakeDiscountLine.MatchString(line):
+			discount += amount
+		default:
+			qty := 1
+			if m[2] != "" {
+				qty, _ = strconv.Atoi(m[2])
+			}
+			items = append(items, map[string]any{"name": m[1], "quantity": qty, "amount": amount})
+			sum += amount
+		}
+	}
+	if items != nil {
+		draft["line_items"] = items
+	}
+	if draft["amount"] == nil && sum > 0 {
+		if tax, ok := draft["tax"].(float64); ok {
+			sum += tax
+		}
+		draft["amount"] = math.Round((sum-discount)*100) / 100
+	}
+	draft["source_text"] = strings.Join(lines, " / ")
+	return wrapDrafts(draft)
+}
@@ -0,0 +1,68 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestFakeParseImageReceipt(t *testing.T) {
+	receipt := `Cafe Mocha
+Cappuccino 2 x 360
+Sandwich 220
+Subtotal 580
+CGST 2.5% 14.50
+SGST 2.5% 14.50
+Discount 50
+Round off 0.50
+Grand Total 559.50`
+
+	tests := []struct {
+		name    string
+		receipt string
+		amount  float64
+	}{
+		{"printed total", receipt, 559.50},
+		// Without a total line the amount is items + tax - discounts.
+		{"no total", receipt[:len(receipt)-len("\nGrand Total 559.50")], 559},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := (&FakeClient{}).ParseImage(context.Background(), []byte(tt.receipt), "text/plain", "Asia/Kolkata", Profile{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out struct {
+				Entries []struct {
+					Merchant  string  `json:"merchant"`
+					Amount    float64 `json:"amount"`
+					Tax       float64 `json:"tax"`
+					LineItems []struct {
+						Name     string  `json:"name"`
+						Quantity int     `json:"quantity"`
+						Amount   float64 `json:"amount"`
+					} `json:"line_items"`
+				} `json:"entries"`
+			}
+			if err := json.Unmarshal(raw, &out); err != nil {
+				t.Fatal(err)
+			}
+			d := out.Entries[0]
+			if d.Merchant != "Cafe Mocha" {
+				t.Errorf("merchant = %q", d.Merchant)
+			}
+			if d.Amount != tt.amount {
+				t.Errorf("amount = %v, want %v", d.Amount, tt.amount)
+			}
+			if d.Tax != 29 {
+				t.Errorf("tax = %v, want 29", d.Tax)
+			}
+			if len(d.LineItems) != 2 {
+				t.Fatalf("line items = %+v, want Cappuccino and Sandwich", d.LineItems)
+			}
+			if it := d.LineItems[0]; it.Name != "Cappuccino" || it.Quantity != 2 || it.Amount != 360 {
+				t.Errorf("first item = %+v", it)
+			}
+		})
+	}
+}
@@ -0,0 +1,612 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance-parser-go/internal/merchants"
+)
+
+// OfflineParser is a rule-based Parser for the common spoken grammar, e.g.
+// "spent 500 on lunch at Subway via UPI yesterday". It needs no network and
+// is used when the LLM is unavailable, or as a fast path in front of it.
+// Confidence values are kept low so the app still asks the user to check.
+type OfflineParser struct{}
+
+const (
+	offlineConfidence     = 0.6 // fields matched by an explicit keyword
+	offlineWeakConfidence = 0.4 // fields inferred or defaulted
+)
+
+var (
+	// ₹500, Rs. 1,200, 2.5k, 1 lakh, 500 rupees.
+	offlineAmount = regexp.MustCompile(`(?i)(₹|\brs\.?|\binr)?\s*(\d+(?:,\d{2,3})*(?:\.\d+)?)\s*(k|thousand|lakhs?|lacs?|l|crores?|cr)?\b\s*(rupees?|rs\.?|bucks)?`)
+	// Numbers that are part of a date or time, not an amount.
+	offlineNotAmount = regexp.MustCompile(`(?i)^\s*(?:(?:st|nd|rd|th|am|pm)\b|:\d|days?\s+ago|/\d|-\d|` + offlineMonthName + `)`)
+	offlineIncome    = regexp.MustCompile(`(?i)\b(received|receive|got paid|got\s+(?:₹|rs\.?)?\s*\d[\d,.]*\s*(?:k|rupees?|rs)?\s+(?:from|back)|salary|credited|refund(?:ed)?|earned|income|cashback|paid me|gave me|returned|borrowed)\b`)
+	// Money lent out or borrowed; borrowing is also caught by offlineIncome.
+	offlineLending = regexp.MustCompile(`(?i)\b(lent|lend|lending|loaned|borrowed|as (?:a )?loan|udhaar|udhar)\b`)
+	// The person money was lent to: "lent 500 to Rahul".
+	offlineLentTo = regexp.MustCompile(`(?i)\b(?:lent|lend|loaned|gave)\b.*?\bto\s+([a-z][a-z.'-]*(?:\s+[a-z][a-z.'-]*)??)(?:\s+(?:on|via|using|with|by|for|yesterday|today|tonight|this|last|and|as)\b|[,.;!]|$)`)
+	// Words after which a number is the amount: "a 2 litre coke for 90".
+	offlineAmountCue = regexp.MustCompile(`(?i)\b(?:for|of|paid|pay|spent|spend|cost|costs|costing|worth|lent|borrowed|received|got)\s*$`)
+
+	offlineDaysAgo  = regexp.MustCompile(`(?i)\b(\d+|one|two|three|four|five|six|seven)\s+days?\s+ago\b`)
+	offlineWeekday  = regexp.MustCompile(`(?i)\b(last|on|this past)?\s*(monday|tuesday|wednesday|thursday|friday|saturday|sunday)\b`)
+	offlineISODate  = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	offlineDayMonth = regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th)?\s+(?:of\s+)?` + offlineMonthName)
+	offlineMonthDay = regexp.MustCompile(`(?i)\b` + offlineMonthName + `\s+(\d{1,2})(?:st|nd|rd|th)?\b`)
+	offlineSlash    = regexp.MustCompile(`\b(\d{1,2})/(\d{1,2})(?:/(\d{2,4}))?\b`)
+	offlineClock    = regexp.MustCompile(`(?i)\b(?:at\s+)?(\d{1,2})(?::(\d{2}))?\s*(am|pm)\b|\b([01]?\d|2[0-3]):([0-5]\d)\b`)
+
+	// "at Subway", "from Amazon" up to the next function word.
+	offlineMerchant = regexp.MustCompile(`(?i)\b(?:at|from|@)\s+([a-z0-9][a-z0-9&'.-]*(?:\s+[a-z0-9&'.-]+){0,3}?)(?:\s+(?:on|via|using|with|by|for|yesterday|today|tonight|this|last|and|in|through|paid|to)\b|[,.;!]|$)`)
+)
+
+// offlineMonthName captures a month written in full or as its usual
+// abbreviation; "junk" or "market" are not months.
+const offlineMonthName = `(jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sep(?:t(?:ember)?)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\b`
+
+var offlineNumberWords = map[string]int{"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7}
+
+var offlineMonths = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April, "may": time.May, "jun": time.June,
+	"jul": time.July, "aug": time.August, "sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+// offlineModes is checked in order; the first keyword found wins.
+var offlineModes = []struct {
+	mode, network string
+	words         []string
+}{
+	{"UPI", "", []string{"upi", "gpay", "google pay", "phonepe", "phone pe", "paytm upi", "bhim"}},
+	{"Wallets", "", []string{"wallet", "paytm", "amazon pay", "mobikwik"}},
+	{"Credit Card", "Amex", []string{"amex", "american express"}},
+	{"Credit Card", "Visa", []string{"visa"}},
+	{"Credit Card", "Mastercard", []string{"mastercard", "master card"}},
+	{"Credit Card", "Rupay", []string{"rupay"}},
+	{"Credit Card", "", []string{"credit card", "card"}},
+	{"Cash", "", []string{"cash"}},
+}
+
+// offlineCategories maps keywords to the default category names; the result
+// is only used when the user has a category of that name.
+var offlineCategories = []struct {
+	category string
+	words    []string
+}{
+	{"Food", []string{"lunch", "dinner", "breakfast", "snack", "snacks", "coffee", "tea", "chai", "food", "groceries", "grocery", "restaurant", "pizza", "burger", "biryani", "vegetables", "fruits", "milk"}},
+	{"Travel", []string{"cab", "taxi", "auto", "rickshaw", "metro", "train", "flight", "bus", "petrol", "diesel", "fuel", "parking", "toll", "ride", "trip"}},
+	{"Shopping", []string{"clothes", "shirt", "shoes", "shopping", "jeans", "dress", "electronics", "laptop"}},
+	{"Bills", []string{"electricity", "rent", "bill", "recharge", "internet", "wifi", "broadband", "gas", "water", "subscription", "emi", "insurance"}},
+	{"Family/Gifts", []string{"gift", "birthday", "anniversary", "wife", "husband", "mom", "dad", "mother", "father", "kids", "son", "daughter"}},
+}
+
+// ParseText builds one draft per clause that carries an amount. It only
+// fails when no amount can be found, since a draft without one is not useful.
+func (p *OfflineParser) ParseText(ctx context.Context, transcript, tz string, profile Profile) ([]byte, error) {
+	drafts, ok := p.ParseAll(transcript, time.Now().In(userLocation(tz)), profile.Categories)
+	if !ok {
+		return nil, fmt.Errorf("offline parser: no amount found")
+	}
+	return wrapDrafts(drafts...)
+}
+
+// offlineClauseBreak splits "200 for auto and 450 for lunch, got 5000 from dad".
+var offlineClauseBreak = regexp.MustCompile(`(?i)\s*(?:;|,\s|\band then\b|\bthen\b|\band\b|\balso\b)\s*`)
+
+// ParseAll splits the transcript into clauses, each with its own amount, and
+// parses them separately. A date or mode stated once applies to every
+// clause that doesn't name its own.
+func (p *OfflineParser) ParseAll(transcript string, now time.Time, categories []string) ([]map[string]any, bool) {
+	text := strings.TrimSpace(transcript)
+	type span struct {
+		start, end int
+		carried    bool // starts with pieces that had no amount of their own
+	}
+	var spans []span
+	start, lead := 0, -1
+	for _, sep := range append(offlineClauseBreak.FindAllStringIndex(text, -1), []int{len(text), len(text)}) {
+		if part := strings.TrimSpace(text[start:sep[0]]); part != "" {
+			_, ok := offlineFindAmount(part)
+			switch {
+			case !ok && len(spans) > 0:
+				// Pieces without an amount ("... at Subway and Starbucks")
+				// stay with the clause before, separator included.
+				spans[len(spans)-1].end = sep[0]
+			case !ok:
+				// Leading ones ("bread and butter 50") go with the next.
+				if lead < 0 {
+					lead = start
+				}
+			case lead >= 0:
+				spans = append(spans, span{lead, sep[0], true})
+				lead = -1
+			default:
+				spans = append(spans, span{start, sep[0], false})
+			}
+		}
+		start = sep[1]
+	}
+	if len(spans) <= 1 {
+		draft, ok := p.Parse(transcript, now, categories)
+		if len(spans) == 1 && spans[0].carried {
+			offlineCarryTitle(draft, transcript)
+		}
+		return []map[string]any{draft}, ok
+	}
+
+	whole, _ := p.Parse(transcript, now, categories)
+	wholeConf, _ := whole["confidence"].(map[string]any)
+	var drafts []map[string]any
+	for _, sp := range spans {
+		clause := strings.TrimSpace(text[sp.start:sp.end])
+		draft, ok := p.Parse(clause, now, categories)
+		if !ok {
+			continue
+		}
+		if sp.carried {
+			offlineCarryTitle(draft, clause)
+		}
+		conf, _ := draft["confidence"].(map[string]any)
+		if conf["date"] != offlineConfidence && wholeConf["date"] == offlineConfidence {
+			draft["date"] = whole["date"]
+			conf["date"] = offlineWeakConfidence
+		}
+		if draft["mode"] == nil && whole["mode"] != nil {
+			draft["mode"], draft["card_network"] = whole["mode"], whole["card_network"]
+			conf["mode"] = offlineWeakConfidence
+		}
+		drafts = append(drafts, draft)
+	}
+	return drafts, len(drafts) > 0
+}
+
+// Parse returns the draft as a map along with whether an amount was found.
+func (p *OfflineParser) Parse(transcript string, now time.Time, categories []string) (map[string]any, bool) {
+	text := strings.TrimSpace(transcript)
+	lower := strings.ToLower(text)
+
+	confidence := map[string]any{}
+	needs := map[string]any{}
+	clarifications := []string{}
+
+	draft := map[string]any{
+		"stage":        "draft",
+		"type":         "expense",
+		"title":        nil,
+		"amount":       nil,
+		"currency":     "INR",
+		"mode":         nil,
+		"card_network": nil,
+		"category":     nil,
+		"merchant":     nil,
+		"purpose_type": "normal_spend",
+		"tags":         []string{},
+		"date":         now.Format("2006-01-02"),
+		"time":         nil,
+		"source_text":  transcript,
+	}
+
+	// Amount.
+	amount, found := offlineFindAmount(text)
+	if found {
+		draft["amount"] = amount
+		confidence["amount"] = offlineConfidence
+	} else {
+		needs["amount"] = true
+		clarifications = append(clarifications, "How much was it?")
+	}
+
+	// Type.
+	confidence["type"] = offlineWeakConfidence
+	if offlineIncome.MatchString(text) {
+		draft["type"] = "income"
+		confidence["type"] = offlineConfidence
+		if strings.Contains(lower, "refund") || strings.Contains(lower, "returned") {
+			draft["purpose_type"] = "refund"
+		}
+	}
+	lending := offlineLending.MatchString(text)
+	if lending {
+		draft["purpose_type"] = "lending"
+		draft["tags"] = []string{"Lending"}
+	}
+
+	// Date and time.
+	if date, ok := offlineFindDate(lower, now); ok {
+		draft["date"] = date.Format("2006-01-02")
+		confidence["date"] = offlineConfidence
+	} else {
+		confidence["date"] = offlineWeakConfidence
+	}
+	if clock, ok := offlineFindTime(lower); ok {
+		draft["time"] = clock
+	}
+
+	// Mode.
+	for _, m := range offlineModes {
+		if offlineContainsAny(lower, m.words) {
+			draft["mode"] = m.mode
+			if m.network != "" {
+				draft["card_network"] = m.network
+			}
+			confidence["mode"] = offlineConfidence
+			break
+		}
+	}
+
+	// Merchant: a known merchant anywhere in the text, else "at X".
+	var merchantCategory string
+	for _, m := range merchants.Builtin {
+		names := append([]string{m.Name}, m.Aliases...)
+		if offlineContainsAny(lower, lowerAll(names)) {
+			draft["merchant"] = m.Name
+			merchantCategory = m.DefaultCategory
+			confidence["merchant"] = offlineConfidence
+			break
+		}
+	}
+	if draft["merchant"] == nil && lending {
+		if m := offlineLentTo.FindStringSubmatch(text); m != nil && !offlineIsFunctionWord(m[1]) {
+			draft["merchant"] = offlineTitleCase(m[1])
+			confidence["merchant"] = offlineWeakConfidence
+		}
+	}
+	if draft["merchant"] == nil {
+		if m := offlineMerchant.FindStringSubmatch(text); m != nil && !offlineIsFunctionWord(m[1]) {
+			draft["merchant"] = offlineTitleCase(m[1])
+			confidence["merchant"] = offlineWeakConfidence
+		}
+	}
+
+	// Category: the user's own category named outright, then keywords, then
+	// the merchant's default.
+	var keyword string
+	for _, c := range categories {
+		if c != "" && offlineContainsAny(lower, []string{strings.ToLower(c)}) {
+			draft["category"] = c
+			confidence["category"] = offlineConfidence
+			break
+		}
+	}
+	for _, kc := range offlineCategories {
+		for _, w := range kc.words {
+			if offlineContainsAny(lower, []string{w}) {
+				if keyword == "" {
+					keyword = w
+				}
+				if draft["category"] == nil {
+					if c := offlinePickCategory(kc.category, categories); c != "" {
+						draft["category"] = c
+						confidence["category"] = offlineWeakConfidence
+					}
+				}
+			}
+		}
+	}
+	if draft["category"] == nil && merchantCategory != "" {
+		if c := offlinePickCategory(merchantCategory, categories); c != "" {
+			draft["category"] = c
+			confidence["category"] = offlineWeakConfidence
+		}
+	}
+	if draft["category"] == nil {
+		needs["category"] = true
+		clarifications = append(clarifications, "Which category should this go under?")
+	}
+
+	// Title, e.g. "Lunch at Subway", kept to the prompt's 15 characters.
+	title := ""
+	merchant, _ := draft["merchant"].(string)
+	switch {
+	case keyword != "" && merchant != "" && !strings.EqualFold(keyword, merchant):
+		title = offlineTitleCase(keyword) + " at " + merchant
+		if len([]rune(title)) > 15 {
+			title = merchant
+		}
+	case merchant != "":
+		title = merchant
+	case keyword != "":
+		title = offlineTitleCase(keyword)
+	case draft["type"] == "income":
+		title = "Income"
+	default:
+		title = "Expense"
+	}
+	if r := []rune(title); len(r) > 15 {
+		title = strings.TrimSpace(string(r[:15]))
+	}
+	draft["title"] = title
+
+	draft["confidence"] = confidence
+	if len(needs) > 0 {
+		draft["needs_confirmation"] = needs
+		draft["clarifications"] = clarifications
+	}
+	return draft, found
+}
+
+// Confident reports whether drafts from ParseAll are complete enough to
+// skip the LLM: each has an amount plus a merchant or category.
+func (p *OfflineParser) Confident(drafts []map[string]any) bool {
+	for _, d := range drafts {
+		if d["amount"] == nil || d["merchant"] == nil && d["category"] == nil {
+			return false
+		}
+	}
+	return len(drafts) > 0
+}
+
+// offlineFindAmount returns the amount in text. A number marked as money,
+// by a currency, a multiplier or a word like "for" or "paid" before it, wins
+// over a bare one, so "a 2 litre coke for 90" is 90; otherwise the first
+// number that isn't part of a date or time is used.
+func offlineFindAmount(text string) (float64, bool) {
+	bare, found := 0.0, false
+	for _, m := range offlineAmount.FindAllStringSubmatchIndex(text, -1) {
+		numEnd := m[5]
+		rest := text[numEnd:]
+		currency := m[2] >= 0 || m[8] >= 0
+		multiplier := ""
+		if m[6] >= 0 {
+			multiplier = strings.ToLower(text[m[6]:m[7]])
+		}
+		if !currency && multiplier == "" && (offlineNotAmount.MatchString(rest) || m[4] > 0 && strings.ContainsRune("-/:.", rune(text[m[4]-1]))) {
+			continue
+		}
+		// A bare "l" is only a multiplier right after the digits ("2l").
+		if multiplier == "l" && m[6] != numEnd {
+			multiplier = ""
+		}
+		v, err := strconv.ParseFloat(strings.ReplaceAll(text[m[4]:numEnd], ",", ""), 64)
+		if err != nil || v <= 0 {
+			continue
+		}
+		switch {
+		case multiplier == "k" || multiplier == "thousand":
+			v *= 1e3
+		case strings.HasPrefix(multiplier, "lakh"), strings.HasPrefix(multiplier, "lac"), multiplier == "l":
+			v *= 1e5
+		case strings.HasPrefix(multiplier, "crore"), multiplier == "cr":
+			v *= 1e7
+		}
+		if currency || multiplier != "" || offlineAmountCue.MatchString(text[:m[4]]) {
+			return v, true
+		}
+		if !found {
+			bare, found = v, true
+		}
+	}
+	return bare, found
+}
+
+func offlineFindDate(lower string, now time.Time) (time.Time, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch {
+	case strings.Contains(lower, "day before yesterday"):
+		return today.AddDate(0, 0, -2), true
+	case strings.Contains(lower, "yesterday"):
+		return today.AddDate(0, 0, -1), true
+	case strings.Contains(lower, "today") || strings.Contains(lower, "tonight") || strings.Contains(lower, "this morning"):
+		return today, true
+	}
+	if m := offlineDaysAgo.FindStringSubmatch(lower); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			n = offlineNumberWords[m[1]]
+		}
+		return today.AddDate(0, 0, -n), true
+	}
+	if m := offlineISODate.FindStringSubmatch(lower); m != nil {
+		if t, err := time.ParseInLocation("2006-01-02", m[0], now.Location()); err == nil {
+			return t, true
+		}
+	}
+	if m := offlineDayMonth.FindStringSubmatch(lower); m != nil {
+		day, _ := strconv.Atoi(m[1])
+		return offlinePastDate(today, offlineMonths[m[2][:3]], day)
+	}
+	if m := offlineMonthDay.FindStringSubmatch(lower); m != nil {
+		day, _ := strconv.Atoi(m[2])
+		return offlinePastDate(today, offlineMonths[m[1][:3]], day)
+	}
+	if m := offlineSlash.FindStringSubmatch(lower); m != nil {
+		// Indian order: day/month.
+		day, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		if month >= 1 && month <= 12 {
+			if m[3] != "" {
+				year, _ := strconv.Atoi(m[3])
+				if year < 100 {
+					year += 2000
+				}
+				t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, now.Location())
+				if t.Day() == day {
+					return t, true
+				}
+			} else {
+				return offlinePastDate(today, time.Month(month), day)
+			}
+		}
+	}
+	if m := offlineWeekday.FindStringSubmatch(lower); m != nil {
+		var target time.Weekday
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if strings.EqualFold(d.String(), m[2]) {
+				target = d
+			}
+		}
+		// The most recent such day before today; "last" never means today.
+		diff := (int(today.Weekday()) - int(target) + 7) % 7
+		if diff == 0 {
+			diff = 7
+		}
+		return today.AddDate(0, 0, -diff), true
+	}
+	return time.Time{}, false
+}
+
+// offlinePastDate places day/month in the current year, or last year when
+// that would be in the future.
+func offlinePastDate(today time.Time, month time.Month, day int) (time.Time, bool) {
+	if month == 0 || day < 1 || day > 31 {
+		return time.Time{}, false
+	}
+	t := time.Date(today.Year(), month, day, 0, 0, 0, 0, today.Location())
+	if t.Day() != day {
+		return time.Time{}, false
+	}
+	if t.After(today) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t, true
+}
+
+func offlineFindTime(lower string) (string, bool) {
+	m := offlineClock.FindStringSubmatch(lower)
+	if m == nil {
+		return "", false
+	}
+	if m[1] != "" {
+		h, _ := strconv.Atoi(m[1])
+		if h < 1 || h > 12 {
+			return "", false
+		}
+		min := 0
+		if m[2] != "" {
+			min, _ = strconv.Atoi(m[2])
+		}
+		if m[3] == "pm" && h != 12 {
+			h += 12
+		}
+		if m[3] == "am" && h == 12 {
+			h = 0
+		}
+		return fmt.Sprintf("%02d:%02d", h, min), true
+	}
+	h, _ := strconv.Atoi(m[4])
+	min, _ := strconv.Atoi(m[5])
+	return fmt.Sprintf("%02d:%02d", h, min), true
+}
+
+func offlinePickCategory(name string, categories []string) string {
+	for _, c := range categories {
+		if strings.EqualFold(c, name) {
+			return c
+		}
+	}
+	return ""
+}
+
+// offlineContainsAny matches whole words or phrases only, so "card" does not
+// match "discarded".
+func offlineContainsAny(lower string, words []string) bool {
+	for _, w := range words {
+		if w == "" {
+			continue
+		}
+		for i := 0; ; {
+			j := strings.Index(lower[i:], w)
+			if j < 0 {
+				break
+			}
+			start, end := i+j, i+j+len(w)
+			if (start == 0 || !isWordByte(lower[start-1])) && (end == len(lower) || !isWordByte(lower[end])) {
+				return true
+			}
+			i = start + 1
+		}
+	}
+	return false
+}
+
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= '0' && b <= '9'
+}
+
+func lowerAll(in []string) []string {
+	out := make([]string, len(in))
+	for i, s := range in {
+		out[i] = strings.ToLower(s)
+	}
+	return out
+}
+
+func offlineIsFunctionWord(s string) bool {
+	switch strings.ToLower(strings.Fields(s)[0]) {
+	case "the", "a", "an", "my", "home", "work", "office", "night", "noon", "morning", "evening":
+		return true
+	}
+	return s[0] >= '0' && s[0] <= '9'
+}
+
+// offlineCarryTitle names a draft after the items in its clause when Parse
+// found no keyword or merchant to do it: "bread and butter 50" becomes
+// "Bread & Butter" rather than "Expense".
+func offlineCarryTitle(draft map[string]any, clause string) {
+	if title := draft["title"]; title != "Expense" && title != "Income" {
+		return
+	}
+	var words []string
+	for _, w := range strings.Fields(offlineAmount.ReplaceAllString(clause, " ")) {
+		switch strings.ToLower(w) {
+		case "and":
+			w = "&"
+		case "for", "of", "on", "spent", "paid", "bought", "rupees", "rs", "rs.":
+			continue
+		}
+		if n := len([]rune(strings.Join(append(words, w), " "))); n > 15 {
+			break
+		}
+		words = append(words, w)
+	}
+	for len(words) > 0 && words[len(words)-1] == "&" {
+		words = words[:len(words)-1]
+	}
+	if len(words) > 0 {
+		draft["title"] = offlineTitleCase(strings.Join(words, " "))
+	}
+}
+
+func offlineTitleCase(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		if w == strings.ToLower(w) {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// FallbackParser runs Primary and falls back to Offline when it fails. With
+// FastPath set, a confident offline parse is returned without calling
+// Primary at all.
+type FallbackParser struct {
+	Primary  Parser
+	Offline  *OfflineParser
+	FastPath bool
+}
+
+func (f *FallbackParser) ParseText(ctx context.Context, transcript, tz string, profile Profile) ([]byte, error) {
+	if f.FastPath {
+		drafts, ok := f.Offline.ParseAll(transcript, time.Now().In(userLocation(tz)), profile.Categories)
+		if ok && f.Offline.Confident(drafts) {
+			answeredBy(ctx, BackendFastPath)
+			return wrapDrafts(drafts...)
+		}
+	}
+	out, err := f.Primary.ParseText(ctx, transcript, tz, profile)
+	if err == nil {
+		return out, nil
+	}
+	if offline, oerr := f.Offline.ParseText(ctx, transcript, tz, profile); oerr == nil {
+		answeredBy(ctx, BackendFallback)
+		return offline, nil
+	}
+	return nil, err
+}
@@ -0,0 +1,137 @@
+package ai
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// offlineNow is a Tuesday.
+var offlineNow = time.Date(2024, time.August, 20, 10, 0, 0, 0, time.UTC)
+
+var offlineTestCategories = []string{"Food", "Travel", "Shopping", "Bills", "Family/Gifts"}
+
+// offlineDraft is the part of an offline draft the tests check; nil fields
+// read as "".
+type offlineDraft struct {
+	amount                                                float64
+	typ, date, mode, network, merchant, category, purpose string
+}
+
+func readOfflineDraft(d map[string]any) offlineDraft {
+	str := func(v any) string {
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(v)
+	}
+	amount, _ := d["amount"].(float64)
+	return offlineDraft{amount, str(d["type"]), str(d["date"]), str(d["mode"]), str(d["card_network"]), str(d["merchant"]), str(d["category"]), str(d["purpose_type"])}
+}
+
+func TestOfflineParse(t *testing.T) {
+	tests := []struct {
+		text string
+		want offlineDraft
+	}{
+		{"spent 500 on lunch at Subway via UPI yesterday", offlineDraft{500, "expense", "2024-08-19", "UPI", "", "Subway", "Food", "normal_spend"}},
+		// Amounts.
+		{"₹1,200 for groceries at DMart", offlineDraft{1200, "expense", "2024-08-20", "", "", "DMart", "Food", "normal_spend"}},
+		{"paid 2.5k rent by card", offlineDraft{2500, "expense", "2024-08-20", "Credit Card", "", "", "Bills", "normal_spend"}},
+		{"bought a new laptop for 1 lakh", offlineDraft{100000, "expense", "2024-08-20", "", "", "", "Shopping", "normal_spend"}},
+		{"salary of 85k credited", offlineDraft{85000, "income", "2024-08-20", "", "", "", "", "normal_spend"}},
+		{"500 rupees on 3 samosas", offlineDraft{500, "expense", "2024-08-20", "", "", "", "", "normal_spend"}},
+		// A number marked by "for" is the amount, not the one before it.
+		{"bought a 2 litre coke for 90", offlineDraft{90, "expense", "2024-08-20", "", "", "", "", "normal_spend"}},
+		// Numbers in dates and times are not amounts.
+		{"coffee at starbucks 350 at 9 am", offlineDraft{350, "expense", "2024-08-20", "", "", "Starbucks", "Food", "normal_spend"}},
+		// Relative and named dates.
+		{"petrol 3000 cash 3 days ago", offlineDraft{3000, "expense", "2024-08-17", "Cash", "", "", "Travel", "normal_spend"}},
+		{"got 5000 from dad last monday", offlineDraft{5000, "income", "2024-08-19", "", "", "Dad", "Family/Gifts", "normal_spend"}},
+		{"uber ride 250 on 5th August", offlineDraft{250, "expense", "2024-08-05", "", "", "Uber", "Travel", "normal_spend"}},
+		// Modes and card networks.
+		{"Rs. 799 netflix subscription via amex", offlineDraft{799, "expense", "2024-08-20", "Credit Card", "Amex", "Netflix", "Bills", "normal_spend"}},
+		// Lending.
+		{"lent 500 to Rahul", offlineDraft{500, "expense", "2024-08-20", "", "", "Rahul", "", "lending"}},
+		{"borrowed 2000 from Amit yesterday", offlineDraft{2000, "income", "2024-08-19", "", "", "Amit", "", "lending"}},
+	}
+	p := &OfflineParser{}
+	for _, tt := range tests {
+		d, ok := p.Parse(tt.text, offlineNow, offlineTestCategories)
+		if !ok {
+			t.Errorf("%q: no amount found", tt.text)
+			continue
+		}
+		if got := readOfflineDraft(d); got != tt.want {
+			t.Errorf("%q:\n got  %+v\n want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestOfflineParseNoAmount(t *testing.T) {
+	d, ok := (&OfflineParser{}).Parse("lunch at subway yesterday", offlineNow, offlineTestCategories)
+	if ok || d["amount"] != nil {
+		t.Fatalf("amount %v found in a transcript without one", d["amount"])
+	}
+	if needs, _ := d["needs_confirmation"].(map[string]any); needs["amount"] != true {
+		t.Errorf("needs_confirmation = %v, want amount", d["needs_confirmation"])
+	}
+}
+
+func TestOfflineParseLendingTags(t *testing.T) {
+	d, _ := (&OfflineParser{}).Parse("lent 500 to Rahul", offlineNow, nil)
+	if tags, _ := d["tags"].([]string); len(tags) != 1 || tags[0] != "Lending" {
+		t.Errorf("tags = %v, want [Lending]", d["tags"])
+	}
+}
+
+func TestOfflineParseAll(t *testing.T) {
+	type want struct {
+		typ    string
+		amount float64
+	}
+	tests := []struct {
+		text   string
+		drafts []want
+	}{
+		{"Paid 200 for auto and 450 for lunch, got 5000 from dad", []want{{"expense", 200}, {"expense", 450}, {"income", 5000}}},
+		{"spent 300 on petrol; 1.2k on groceries then 99 for netflix", []want{{"expense", 300}, {"expense", 1200}, {"expense", 99}}},
+		{"got salary of 85k also paid 20k rent", []want{{"income", 85000}, {"expense", 20000}}},
+		// Pieces without an amount stay with their clause.
+		{"coffee at Starbucks and Costa 600", []want{{"expense", 600}}},
+		{"bread and butter 50 and milk 30", []want{{"expense", 50}, {"expense", 30}}},
+		{"lunch 250", []want{{"expense", 250}}},
+	}
+	p := &OfflineParser{}
+	for _, tt := range tests {
+		drafts, ok := p.ParseAll(tt.text, offlineNow, offlineTestCategories)
+		if !ok {
+			t.Errorf("%q: no drafts", tt.text)
+			continue
+		}
+		if len(drafts) != len(tt.drafts) {
+			t.Errorf("%q: %d drafts, want %d", tt.text, len(drafts), len(tt.drafts))
+			continue
+		}
+		for i, w := range tt.drafts {
+			if d := drafts[i]; d["type"] != w.typ || d["amount"] != w.amount {
+				t.Errorf("%q draft %d: %v %v, want %s %v", tt.text, i, d["type"], d["amount"], w.typ, w.amount)
+			}
+		}
+	}
+}
+
+func TestOfflineParseAllCarriesDateAndMode(t *testing.T) {
+	drafts, _ := (&OfflineParser{}).ParseAll("yesterday 200 for auto and 450 for lunch via UPI", offlineNow, offlineTestCategories)
+	if len(drafts) != 2 {
+		t.Fatalf("%d drafts, want 2", len(drafts))
+	}
+	for i, d := range drafts {
+		if d["date"] != "2024-08-19" || d["mode"] != "UPI" {
+			t.Errorf("draft %d: date %v mode %v, want 2024-08-19 UPI", i, d["date"], d["mode"])
+		}
+	}
+	if drafts[0]["category"] != "Travel" || drafts[1]["category"] != "Food" {
+		t.Errorf("categories %v, %v; want Travel, Food", drafts[0]["category"], drafts[1]["category"])
+	}
+}
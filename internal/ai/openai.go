@@ -1,105 +1,213 @@
 package ai
 
 import (
+	"bufio"
 	"bytes"
 	"context"
-	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"strings"
-	"time"
 
 	"finance-parser-go/internal/config"
 )
 
-//go:embed prompt.txt
-var promptText string
-
+// OpenAIClient talks to the OpenAI chat/completions and audio/transcriptions
+// endpoints, or to any server that mirrors them.
 type OpenAIClient struct {
-	cfg  *config.Config
-	http *http.Client
+	baseURL      string
+	apiKey       string
+	model        string
+	whisperModel string
+	visionModel  string
+	prompt       string // default prompt version, see WithPromptVersion
+	requireKey   bool   // hosted OpenAI needs a key; local servers usually don't
+	retries      int    // extra attempts after a retryable failure, see send
+	http         *http.Client
+	streamHTTP   *http.Client // no overall timeout, see streamClient
+	breaker      *breaker
 }
 
 func NewOpenAIClient(cfg *config.Config) *OpenAIClient {
-	return &OpenAIClient{cfg: cfg, http: &http.Client{}}
+	return &OpenAIClient{
+		baseURL:      cfg.OpenAIBaseURL,
+		apiKey:       cfg.OpenAIKey,
+		model:        cfg.OpenAILlmModel,
+		whisperModel: cfg.OpenAIWhisper,
+		visionModel:  cfg.OpenAIVision,
+		prompt:       cfg.PromptVersion,
+		requireKey:   true,
+		retries:      cfg.LLMMaxRetries,
+		http:         newHTTPClient(cfg),
+		streamHTTP:   streamClient,
+		breaker:      newBreaker(cfg),
+	}
+}
+
+// NewCompatibleClient targets a local OpenAI-compatible server such as
+// Ollama (http://localhost:11434/v1) or llama.cpp's server. The API key is
+// optional and only sent when set.
+func NewCompatibleClient(cfg *config.Config) *OpenAIClient {
+	return &OpenAIClient{
+		baseURL:      strings.TrimRight(cfg.LLMBaseURL, "/"),
+		apiKey:       cfg.LLMAPIKey,
+		model:        cfg.LLMModel,
+		whisperModel: cfg.LLMWhisper,
+		visionModel:  cfg.LLMVision,
+		prompt:       cfg.PromptVersion,
+		retries:      cfg.LLMMaxRetries,
+		http:         newHTTPClient(cfg),
+		streamHTTP:   streamClient,
+		breaker:      newBreaker(cfg),
+	}
+}
+
+func (c *OpenAIClient) authorize(req *http.Request) {
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+}
+
+// systemPrompt returns the parse prompt selected for this call.
+func (c *OpenAIClient) systemPrompt(ctx context.Context) string {
+	return parsePrompts[PromptVersionFrom(ctx, c.prompt)]
 }
 
-func (c *OpenAIClient) Transcribe(ctx context.Context, filename string, audio []byte) (string, error) {
-	if c.cfg.OpenAIKey == "" {
-		return "", fmt.Errorf("OPENAI_API_KEY missing")
+func (c *OpenAIClient) checkKey() error {
+	if c.requireKey && c.apiKey == "" {
+		return fmt.Errorf("%w: OPENAI_API_KEY missing", ErrNotConfigured)
+	}
+	return nil
+}
+
+func (c *OpenAIClient) Transcribe(ctx context.Context, filename string, audio []byte, language string) (string, error) {
+	if err := c.checkKey(); err != nil {
+		return "", err
 	}
 	var buf bytes.Buffer
 	mw := multipart.NewWriter(&buf)
-	fw, _ := mw.CreateFormFile("file", filename)
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return "", err
+	}
 	if _, err := io.Copy(fw, bytes.NewReader(audio)); err != nil {
 		return "", err
 	}
-	_ = mw.WriteField("model", c.cfg.OpenAIWhisper)
-	_ = mw.Close()
-
-	req, _ := http.NewRequestWithContext(ctx, "POST", c.cfg.OpenAIBaseURL+"/audio/transcriptions", &buf)
-	req.Header.Set("Authorization", "Bearer "+c.cfg.OpenAIKey)
-	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if err := mw.WriteField("model", c.whisperModel); err != nil {
+		return "", err
+	}
+	if language != "" {
+		if err := mw.WriteField("language", language); err != nil {
+			return "", err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+	form := buf.Bytes()
 
-	resp, err := c.http.Do(req)
+	resp, err := c.send(ctx, "whisper", c.http, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/audio/transcriptions", bytes.NewReader(form))
+		if err != nil {
+			return nil, err
+		}
+		c.authorize(req)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		return req, nil
+	})
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
-		b, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("whisper error: %s", string(b))
-	}
 
 	var out struct {
-		Text string `json:"text"`
+		Text  string      `json:"text"`
+		Usage *tokenUsage `json:"usage"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return "", err
+		return "", &ProviderError{Op: "whisper", Kind: ErrBadResponse, Err: err}
 	}
+	out.Usage.record(ctx)
 	return strings.TrimSpace(out.Text), nil
 }
 
-func (c *OpenAIClient) ParseText(ctx context.Context, transcript, tz string) ([]byte, error) {
-	if c.cfg.OpenAIKey == "" {
-		return nil, fmt.Errorf("OPENAI_API_KEY missing")
+func (c *OpenAIClient) ParseText(ctx context.Context, transcript, tz string, profile Profile) ([]byte, error) {
+	if err := c.checkKey(); err != nil {
+		return nil, err
 	}
 
-	// Calculate current date in User's TZ
-	loc, err := time.LoadLocation(tz)
-	if err != nil {
-		loc, _ = time.LoadLocation("Asia/Kolkata")
+	return c.chat(ctx, c.model, []map[string]any{
+		{"role": "system", "content": c.systemPrompt(ctx)},
+		{"role": "user", "content": userMessage(transcript, tz, profile)},
+	})
+}
+
+// Clarify continues the original exchange: the prior draft is replayed as
+// the assistant's reply and the user's answer follows it.
+func (c *OpenAIClient) Clarify(ctx context.Context, transcript string, prior []byte, answer, tz string, profile Profile) ([]byte, error) {
+	if err := c.checkKey(); err != nil {
+		return nil, err
 	}
-	if loc == nil {
-		loc = time.FixedZone("IST", 5*3600+1800)
+
+	return c.chat(ctx, c.model, []map[string]any{
+		{"role": "system", "content": c.systemPrompt(ctx)},
+		{"role": "user", "content": userMessage(transcript, tz, profile)},
+		{"role": "assistant", "content": string(prior)},
+		{"role": "user", "content": clarifyMessage(answer)},
+	})
+}
+
+// ParseTextStream is ParseText with the reply streamed back as it is
+// generated.
+func (c *OpenAIClient) ParseTextStream(ctx context.Context, transcript, tz string, profile Profile, onDelta func(string)) ([]byte, error) {
+	if err := c.checkKey(); err != nil {
+		return nil, err
 	}
-	nowStr := time.Now().In(loc).Format("2006-01-02")
 
+	return c.chatStream(ctx, []map[string]any{
+		{"role": "system", "content": c.systemPrompt(ctx)},
+		{"role": "user", "content": userMessage(transcript, tz, profile)},
+	}, onDelta)
+}
+
+func (c *OpenAIClient) postChat(ctx context.Context, model string, messages []map[string]any, stream bool) (*http.Response, error) {
 	body := map[string]any{
-		"model":           c.cfg.OpenAILlmModel,
+		"model":           model,
 		"response_format": map[string]string{"type": "json_object"},
-		"messages": []map[string]string{
-			{"role": "system", "content": promptText},
-			{"role": "user", "content": fmt.Sprintf("Context: Timezone is %s. Today is %s.\nText: %s", tz, nowStr, transcript)},
-		},
+		"messages":        messages,
+	}
+	if stream {
+		body["stream"] = true
+		body["stream_options"] = map[string]bool{"include_usage": true}
 	}
-	b, _ := json.Marshal(body)
-	req, _ := http.NewRequestWithContext(ctx, "POST", c.cfg.OpenAIBaseURL+"/chat/completions", bytes.NewReader(b))
-	req.Header.Set("Authorization", "Bearer "+c.cfg.OpenAIKey)
-	req.Header.Set("Content-Type", "application/json")
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	client := c.http
+	if stream {
+		client = c.streamHTTP
+	}
+	return c.send(ctx, "llm", client, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		c.authorize(req)
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+}
 
-	resp, err := c.http.Do(req)
+func (c *OpenAIClient) chat(ctx context.Context, model string, messages []map[string]any) ([]byte, error) {
+	resp, err := c.postChat(ctx, model, messages, false)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
-		bs, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("llm error: %s", string(bs))
-	}
 
 	var out struct {
 		Choices []struct {
@@ -107,12 +215,66 @@ func (c *OpenAIClient) ParseText(ctx context.Context, transcript, tz string) ([]
 				Content string `json:"content"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage *tokenUsage `json:"usage"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return nil, err
+		return nil, &ProviderError{Op: "llm", Kind: ErrBadResponse, Err: err}
 	}
+	out.Usage.record(ctx)
 	if len(out.Choices) == 0 {
-		return nil, fmt.Errorf("no choices")
+		return nil, &ProviderError{Op: "llm", Kind: ErrBadResponse, Err: errors.New("no choices")}
 	}
 	return []byte(out.Choices[0].Message.Content), nil
 }
+
+// chatStream reads a streamed completion: server-sent "data:" lines, each
+// carrying a content delta, ending with "data: [DONE]".
+func (c *OpenAIClient) chatStream(ctx context.Context, messages []map[string]any, onDelta func(string)) ([]byte, error) {
+	resp, err := c.postChat(ctx, c.model, messages, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var content strings.Builder
+	var usage *tokenUsage // sent in the last chunk when the server supports it
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *tokenUsage `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, &ProviderError{Op: "llm", Kind: ErrBadResponse, Err: fmt.Errorf("stream: %w", err)}
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+		content.WriteString(chunk.Choices[0].Delta.Content)
+		onDelta(chunk.Choices[0].Delta.Content)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	usage.record(ctx)
+	if content.Len() == 0 {
+		return nil, &ProviderError{Op: "llm", Kind: ErrBadResponse, Err: errors.New("no choices")}
+	}
+	return []byte(content.String()), nil
+}
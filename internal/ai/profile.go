@@ -0,0 +1,75 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Profile is the per-user context sent with every parse so the model can
+// name the user's own accounts, merchants and categories.
+type Profile struct {
+	Categories  []string
+	Accounts    []ProfileAccount
+	Merchants   []ProfileMerchant // most frequent first
+	DefaultMode string            // Cash, UPI, Credit Card or Wallets; empty when unknown
+}
+
+type ProfileAccount struct {
+	Name     string
+	Type     string // credit, debit, wallet, upi, bank, other
+	Provider string
+	Last4    string
+}
+
+// ProfileMerchant is a merchant the user pays often and the category they
+// usually file it under.
+type ProfileMerchant struct {
+	Name     string
+	Category string
+}
+
+// describe renders the profile as the context lines of the user message.
+// Sections without data are left out.
+func (p Profile) describe() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Categories: %s\n", strings.Join(p.Categories, ", "))
+
+	if len(p.Accounts) > 0 {
+		parts := make([]string, 0, len(p.Accounts))
+		for _, a := range p.Accounts {
+			details := []string{}
+			if a.Type != "" {
+				details = append(details, a.Type)
+			}
+			if a.Provider != "" && !strings.EqualFold(a.Provider, a.Name) {
+				details = append(details, a.Provider)
+			}
+			if a.Last4 != "" {
+				details = append(details, "ending "+a.Last4)
+			}
+			s := a.Name
+			if len(details) > 0 {
+				s += " (" + strings.Join(details, ", ") + ")"
+			}
+			parts = append(parts, s)
+		}
+		fmt.Fprintf(&b, "Accounts: %s\n", strings.Join(parts, "; "))
+	}
+
+	if len(p.Merchants) > 0 {
+		parts := make([]string, 0, len(p.Merchants))
+		for _, m := range p.Merchants {
+			if m.Category != "" {
+				parts = append(parts, m.Name+" -> "+m.Category)
+			} else {
+				parts = append(parts, m.Name)
+			}
+		}
+		fmt.Fprintf(&b, "Frequent merchants: %s\n", strings.Join(parts, ", "))
+	}
+
+	if p.DefaultMode != "" {
+		fmt.Fprintf(&b, "Default mode: %s\n", p.DefaultMode)
+	}
+	return b.String()
+}
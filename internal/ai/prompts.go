@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// Parse prompts live in prompts/parse_<version>.txt. Edit a prompt by adding
+// a new version next to it, so the change can be evaluated with
+// cmd/parseeval and rolled out with PROMPT_VERSION.
+//
+//go:embed prompts/parse_*.txt
+var promptFiles embed.FS
+
+// DefaultPromptVersion is used when PROMPT_VERSION is not set.
+const DefaultPromptVersion = "v1"
+
+var parsePrompts = func() map[string]string {
+	files, _ := promptFiles.ReadDir("prompts")
+	out := map[string]string{}
+	for _, f := range files {
+		version := strings.TrimSuffix(strings.TrimPrefix(f.Name(), "parse_"), ".txt")
+		b, _ := promptFiles.ReadFile("prompts/" + f.Name())
+		out[version] = string(b)
+	}
+	return out
+}()
+
+// PromptVersions lists the available parse prompt versions.
+func PromptVersions() []string {
+	out := make([]string, 0, len(parsePrompts))
+	for v := range parsePrompts {
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func KnownPromptVersion(version string) bool {
+	_, ok := parsePrompts[version]
+	return ok
+}
+
+// PromptFingerprint identifies a prompt version by name and content, so
+// cached or recorded replies are not reused after the file is edited.
+func PromptFingerprint(version string) string {
+	sum := sha256.Sum256([]byte(parsePrompts[version]))
+	return version + "-" + hex.EncodeToString(sum[:6])
+}
+
+type promptVersionKey struct{}
+
+// WithPromptVersion selects the parse prompt for calls made with ctx.
+func WithPromptVersion(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, promptVersionKey{}, version)
+}
+
+// PromptVersionFrom returns the version selected on ctx, or fallback when
+// none (or an unknown one) is.
+func PromptVersionFrom(ctx context.Context, fallback string) string {
+	if v, _ := ctx.Value(promptVersionKey{}).(string); KnownPromptVersion(v) {
+		return v
+	}
+	if KnownPromptVersion(fallback) {
+		return fallback
+	}
+	return DefaultPromptVersion
+}
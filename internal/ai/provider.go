@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"finance-parser-go/internal/config"
+)
+
+// Transcriber turns recorded audio into text. language is an ISO-639-1
+// hint such as "hi" or "en"; "" lets the model detect it.
+type Transcriber interface {
+	Transcribe(ctx context.Context, filename string, audio []byte, language string) (string, error)
+}
+
+// Parser turns a transcript into draft entries, returned as JSON matching
+// schemas/parse_response.schema.json: {"entries": [...]} with one draft per
+// transaction mentioned. Use SplitDrafts to read the result.
+type Parser interface {
+	ParseText(ctx context.Context, transcript, tz string, profile Profile) ([]byte, error)
+}
+
+// NewParser returns the Parser selected by LLM_PROVIDER, wrapped with the
+// offline rule parser according to OFFLINE_PARSER.
+func NewParser(cfg *config.Config) (Parser, error) {
+	if !KnownPromptVersion(cfg.PromptVersion) {
+		return nil, fmt.Errorf("unknown PROMPT_VERSION %q (have %s)", cfg.PromptVersion, strings.Join(PromptVersions(), ", "))
+	}
+	var primary Parser
+	switch strings.ToLower(cfg.LLMProvider) {
+	case "", "openai":
+		primary = NewOpenAIClient(cfg)
+	case "compatible", "ollama", "llamacpp":
+		primary = NewCompatibleClient(cfg)
+	case "fake":
+		return &FakeClient{}, nil
+	case "offline":
+		return &OfflineParser{}, nil
+	default:
+		return nil, fmt.Errorf("unknown LLM_PROVIDER %q", cfg.LLMProvider)
+	}
+
+	switch strings.ToLower(cfg.OfflineParser) {
+	case "off":
+		return primary, nil
+	case "", "fallback":
+		return &FallbackParser{Primary: primary, Offline: &OfflineParser{}}, nil
+	case "fastpath":
+		return &FallbackParser{Primary: primary, Offline: &OfflineParser{}, FastPath: true}, nil
+	}
+	return nil, fmt.Errorf("unknown OFFLINE_PARSER %q", cfg.OfflineParser)
+}
+
+// NewTranscriber returns the Transcriber selected by STT_PROVIDER.
+func NewTranscriber(cfg *config.Config) (Transcriber, error) {
+	switch strings.ToLower(cfg.STTProvider) {
+	case "", "openai":
+		return NewOpenAIClient(cfg), nil
+	case "compatible", "ollama", "llamacpp":
+		return NewCompatibleClient(cfg), nil
+	case "fake":
+		return &FakeClient{}, nil
+	}
+	return nil, fmt.Errorf("unknown STT_PROVIDER %q", cfg.STTProvider)
+}
+
+// userLocation resolves tz, falling back to IST.
+func userLocation(tz string) *time.Location {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		loc, _ = time.LoadLocation("Asia/Kolkata")
+	}
+	if loc == nil {
+		loc = time.FixedZone("IST", 5*3600+1800)
+	}
+	return loc
+}
+
+// userMessage is the per-request message sent after the system prompt.
+func userMessage(transcript, tz string, profile Profile) string {
+	nowStr := time.Now().In(userLocation(tz)).Format("2006-01-02")
+	return fmt.Sprintf("Context: Timezone is %s. Today is %s.\n%sText: %s", tz, nowStr, profile.describe(), transcript)
+}
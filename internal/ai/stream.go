@@ -0,0 +1,133 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// StreamParser is implemented by parsers that can hand out the model's
+// reply while it is still being generated. onDelta receives each chunk of
+// text; the return value is the complete reply, as from ParseText.
+type StreamParser interface {
+	ParseTextStream(ctx context.Context, transcript, tz string, profile Profile, onDelta func(string)) ([]byte, error)
+}
+
+// ParseStream parses transcript and calls onDraft with each entry as soon
+// as its JSON object is complete. Parsers that can't stream report all
+// their drafts once parsing finishes. The drafts are raw model output; the
+// caller still post-processes the returned reply.
+func ParseStream(ctx context.Context, p Parser, transcript, tz string, profile Profile, onDraft func(int, map[string]any)) ([]byte, error) {
+	sc := &entryScanner{}
+	var out []byte
+	var err error
+	if sp, ok := p.(StreamParser); ok {
+		out, err = sp.ParseTextStream(ctx, transcript, tz, profile, func(delta string) {
+			sc.write(delta, onDraft)
+		})
+	} else {
+		out, err = p.ParseText(ctx, transcript, tz, profile)
+	}
+	if err != nil || sc.emitted > 0 {
+		return out, err
+	}
+
+	// Nothing was streamed: a non-streaming parser, a fallback reply, or a
+	// single bare object.
+	if drafts, derr := SplitDrafts(out); derr == nil {
+		for i, d := range drafts {
+			onDraft(i, d)
+		}
+	}
+	return out, nil
+}
+
+// ParseTextStream streams from the primary parser when it can. A fast-path
+// offline parse, or the offline fallback after a failure, is returned whole.
+func (f *FallbackParser) ParseTextStream(ctx context.Context, transcript, tz string, profile Profile, onDelta func(string)) ([]byte, error) {
+	if f.FastPath {
+		drafts, ok := f.Offline.ParseAll(transcript, time.Now().In(userLocation(tz)), profile.Categories)
+		if ok && f.Offline.Confident(drafts) {
+			answeredBy(ctx, BackendFastPath)
+			return wrapDrafts(drafts...)
+		}
+	}
+
+	var out []byte
+	var err error
+	if sp, ok := f.Primary.(StreamParser); ok {
+		out, err = sp.ParseTextStream(ctx, transcript, tz, profile, onDelta)
+	} else {
+		out, err = f.Primary.ParseText(ctx, transcript, tz, profile)
+	}
+	if err == nil {
+		return out, nil
+	}
+	if offline, oerr := f.Offline.ParseText(ctx, transcript, tz, profile); oerr == nil {
+		answeredBy(ctx, BackendFallback)
+		return offline, nil
+	}
+	return nil, err
+}
+
+// entryScanner finds complete entry objects in a reply that arrives in
+// pieces: the elements of {"entries": [...]} or of a bare top-level array.
+type entryScanner struct {
+	buf      []byte
+	stack    []byte // open '{' and '[' outside strings
+	inString bool
+	escaped  bool
+	start    int
+	emitted  int
+}
+
+func (s *entryScanner) write(chunk string, onDraft func(int, map[string]any)) {
+	for i := 0; i < len(chunk); i++ {
+		ch := chunk[i]
+		pos := len(s.buf)
+		s.buf = append(s.buf, ch)
+
+		if s.inString {
+			switch {
+			case s.escaped:
+				s.escaped = false
+			case ch == '\\':
+				s.escaped = true
+			case ch == '"':
+				s.inString = false
+			}
+			continue
+		}
+
+		switch ch {
+		case '"':
+			s.inString = true
+		case '{', '[':
+			if ch == '{' && s.atEntryLevel() {
+				s.start = pos
+			}
+			s.stack = append(s.stack, ch)
+		case '}', ']':
+			if len(s.stack) == 0 {
+				continue
+			}
+			s.stack = s.stack[:len(s.stack)-1]
+			if ch == '}' && s.atEntryLevel() {
+				var draft map[string]any
+				if err := json.Unmarshal(s.buf[s.start:pos+1], &draft); err == nil {
+					onDraft(s.emitted, draft)
+					s.emitted++
+				}
+			}
+		}
+	}
+}
+
+// atEntryLevel reports whether an object opened now would be an entry.
+func (s *entryScanner) atEntryLevel() bool {
+	switch string(s.stack) {
+	case "[", "{[":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"testing"
+)
+
+func TestEntryScanner(t *testing.T) {
+	tests := []struct {
+		name   string
+		reply  string
+		titles []string
+	}{
+		{
+			name:   "entries object",
+			reply:  `{"entries":[{"title":"Lunch","amount":500,"tags":[]},{"title":"Auto","amount":200,"tags":["Travel"]}]}`,
+			titles: []string{"Lunch", "Auto"},
+		},
+		{
+			name:   "bare array",
+			reply:  `[{"title":"Tea","amount":20}]`,
+			titles: []string{"Tea"},
+		},
+		{
+			name:   "braces and brackets inside strings",
+			reply:  `{"entries":[{"title":"Lunch {at} [Subway]","notes":"}]}","amount":500},{"title":"}","amount":1}]}`,
+			titles: []string{"Lunch {at} [Subway]", "}"},
+		},
+		{
+			name:   "escaped quotes and backslashes",
+			reply:  `{"entries":[{"title":"The \"Good\" Cafe","notes":"c:\\dir\\","amount":300},{"title":"Auto \\\"x","amount":2}]}`,
+			titles: []string{`The "Good" Cafe`, `Auto \"x`},
+		},
+		{
+			name:   "nested objects are part of their entry",
+			reply:  `{"entries":[{"title":"Cafe","line_items":[{"name":"Tea"},{"name":"Cake"}],"confidence":{"amount":0.9}}],"usage":{"x":1}}`,
+			titles: []string{"Cafe"},
+		},
+		{
+			name:   "prose and fences around the JSON",
+			reply:  "Here you go:\n```json\n{\"entries\":[{\"title\":\"Tea\"}]}\n```",
+			titles: []string{"Tea"},
+		},
+	}
+	for _, tt := range tests {
+		// Every chunk size, so entries, strings and escapes are split at
+		// every possible byte.
+		for size := 1; size <= len(tt.reply); size++ {
+			sc := &entryScanner{}
+			var titles []string
+			onDraft := func(i int, d map[string]any) {
+				if i != len(titles) {
+					t.Errorf("%s, chunks of %d: draft index %d, want %d", tt.name, size, i, len(titles))
+				}
+				title, _ := d["title"].(string)
+				titles = append(titles, title)
+			}
+			for i := 0; i < len(tt.reply); i += size {
+				sc.write(tt.reply[i:min(i+size, len(tt.reply))], onDraft)
+			}
+			if len(titles) != len(tt.titles) {
+				t.Errorf("%s, chunks of %d: drafts %q, want %q", tt.name, size, titles, tt.titles)
+				continue
+			}
+			for i := range titles {
+				if titles[i] != tt.titles[i] {
+					t.Errorf("%s, chunks of %d: draft %d title %q, want %q", tt.name, size, i, titles[i], tt.titles[i])
+				}
+			}
+		}
+	}
+}
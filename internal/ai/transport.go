@@ -0,0 +1,205 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"finance-parser-go/internal/config"
+)
+
+const (
+	backoffBase  = 500 * time.Millisecond
+	backoffMax   = 8 * time.Second
+	maxRetryWait = 30 * time.Second // a longer Retry-After is passed on to the caller instead
+)
+
+// transport is shared by every client so connections to a provider are
+// pooled across the parser, transcriber and vision model.
+var transport = &http.Transport{
+	Proxy:                 http.ProxyFromEnvironment,
+	DialContext:           (&net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          100,
+	MaxIdleConnsPerHost:   20,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: time.Second,
+}
+
+func newHTTPClient(cfg *config.Config) *http.Client {
+	return &http.Client{Transport: transport, Timeout: time.Duration(cfg.LLMTimeoutSec) * time.Second}
+}
+
+// streamClient is used for streamed replies. A Client.Timeout also covers
+// reading the body, which would cut a stream off mid-reply; streams end at
+// the request context's deadline instead.
+var streamClient = &http.Client{Transport: transport}
+
+// breaker stops calls to a provider after threshold failures in a row.
+// Once cooldown has passed one call is let through; its success closes the
+// circuit again.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func newBreaker(cfg *config.Config) *breaker {
+	return &breaker{threshold: cfg.LLMBreakerFailures, cooldown: time.Duration(cfg.LLMBreakerCooldownSec) * time.Second}
+}
+
+// allow reports whether a call may go out and, when not, how long until
+// one may.
+func (b *breaker) allow() (time.Duration, bool) {
+	if b == nil || b.threshold <= 0 {
+		return 0, true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return 0, true
+	}
+	if wait := time.Until(b.openUntil); wait > 0 {
+		return wait, false
+	}
+	if b.probing {
+		return b.cooldown, false
+	}
+	b.probing = true
+	return 0, true
+}
+
+// record counts the outcome of an allowed call. Errors the provider isn't
+// to blame for, such as a cancelled request, don't count either way.
+func (b *breaker) record(op string, err error) {
+	if b == nil || b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	var pe *ProviderError
+	switch {
+	case err == nil, errors.As(err, &pe) && !pe.retryable():
+		b.failures = 0
+	case pe != nil:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.openUntil = time.Now().Add(b.cooldown)
+			log.Printf("%s circuit open for %s after %d failures: %v", op, b.cooldown, b.failures, err)
+		}
+	}
+}
+
+// send makes the request built by newReq with client, retrying rate
+// limits, 5xx replies and connection errors with jittered exponential
+// backoff, or after the provider's Retry-After. It fails fast while the
+// circuit is open. A 2xx response is returned with its body unread; any
+// other reply becomes a *ProviderError.
+func (c *OpenAIClient) send(ctx context.Context, op string, client *http.Client, newReq func() (*http.Request, error)) (*http.Response, error) {
+	if wait, ok := c.breaker.allow(); !ok {
+		return nil, &ProviderError{Op: op, Kind: ErrUnavailable, RetryAfter: wait, Err: errors.New("circuit open")}
+	}
+	resp, err := c.sendRetrying(ctx, op, client, newReq)
+	c.breaker.record(op, err)
+	return resp, err
+}
+
+func (c *OpenAIClient) sendRetrying(ctx context.Context, op string, client *http.Client, newReq func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err == nil && resp.StatusCode < 300 {
+			return resp, nil
+		}
+		perr := providerError(ctx, op, resp, err)
+		if perr == nil {
+			return nil, err // cancelled by the caller
+		}
+		if !perr.retryable() || attempt >= c.retries {
+			return nil, perr
+		}
+
+		wait := perr.RetryAfter
+		if wait == 0 {
+			wait = backoff(attempt)
+		} else if wait > maxRetryWait {
+			return nil, perr
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return nil, perr
+		}
+		log.Printf("%s retry %d in %s: %v", op, attempt+1, wait.Round(time.Millisecond), perr)
+		select {
+		case <-ctx.Done():
+			return nil, perr
+		case <-time.After(wait):
+		}
+	}
+}
+
+// providerError describes a failed attempt, closing the reply's body. It
+// is nil when the caller cancelled the request.
+func providerError(ctx context.Context, op string, resp *http.Response, err error) *ProviderError {
+	if err != nil {
+		var ne net.Error
+		switch {
+		case errors.Is(ctx.Err(), context.Canceled):
+			return nil
+		case errors.Is(err, context.DeadlineExceeded), errors.As(err, &ne) && ne.Timeout():
+			return &ProviderError{Op: op, Kind: ErrTimeout, Err: err}
+		}
+		return &ProviderError{Op: op, Kind: ErrUnavailable, Err: err}
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+	return &ProviderError{
+		Op:         op,
+		Kind:       statusKind(resp.StatusCode),
+		Status:     resp.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// backoff is the wait before retry attempt+1: full jitter over an
+// exponentially growing window.
+func backoff(attempt int) time.Duration {
+	window := backoffBase << attempt
+	if window > backoffMax || window <= 0 {
+		window = backoffMax
+	}
+	return time.Duration(rand.Int63n(int64(window))) + 50*time.Millisecond
+}
+
+// parseRetryAfter reads a Retry-After header in seconds or as an HTTP date.
+func parseRetryAfter(h string) time.Duration {
+	if h == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(strings.TrimSpace(h)); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
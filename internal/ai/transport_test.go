@@ -0,0 +1,200 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"finance-parser-go/internal/config"
+)
+
+// chatReply is a non-streamed chat completion carrying content.
+func chatReply(w http.ResponseWriter, content string) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"choices":[{"message":{"content":%q}}]}`, content)
+}
+
+// testClient points a compatible client at srv.
+func testClient(srv *httptest.Server, retries, breakerFailures int) *OpenAIClient {
+	return NewCompatibleClient(&config.Config{
+		LLMBaseURL:         srv.URL,
+		LLMModel:           "test",
+		LLMTimeoutSec:      5,
+		LLMMaxRetries:      retries,
+		LLMBreakerFailures: breakerFailures,
+	})
+}
+
+func TestSendRetriesRateLimitAfterRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, `{"error":"slow down"}`, http.StatusTooManyRequests)
+			return
+		}
+		chatReply(w, `{"entries":[]}`)
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	out, err := testClient(srv, 2, 0).chat(context.Background(), "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"entries":[]}` || calls.Load() != 2 {
+		t.Errorf("reply %s after %d calls, want 2", out, calls.Load())
+	}
+	if waited := time.Since(start); waited < time.Second {
+		t.Errorf("retried after %s, before the 1s Retry-After", waited)
+	}
+}
+
+func TestSendRateLimitPastDeadline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	// A Retry-After beyond the caller's deadline is passed on, not waited out.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err := testClient(srv, 2, 0).chat(ctx, "test", nil)
+	var pe *ProviderError
+	if !errors.As(err, &pe) || pe.Kind != ErrRateLimited || pe.RetryAfter != 10*time.Second {
+		t.Fatalf("err = %v, want rate limited with a 10s Retry-After", err)
+	}
+}
+
+func TestSendRetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			http.Error(w, "upstream", http.StatusBadGateway)
+			return
+		}
+		chatReply(w, "ok")
+	}))
+	defer srv.Close()
+
+	out, err := testClient(srv, 2, 0).chat(context.Background(), "test", nil)
+	if err != nil || string(out) != "ok" {
+		t.Fatalf("chat = %q, %v after %d calls", out, err, calls.Load())
+	}
+
+}
+
+func TestSendDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	if _, err := testClient(srv, 2, 0).chat(context.Background(), "test", nil); err == nil || calls.Load() != 1 {
+		t.Errorf("400 made %d calls (err %v), want 1", calls.Load(), err)
+	}
+}
+
+func TestBreakerTripsAndHalfOpens(t *testing.T) {
+	var calls atomic.Int32
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		chatReply(w, "ok")
+	}))
+	defer srv.Close()
+
+	c := testClient(srv, 0, 2)
+	c.breaker.cooldown = 100 * time.Millisecond
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := c.chat(ctx, "test", nil); err == nil {
+			t.Fatal("failing provider succeeded")
+		}
+	}
+
+	// Open: calls fail without reaching the provider.
+	_, err := c.chat(ctx, "test", nil)
+	var pe *ProviderError
+	if !errors.As(err, &pe) || pe.Kind != ErrUnavailable || pe.RetryAfter <= 0 || calls.Load() != 2 {
+		t.Fatalf("open circuit: err %v after %d calls", err, calls.Load())
+	}
+
+	// Half-open: after the cooldown one probe goes out; a second caller is
+	// still turned away while it is in flight.
+	time.Sleep(c.breaker.cooldown)
+	if _, ok := c.breaker.allow(); !ok {
+		t.Fatal("no probe allowed after the cooldown")
+	}
+	if _, ok := c.breaker.allow(); ok {
+		t.Error("second call allowed while probing")
+	}
+	c.breaker.record("llm", &ProviderError{Kind: ErrUnavailable})
+	if _, ok := c.breaker.allow(); ok {
+		t.Error("circuit closed after a failed probe")
+	}
+
+	// A successful probe closes it.
+	time.Sleep(c.breaker.cooldown)
+	healthy.Store(true)
+	if out, err := c.chat(ctx, "test", nil); err != nil || string(out) != "ok" {
+		t.Fatalf("probe = %q, %v", out, err)
+	}
+	if out, err := c.chat(ctx, "test", nil); err != nil || string(out) != "ok" {
+		t.Errorf("after the probe = %q, %v", out, err)
+	}
+}
+
+func TestStreamOutlivesClientTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, part := range []string{`{"entries":`, `[]`, `}`} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", part)
+			w.(http.Flusher).Flush()
+			time.Sleep(600 * time.Millisecond)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	// The stream takes longer than the one-second per-call timeout; only
+	// the context's deadline bounds it.
+	c := testClient(srv, 0, 0)
+	c.http.Timeout = time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var deltas int
+	out, err := c.chatStream(ctx, nil, func(string) { deltas++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"entries":[]}` || deltas != 3 {
+		t.Errorf("stream = %s in %d deltas", out, deltas)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("7"); got != 7*time.Second {
+		t.Errorf("seconds: %s", got)
+	}
+	if got := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)); got < 58*time.Second || got > time.Minute {
+		t.Errorf("HTTP date: %s", got)
+	}
+	for _, h := range []string{"", "0", "-3", "soon", "Mon, 01 Jan 2001 00:00:00 GMT"} {
+		if got := parseRetryAfter(h); got != 0 {
+			t.Errorf("parseRetryAfter(%q) = %s, want 0", h, got)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package ai
+
+import (
+	"context"
+	"sync"
+)
+
+// Usage adds up what model calls report. Attach one to a context with
+// WithUsage; calls made with that context add to it.
+type Usage struct {
+	mu               sync.Mutex
+	Calls            int
+	PromptTokens     int
+	CompletionTokens int
+}
+
+type usageKey struct{}
+
+func WithUsage(ctx context.Context, u *Usage) context.Context {
+	return context.WithValue(ctx, usageKey{}, u)
+}
+
+// addUsage records one call against the context's Usage, if any.
+func addUsage(ctx context.Context, prompt, completion int) {
+	u, _ := ctx.Value(usageKey{}).(*Usage)
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.Calls++
+	u.PromptTokens += prompt
+	u.CompletionTokens += completion
+}
+
+// tokenUsage is the "usage" object of chat and transcription responses.
+// Chat uses prompt/completion, newer transcription models input/output.
+type tokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	InputTokens      int `json:"input_tokens"`
+	OutputTokens     int `json:"output_tokens"`
+}
+
+func (t *tokenUsage) record(ctx context.Context) {
+	if t == nil {
+		addUsage(ctx, 0, 0)
+		return
+	}
+	addUsage(ctx, t.PromptTokens+t.InputTokens, t.CompletionTokens+t.OutputTokens)
+}
@@ -0,0 +1,169 @@
+// Package audio checks voice uploads before they are sent to speech-to-text:
+// it sniffs the real format from the bytes, converts formats the model
+// can't read with a Transcoder, and rejects clips that are empty, silent,
+// or longer than allowed.
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Format is an audio container, named by its usual file extension.
+type Format string
+
+const (
+	WAV     Format = "wav"
+	MP3     Format = "mp3"
+	OGG     Format = "ogg"
+	FLAC    Format = "flac"
+	WebM    Format = "webm"
+	M4A     Format = "m4a"
+	AAC     Format = "aac" // raw ADTS
+	AMR     Format = "amr"
+	CAF     Format = "caf"
+	ThreeGP Format = "3gp"
+)
+
+// supported are the formats Whisper-style transcription endpoints accept.
+var supported = map[Format]bool{WAV: true, MP3: true, OGG: true, FLAC: true, WebM: true, M4A: true}
+
+var mimeTypes = map[Format]string{
+	WAV: "audio/wav", MP3: "audio/mpeg", OGG: "audio/ogg", FLAC: "audio/flac", WebM: "audio/webm",
+	M4A: "audio/mp4", AAC: "audio/aac", AMR: "audio/amr", CAF: "audio/x-caf", ThreeGP: "audio/3gpp",
+}
+
+// Supported reports whether speech-to-text reads f as is.
+func (f Format) Supported() bool { return supported[f] }
+
+// MIME is the format's media type.
+func (f Format) MIME() string { return mimeTypes[f] }
+
+// Reasons an upload is refused. Prepare wraps them with details.
+var (
+	ErrEmpty       = errors.New("empty audio")
+	ErrUnsupported = errors.New("unsupported audio format")
+	ErrTooLong     = errors.New("audio too long")
+	ErrTooShort    = errors.New("audio too short")
+	ErrSilent      = errors.New("no speech in audio")
+	ErrUnmeasured  = errors.New("audio length could not be measured")
+	ErrTranscode   = errors.New("audio could not be converted")
+)
+
+// MinDuration is the shortest clip worth transcribing; anything shorter is
+// an accidental tap.
+const MinDuration = 300 * time.Millisecond
+
+// Clip is an upload ready for speech-to-text.
+type Clip struct {
+	Filename   string // extension matches Format; Whisper goes by it
+	Data       []byte
+	Format     Format
+	Duration   time.Duration // 0 when the container doesn't say
+	Transcoded bool          // converted from Source
+	Source     Format        // format of the upload
+}
+
+// Sniff identifies the container from its leading bytes.
+func Sniff(data []byte) (Format, error) {
+	has := func(off int, magic string) bool {
+		return len(data) >= off+len(magic) && string(data[off:off+len(magic)]) == magic
+	}
+	switch {
+	case len(data) == 0:
+		return "", ErrEmpty
+	case has(0, "RIFF") && has(8, "WAVE"):
+		return WAV, nil
+	case has(0, "OggS"):
+		return OGG, nil
+	case has(0, "fLaC"):
+		return FLAC, nil
+	case has(0, "\x1a\x45\xdf\xa3"):
+		return WebM, nil
+	case has(0, "#!AMR"):
+		return AMR, nil
+	case has(0, "caff"):
+		return CAF, nil
+	case has(4, "ftyp"):
+		if has(8, "3gp") || has(8, "3g2") {
+			return ThreeGP, nil
+		}
+		return M4A, nil
+	case has(0, "ID3"):
+		return MP3, nil
+	case len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0:
+		if data[1]&0x06 == 0 { // layer bits unset: ADTS AAC
+			return AAC, nil
+		}
+		return MP3, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupported, http.DetectContentType(data))
+}
+
+// Limits bound what Prepare accepts.
+type Limits struct {
+	MaxDuration time.Duration // 0 is unlimited
+}
+
+// Prepare sniffs data, converts it with t when speech-to-text can't read
+// it, and checks its length and loudness. Only PCM WAV shows its loudness
+// without decoding, and not every container records its length; for the
+// rest a copy decoded with t is measured. With a MaxDuration set, a clip
+// whose length can't be measured is refused. The clip is returned with
+// whatever was learned even when it is refused, so callers can report the
+// format and duration. t may be nil.
+func Prepare(ctx context.Context, filename string, data []byte, t Transcoder, lim Limits) (*Clip, error) {
+	format, err := Sniff(data)
+	if err != nil {
+		return nil, err
+	}
+	clip := &Clip{Data: data, Format: format, Source: format}
+	if !format.Supported() {
+		if t == nil {
+			return clip, fmt.Errorf("%w: %s", ErrUnsupported, format)
+		}
+		out, to, err := t.Transcode(ctx, data, format)
+		if err != nil {
+			return clip, fmt.Errorf("%w: %v", ErrTranscode, err)
+		}
+		if !to.Supported() {
+			return clip, fmt.Errorf("%w: transcoder produced %s", ErrTranscode, to)
+		}
+		clip.Data, clip.Format, clip.Transcoded = out, to, true
+	}
+	base := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	if base == "" || base == "." || base == string(filepath.Separator) {
+		base = "audio"
+	}
+	clip.Filename = base + "." + string(clip.Format)
+
+	info := probe(clip.Format, clip.Data)
+	if (info.duration == 0 || !info.judged) && t != nil && !clip.Transcoded {
+		if out, to, err := t.Transcode(ctx, clip.Data, clip.Format); err == nil {
+			decoded := probe(to, out)
+			if info.duration == 0 {
+				info.duration = decoded.duration
+			}
+			if decoded.judged {
+				info.silent, info.judged = decoded.silent, true
+			}
+		}
+	}
+	clip.Duration = info.duration
+	switch {
+	case info.duration > 0 && info.duration < MinDuration:
+		return clip, fmt.Errorf("%w: %s", ErrTooShort, info.duration.Round(time.Millisecond))
+	case lim.MaxDuration > 0 && info.duration > lim.MaxDuration:
+		return clip, fmt.Errorf("%w: %s over %s", ErrTooLong, info.duration.Round(time.Second), lim.MaxDuration)
+	case lim.MaxDuration > 0 && info.duration == 0:
+		return clip, fmt.Errorf("%w: %s", ErrUnmeasured, clip.Format)
+	case info.silent:
+		return clip, ErrSilent
+	}
+	return clip, nil
+}
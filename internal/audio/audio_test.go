@@ -0,0 +1,96 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want Format
+		err  error
+	}{
+		{"wav", wav(10, 0), WAV, nil},
+		{"ogg", opus(1), OGG, nil},
+		{"flac", flac(1, 16000), FLAC, nil},
+		{"webm", mediaRecorderWebM(), WebM, nil},
+		{"m4a", m4a(1, 1), M4A, nil},
+		{"3gp", []byte("\x00\x00\x00\x14ftyp3gp4\x00\x00\x00\x00"), ThreeGP, nil},
+		{"mp3 with id3", []byte("ID3\x04\x00\x00\x00\x00\x00\x00"), MP3, nil},
+		{"mp3 frame", mp3(), MP3, nil},
+		{"adts aac", []byte{0xFF, 0xF1, 0x50, 0x80}, AAC, nil},
+		{"amr", []byte("#!AMR\n"), AMR, nil},
+		{"caf", []byte("caff\x00\x01"), CAF, nil},
+		{"empty", nil, "", ErrEmpty},
+		{"text", []byte("hello there"), "", ErrUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Sniff(tt.data)
+			if got != tt.want || !errors.Is(err, tt.err) {
+				t.Errorf("Sniff = %q, %v; want %q, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+// decoder stands in for ffmpeg, returning out for any input.
+type decoder struct {
+	out   []byte
+	calls int
+}
+
+func (d *decoder) Transcode(ctx context.Context, data []byte, from Format) ([]byte, Format, error) {
+	d.calls++
+	return d.out, WAV, nil
+}
+
+func TestPrepare(t *testing.T) {
+	loud2s, silent2s := wav(32000, 8000), wav(32000, 10)
+	tests := []struct {
+		name     string
+		data     []byte
+		decoder  *decoder
+		max      time.Duration
+		duration time.Duration
+		err      error
+		decoded  bool
+	}{
+		{name: "loud wav", data: wav(16000, 8000), max: time.Minute, duration: time.Second},
+		{name: "silent wav", data: wav(16000, 10), duration: time.Second, err: ErrSilent},
+		{name: "tap", data: wav(1600, 8000), duration: 100 * time.Millisecond, err: ErrTooShort},
+		{name: "mediarecorder over limit", data: mediaRecorderWebM(), max: 2 * time.Second, duration: 3500 * time.Millisecond, err: ErrTooLong},
+		{name: "mp3 not decoded", data: mp3(), max: time.Minute, duration: time.Second},
+		{name: "silent mp3", data: mp3(), decoder: &decoder{out: silent2s}, duration: time.Second, err: ErrSilent, decoded: true},
+		{name: "loud mp3", data: mp3(), decoder: &decoder{out: loud2s}, duration: time.Second, decoded: true},
+		{name: "webm length unknown", data: webmNoTiming(), max: time.Minute, err: ErrUnmeasured},
+		{name: "webm length unknown, no limit", data: webmNoTiming()},
+		{name: "webm measured by decoding", data: webmNoTiming(), decoder: &decoder{out: loud2s}, max: time.Minute, duration: 2 * time.Second, decoded: true},
+		{name: "amr without transcoder", data: []byte("#!AMR\n\x00\x00"), err: ErrUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tc Transcoder
+			if tt.decoder != nil {
+				tc = tt.decoder
+			}
+			clip, err := Prepare(context.Background(), "note.bin", tt.data, tc, Limits{MaxDuration: tt.max})
+			if !errors.Is(err, tt.err) || (tt.err == nil) != (err == nil) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if clip != nil && clip.Duration != tt.duration {
+				t.Errorf("duration = %s, want %s", clip.Duration, tt.duration)
+			}
+			if tt.decoder != nil && (tt.decoder.calls > 0) != tt.decoded {
+				t.Errorf("decoded %d times, want decoded %v", tt.decoder.calls, tt.decoded)
+			}
+			if clip != nil && tt.decoded && string(clip.Data) != string(tt.data) {
+				t.Error("decoded copy replaced the upload")
+			}
+		})
+	}
+}
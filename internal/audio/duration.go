@@ -0,0 +1,372 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// info is what can be read from a container without decoding it.
+type info struct {
+	duration time.Duration // 0 when unknown
+	silent   bool
+	judged   bool // silent was read from the samples: 8- or 16-bit PCM WAV
+}
+
+func probe(f Format, data []byte) info {
+	switch f {
+	case WAV:
+		return probeWAV(data)
+	case FLAC:
+		return info{duration: flacDuration(data)}
+	case OGG:
+		return info{duration: oggDuration(data)}
+	case MP3:
+		return info{duration: mp3Duration(data)}
+	case M4A:
+		return info{duration: mp4Duration(data)}
+	case WebM:
+		return info{duration: webmDuration(data)}
+	}
+	return info{}
+}
+
+func seconds(s float64) time.Duration { return time.Duration(s * float64(time.Second)) }
+
+// silenceThreshold is the peak level, as a fraction of full scale, below
+// which a clip counts as silent (-40 dBFS).
+const silenceThreshold = 0.01
+
+// probeWAV reads the fmt and data chunks. Streamed WAV, such as ffmpeg's
+// output to a pipe, leaves the data size unset; the rest of the file is
+// taken instead.
+func probeWAV(data []byte) info {
+	var pcm bool
+	var channels, bits int
+	var byteRate uint32
+	for off := 12; off+8 <= len(data); {
+		id, size := string(data[off:off+4]), int(binary.LittleEndian.Uint32(data[off+4:off+8]))
+		body := off + 8
+		switch id {
+		case "fmt ":
+			if body+16 > len(data) {
+				return info{}
+			}
+			pcm = binary.LittleEndian.Uint16(data[body:]) == 1
+			channels = int(binary.LittleEndian.Uint16(data[body+2:]))
+			byteRate = binary.LittleEndian.Uint32(data[body+8:])
+			bits = int(binary.LittleEndian.Uint16(data[body+14:]))
+		case "data":
+			if size <= 0 || body+size > len(data) {
+				size = len(data) - body
+			}
+			if byteRate == 0 {
+				return info{}
+			}
+			samples := data[body : body+size]
+			res := info{duration: seconds(float64(len(samples)) / float64(byteRate))}
+			if pcm && channels > 0 && (bits == 8 || bits == 16) {
+				res.silent, res.judged = peak(samples, bits) < silenceThreshold, true
+			}
+			return res
+		}
+		off = body + size + size%2
+		if size < 0 || off < body {
+			break
+		}
+	}
+	return info{}
+}
+
+// peak is the loudest sample of 8- or 16-bit PCM as a fraction of full
+// scale; other depths report full scale.
+func peak(samples []byte, bits int) float64 {
+	var max float64
+	switch bits {
+	case 8:
+		for _, b := range samples {
+			max = math.Max(max, math.Abs(float64(int(b)-128)))
+		}
+		return max / 128
+	case 16:
+		for i := 0; i+1 < len(samples); i += 2 {
+			max = math.Max(max, math.Abs(float64(int16(binary.LittleEndian.Uint16(samples[i:])))))
+		}
+		return max / 32768
+	}
+	return 1
+}
+
+// flacDuration reads total samples and sample rate from STREAMINFO, the
+// first metadata block.
+func flacDuration(data []byte) time.Duration {
+	if len(data) < 8+18 || data[4]&0x7F != 0 {
+		return 0
+	}
+	d := data[8:]
+	rate := uint32(d[10])<<12 | uint32(d[11])<<4 | uint32(d[12])>>4
+	total := uint64(d[13]&0x0F)<<32 | uint64(binary.BigEndian.Uint32(d[14:18]))
+	if rate == 0 {
+		return 0
+	}
+	return seconds(float64(total) / float64(rate))
+}
+
+// oggDuration divides the granule position of the last page by the
+// stream's sample rate: always 48 kHz for Opus (less its pre-skip), read
+// from the identification header for Vorbis.
+func oggDuration(data []byte) time.Duration {
+	if len(data) < 28 {
+		return 0
+	}
+	first := 27 + int(data[26])
+	if first+20 > len(data) {
+		return 0
+	}
+	var rate, preSkip float64
+	switch head := data[first:]; {
+	case bytes.HasPrefix(head, []byte("OpusHead")):
+		rate, preSkip = 48000, float64(binary.LittleEndian.Uint16(head[10:]))
+	case bytes.HasPrefix(head, []byte("\x01vorbis")):
+		rate = float64(binary.LittleEndian.Uint32(head[12:]))
+	default:
+		return 0
+	}
+	last := bytes.LastIndex(data, []byte("OggS"))
+	if last < 0 || last+14 > len(data) || rate == 0 {
+		return 0
+	}
+	granule := int64(binary.LittleEndian.Uint64(data[last+6:]))
+	if granule <= 0 {
+		return 0
+	}
+	return seconds((float64(granule) - preSkip) / rate)
+}
+
+var (
+	mp3Bitrates = [2][16]int{
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}, // MPEG-1 layer III
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},     // MPEG-2/2.5 layer III
+	}
+	mp3Rates = [3]int{44100, 48000, 32000}
+)
+
+// mp3Duration uses the frame count of a Xing/Info header when the encoder
+// wrote one, and otherwise assumes a constant bitrate.
+func mp3Duration(data []byte) time.Duration {
+	off := 0
+	if len(data) >= 10 && string(data[:3]) == "ID3" {
+		size := int(data[6]&0x7F)<<21 | int(data[7]&0x7F)<<14 | int(data[8]&0x7F)<<7 | int(data[9]&0x7F)
+		off = 10 + size
+		if data[5]&0x10 != 0 {
+			off += 10
+		}
+	}
+	for ; off+4 <= len(data); off++ {
+		if data[off] == 0xFF && data[off+1]&0xE0 == 0xE0 && data[off+1]&0x06 == 0x02 {
+			break
+		}
+	}
+	if off+4 > len(data) {
+		return 0
+	}
+	h := data[off : off+4]
+	version := h[1] >> 3 & 0x03 // 3 MPEG-1, 2 MPEG-2, 0 MPEG-2.5
+	if version == 1 {
+		return 0
+	}
+	v := 1
+	if version == 3 {
+		v = 0
+	}
+	bitrate := mp3Bitrates[v][h[2]>>4] * 1000
+	rateIndex := h[2] >> 2 & 0x03
+	if bitrate == 0 || rateIndex == 3 {
+		return 0
+	}
+	rate := mp3Rates[rateIndex]
+	samplesPerFrame := 1152
+	switch version {
+	case 2:
+		rate, samplesPerFrame = rate/2, 576
+	case 0:
+		rate, samplesPerFrame = rate/4, 576
+	}
+
+	// The Xing header follows the side information, whose size depends on
+	// the version and channel mode.
+	mono := h[3]>>6 == 3
+	side := 17
+	switch {
+	case v == 0 && !mono:
+		side = 32
+	case v == 1 && mono:
+		side = 9
+	}
+	if x := off + 4 + side; x+12 <= len(data) {
+		if tag := string(data[x : x+4]); tag == "Xing" || tag == "Info" {
+			if binary.BigEndian.Uint32(data[x+4:])&1 != 0 {
+				frames := binary.BigEndian.Uint32(data[x+8:])
+				return seconds(float64(frames) * float64(samplesPerFrame) / float64(rate))
+			}
+		}
+	}
+	return seconds(float64(len(data)-off) * 8 / float64(bitrate))
+}
+
+// mp4Duration reads the movie header (moov/mvhd).
+func mp4Duration(data []byte) time.Duration {
+	moov := mp4Box(data, "moov")
+	mvhd := mp4Box(moov, "mvhd")
+	if len(mvhd) < 20 {
+		return 0
+	}
+	var scale uint32
+	var length uint64
+	if mvhd[0] == 1 {
+		if len(mvhd) < 32 {
+			return 0
+		}
+		scale, length = binary.BigEndian.Uint32(mvhd[20:]), binary.BigEndian.Uint64(mvhd[24:])
+	} else {
+		scale, length = binary.BigEndian.Uint32(mvhd[12:]), uint64(binary.BigEndian.Uint32(mvhd[16:]))
+	}
+	if scale == 0 {
+		return 0
+	}
+	return seconds(float64(length) / float64(scale))
+}
+
+// mp4Box returns the body of the first box of type typ among the boxes
+// that make up data.
+func mp4Box(data []byte, typ string) []byte {
+	for off := 0; off+8 <= len(data); {
+		size, header := uint64(binary.BigEndian.Uint32(data[off:])), uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data) - off)
+		case 1:
+			if off+16 > len(data) {
+				return nil
+			}
+			size, header = binary.BigEndian.Uint64(data[off+8:]), 16
+		}
+		if size < header || uint64(off)+size > uint64(len(data)) {
+			return nil
+		}
+		if string(data[off+4:off+8]) == typ {
+			return data[off+int(header) : off+int(size)]
+		}
+		off += int(size)
+	}
+	return nil
+}
+
+// WebM (Matroska) element IDs, with their length marker.
+const (
+	ebmlSegment       = 0x18538067
+	ebmlInfo          = 0x1549A966
+	ebmlTimecodeScale = 0x2AD7B1
+	ebmlDuration      = 0x4489
+	ebmlCluster       = 0x1F43B675
+	ebmlTimecode      = 0xE7
+	ebmlBlockGroup    = 0xA0
+	ebmlBlock         = 0xA1
+	ebmlSimpleBlock   = 0xA3
+)
+
+// webmDuration reads the Segment Info's Duration, scaled by its
+// TimecodeScale. Browsers' MediaRecorder leaves Duration out and writes
+// segments and clusters of unknown size, so without it the timecode of the
+// last block is taken instead.
+func webmDuration(data []byte) time.Duration {
+	scale := 1e6 // nanoseconds per tick
+	var duration float64
+	var cluster, last int64
+	for off := 0; off < len(data); {
+		id, n, ok := ebmlVint(data, off, true)
+		if !ok {
+			break
+		}
+		size, m, ok := ebmlVint(data, off+n, false)
+		if !ok {
+			break
+		}
+		body := off + n + m
+		switch id {
+		case ebmlSegment, ebmlInfo, ebmlCluster, ebmlBlockGroup:
+			// Masters are read in place, so their size, known or not,
+			// doesn't matter.
+			off = body
+			continue
+		}
+		if size == 1<<(7*m)-1 || size > uint64(len(data)-body) {
+			// An unknown-size leaf can't be skipped; a truncated one ends
+			// the file. A block's timecode is still read from it.
+			size = uint64(len(data) - body)
+		}
+		end := body + int(size)
+		switch id {
+		case ebmlTimecodeScale:
+			if v := ebmlUint(data[body:end]); v > 0 {
+				scale = float64(v)
+			}
+		case ebmlDuration:
+			duration = ebmlFloat(data[body:end])
+		case ebmlTimecode:
+			cluster = int64(ebmlUint(data[body:end]))
+		case ebmlSimpleBlock, ebmlBlock:
+			// Track number, then the timecode relative to the cluster.
+			if _, t, ok := ebmlVint(data, body, false); ok && body+t+2 <= end {
+				last = max(last, cluster+int64(int16(binary.BigEndian.Uint16(data[body+t:]))))
+			}
+		}
+		off = end
+	}
+	if duration <= 0 || math.IsNaN(duration) || math.IsInf(duration, 0) {
+		duration = float64(last)
+	}
+	return time.Duration(duration * scale)
+}
+
+// ebmlVint reads the variable-length integer at data[off:] and returns it
+// with its length. IDs keep their length marker; sizes don't.
+func ebmlVint(data []byte, off int, marker bool) (uint64, int, bool) {
+	if off >= len(data) || data[off] == 0 {
+		return 0, 0, false
+	}
+	n := 1
+	for data[off]&(0x80>>(n-1)) == 0 {
+		n++
+	}
+	if off+n > len(data) {
+		return 0, 0, false
+	}
+	v := uint64(data[off])
+	if !marker {
+		v &= 0xFF >> n
+	}
+	for _, b := range data[off+1 : off+n] {
+		v = v<<8 | uint64(b)
+	}
+	return v, n, true
+}
+
+func ebmlUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b[:min(len(b), 8)] {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func ebmlFloat(b []byte) float64 {
+	switch len(b) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
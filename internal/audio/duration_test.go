@@ -0,0 +1,167 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// wav builds 16 kHz mono 16-bit PCM holding n samples of amplitude amp.
+func wav(n int, amp int16) []byte {
+	const rate = 16000
+	data := make([]byte, 44+2*n)
+	copy(data, "RIFF")
+	binary.LittleEndian.PutUint32(data[4:], uint32(36+2*n))
+	copy(data[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(data[16:], 16)
+	binary.LittleEndian.PutUint16(data[20:], 1) // PCM
+	binary.LittleEndian.PutUint16(data[22:], 1) // mono
+	binary.LittleEndian.PutUint32(data[24:], rate)
+	binary.LittleEndian.PutUint32(data[28:], rate*2)
+	binary.LittleEndian.PutUint16(data[32:], 2)
+	binary.LittleEndian.PutUint16(data[34:], 16)
+	copy(data[36:], "data")
+	binary.LittleEndian.PutUint32(data[40:], uint32(2*n))
+	for i := 0; i < n; i++ {
+		s := amp
+		if i%2 == 1 {
+			s = -amp
+		}
+		binary.LittleEndian.PutUint16(data[44+2*i:], uint16(s))
+	}
+	return data
+}
+
+// ebml encodes one element with a one-byte size; unknown writes the
+// unknown-size marker MediaRecorder uses for segments and clusters.
+func ebml(id []byte, body ...[]byte) []byte {
+	var b []byte
+	for _, p := range body {
+		b = append(b, p...)
+	}
+	out := append(append([]byte{}, id...), 0x80|byte(len(b)))
+	return append(out, b...)
+}
+
+func unknownSize(id []byte, body ...[]byte) []byte {
+	out := append(append([]byte{}, id...), 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF)
+	for _, p := range body {
+		out = append(out, p...)
+	}
+	return out
+}
+
+var (
+	webmHeader      = ebml([]byte{0x1A, 0x45, 0xDF, 0xA3}, []byte{0x42, 0x82, 0x84}, []byte("webm"))
+	webmScale       = ebml([]byte{0x2A, 0xD7, 0xB1}, []byte{0x0F, 0x42, 0x40}) // 1 ms ticks
+	webmSegmentID   = []byte{0x18, 0x53, 0x80, 0x67}
+	webmInfoID      = []byte{0x15, 0x49, 0xA9, 0x66}
+	webmClusterID   = []byte{0x1F, 0x43, 0xB6, 0x75}
+	webmTimecodeID  = []byte{0xE7}
+	webmSimpleBlock = []byte{0xA3}
+)
+
+// mediaRecorderWebM has no Duration: two clusters at 0 and 3000 ms, the
+// last block 500 ms into the second.
+func mediaRecorderWebM() []byte {
+	block := func(rel uint16) []byte {
+		return ebml(webmSimpleBlock, []byte{0x81, byte(rel >> 8), byte(rel), 0x80}, []byte("opus"))
+	}
+	return append(webmHeader, unknownSize(webmSegmentID,
+		ebml(webmInfoID, webmScale),
+		unknownSize(webmClusterID, ebml(webmTimecodeID, []byte{0}), block(0), block(20)),
+		unknownSize(webmClusterID, ebml(webmTimecodeID, []byte{0x0B, 0xB8}), block(0), block(500)),
+	)...)
+}
+
+func webmWithDuration(ms float64) []byte {
+	d := make([]byte, 8)
+	binary.BigEndian.PutUint64(d, math.Float64bits(ms))
+	return append(webmHeader, unknownSize(webmSegmentID,
+		ebml(webmInfoID, webmScale, ebml([]byte{0x44, 0x89}, d)),
+	)...)
+}
+
+// webmNoTiming has neither a Duration nor any blocks.
+func webmNoTiming() []byte {
+	return append(webmHeader, unknownSize(webmSegmentID, ebml(webmInfoID, webmScale))...)
+}
+
+func flac(samples uint32, rate uint32) []byte {
+	data := make([]byte, 8+34)
+	copy(data, "fLaC")
+	data[4] = 0x80 // last metadata block, STREAMINFO
+	d := data[8:]
+	d[10], d[11], d[12] = byte(rate>>12), byte(rate>>4), byte(rate<<4)
+	binary.BigEndian.PutUint32(d[14:], samples)
+	return data
+}
+
+func box(typ string, body ...[]byte) []byte {
+	var b []byte
+	for _, p := range body {
+		b = append(b, p...)
+	}
+	out := make([]byte, 8, 8+len(b))
+	binary.BigEndian.PutUint32(out, uint32(8+len(b)))
+	copy(out[4:], typ)
+	return append(out, b...)
+}
+
+func m4a(length, scale uint32) []byte {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:], scale)
+	binary.BigEndian.PutUint32(mvhd[16:], length)
+	return append(box("ftyp", []byte("M4A \x00\x00\x00\x00")), box("moov", box("mvhd", mvhd))...)
+}
+
+// opus is two Ogg pages: the identification header and a last page whose
+// granule position is 48 kHz samples plus the 312-sample pre-skip.
+func opus(seconds int) []byte {
+	page := func(granule uint64, body []byte) []byte {
+		p := make([]byte, 27, 28+len(body))
+		copy(p, "OggS")
+		binary.LittleEndian.PutUint64(p[6:], granule)
+		p[26] = 1
+		return append(append(p, byte(len(body))), body...)
+	}
+	head := []byte("OpusHead\x01\x01\x38\x01\x80\xbb\x00\x00\x00\x00\x00")
+	return append(page(0, head), page(uint64(48000*seconds+312), []byte("audio"))...)
+}
+
+// mp3 is constant-bitrate MPEG-1 layer III at 128 kbps, 16000 bytes long.
+func mp3() []byte {
+	data := make([]byte, 16000)
+	copy(data, []byte{0xFF, 0xFB, 0x90, 0x00})
+	return data
+}
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   Format
+		data     []byte
+		duration time.Duration
+		silent   bool
+		judged   bool
+	}{
+		{"loud wav", WAV, wav(16000, 8000), time.Second, false, true},
+		{"silent wav", WAV, wav(8000, 10), 500 * time.Millisecond, true, true},
+		{"webm duration", WebM, webmWithDuration(2500), 2500 * time.Millisecond, false, false},
+		{"mediarecorder webm", WebM, mediaRecorderWebM(), 3500 * time.Millisecond, false, false},
+		{"webm without timing", WebM, webmNoTiming(), 0, false, false},
+		{"flac", FLAC, flac(32000, 16000), 2 * time.Second, false, false},
+		{"m4a", M4A, m4a(4410, 1000), 4410 * time.Millisecond, false, false},
+		{"opus", OGG, opus(3), 3 * time.Second, false, false},
+		{"mp3", MP3, mp3(), time.Second, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := probe(tt.format, tt.data)
+			if got.duration != tt.duration || got.silent != tt.silent || got.judged != tt.judged {
+				t.Errorf("probe = %+v, want duration %s silent %v judged %v", got, tt.duration, tt.silent, tt.judged)
+			}
+		})
+	}
+}
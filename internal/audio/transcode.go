@@ -0,0 +1,68 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"finance-parser-go/internal/config"
+)
+
+// Transcoder converts audio that speech-to-text can't read, such as AMR
+// voice notes or CAF recordings from iOS, into a format it can.
+type Transcoder interface {
+	Transcode(ctx context.Context, data []byte, from Format) ([]byte, Format, error)
+}
+
+// NewTranscoder returns the Transcoder selected by AUDIO_TRANSCODER, or nil
+// when transcoding is turned off.
+func NewTranscoder(cfg *config.Config) (Transcoder, error) {
+	switch strings.ToLower(cfg.AudioTranscoder) {
+	case "", "none":
+		return nil, nil
+	case "ffmpeg":
+		path, err := exec.LookPath(cfg.FFmpegPath)
+		if err != nil {
+			return nil, fmt.Errorf("AUDIO_TRANSCODER=ffmpeg: %w", err)
+		}
+		return &FFmpeg{Path: path}, nil
+	}
+	return nil, fmt.Errorf("unknown AUDIO_TRANSCODER %q", cfg.AudioTranscoder)
+}
+
+// FFmpeg transcodes with the ffmpeg command line tool to 16 kHz mono WAV,
+// the rate transcription models resample to anyway. The WAV output also
+// lets Prepare measure the clip and check it for silence, which is why
+// Prepare decodes formats speech-to-text reads as is, too.
+type FFmpeg struct {
+	Path string
+}
+
+func (f *FFmpeg) Transcode(ctx context.Context, data []byte, from Format) ([]byte, Format, error) {
+	// Some containers keep their index at the end, so ffmpeg reads a file
+	// it can seek in rather than a pipe.
+	in, err := os.CreateTemp("", "audio-*."+string(from))
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.Remove(in.Name())
+	_, err = in.Write(data)
+	if cerr := in.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	var out, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, f.Path, "-hide_banner", "-loglevel", "error", "-nostdin",
+		"-i", in.Name(), "-vn", "-ac", "1", "-ar", "16000", "-c:a", "pcm_s16le", "-f", "wav", "pipe:1")
+	cmd.Stdout, cmd.Stderr = &out, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, "", fmt.Errorf("ffmpeg: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out.Bytes(), WAV, nil
+}
@@ -10,15 +10,42 @@ type Config struct {
 	Port           string
 	AllowOrigins   string
 	AuthBearer     string
+	AdminToken     string // X-Admin-Token for /v1/admin; empty disables it
 	TZDefault      string
 	OpenAIKey      string
 	OpenAIBaseURL  string
 	OpenAILlmModel string
 	OpenAIWhisper  string
+	OpenAIVision   string
+	LLMProvider    string // openai, compatible, fake
+	STTProvider    string // openai, compatible, fake
+	STTLanguage    string // ISO-639-1 hint for speech-to-text, e.g. hi; empty auto-detects
+	VisionProvider string // openai, compatible, fake, none
+	LLMBaseURL     string // OpenAI-compatible server, e.g. Ollama or llama.cpp
+	LLMAPIKey      string
+	LLMModel       string
+	LLMWhisper     string
+	LLMVision      string
+	OfflineParser  string // fallback, fastpath, off
+	PromptVersion  string // internal/ai/prompts/parse_<version>.txt
+	LLMTimeoutSec  int // per HTTP attempt to the provider; streamed replies run to the request deadline
+	LLMMaxRetries  int // retries after 429/5xx/connection errors
+	LLMBreakerFailures int // failures in a row that open the circuit; 0 disables it
+	LLMBreakerCooldownSec int
+	ParseSessionTTLMin int
+	ParseCacheTTLMin int // 0 disables the parse cache
+	ParseLogRetentionDays int // parse logs and field results older than this are deleted; 0 keeps them
+	ParseDailyQuota int  // per user and day; 0 is unlimited
+	TranscribeDailyQuota int
+	GuestParseDailyQuota int
+	GuestTranscribeDailyQuota int
 	ReqTimeoutSec  int
 	RateLimitRPS   float64
 	RateLimitBurst int
 	MaxUploadMB    int64
+	AudioMaxSeconds int // longest voice clip accepted; 0 is unlimited
+	AudioTranscoder string // none, ffmpeg
+	FFmpegPath     string
 }
 
 func getenv(key, def string) string {
@@ -45,14 +72,41 @@ func Load() *Config {
 		Port:           getenv("PORT", "8080"),
 		AllowOrigins:   getenv("ALLOW_ORIGINS", "*"),
 		AuthBearer:     getenv("AUTH_BEARER", ""),
+		AdminToken:     getenv("ADMIN_TOKEN", ""),
 		TZDefault:      getenv("TZ_DEFAULT", "Asia/Kolkata"),
 		OpenAIKey:      getenv("OPENAI_API_KEY", ""),
 		OpenAIBaseURL:  getenv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
 		OpenAILlmModel: getenv("OPENAI_LLM_MODEL", "gpt-4o-mini"),
 		OpenAIWhisper:  getenv("OPENAI_WHISPER_MODEL", "whisper-1"),
+		OpenAIVision:   getenv("OPENAI_VISION_MODEL", getenv("OPENAI_LLM_MODEL", "gpt-4o-mini")),
+		LLMProvider:    getenv("LLM_PROVIDER", "openai"),
+		STTProvider:    getenv("STT_PROVIDER", getenv("LLM_PROVIDER", "openai")),
+		STTLanguage:    getenv("STT_LANGUAGE", ""),
+		VisionProvider: getenv("VISION_PROVIDER", getenv("LLM_PROVIDER", "openai")),
+		LLMBaseURL:     getenv("LLM_BASE_URL", "http://localhost:11434/v1"),
+		LLMAPIKey:      getenv("LLM_API_KEY", ""),
+		LLMModel:       getenv("LLM_MODEL", "llama3.1"),
+		LLMWhisper:     getenv("LLM_WHISPER_MODEL", "whisper-1"),
+		LLMVision:      getenv("LLM_VISION_MODEL", "llava"),
+		OfflineParser:  getenv("OFFLINE_PARSER", "fallback"),
+		PromptVersion:  getenv("PROMPT_VERSION", "v1"),
+		LLMTimeoutSec:  atoi("LLM_TIMEOUT_SECONDS", 60),
+		LLMMaxRetries:  atoi("LLM_MAX_RETRIES", 2),
+		LLMBreakerFailures: atoi("LLM_BREAKER_FAILURES", 5),
+		LLMBreakerCooldownSec: atoi("LLM_BREAKER_COOLDOWN_SECONDS", 30),
+		ParseSessionTTLMin: atoi("PARSE_SESSION_TTL_MINUTES", 30),
+		ParseCacheTTLMin: atoi("PARSE_CACHE_TTL_MINUTES", 720),
+		ParseLogRetentionDays: atoi("PARSE_LOG_RETENTION_DAYS", 90),
+		ParseDailyQuota: atoi("PARSE_DAILY_QUOTA", 200),
+		TranscribeDailyQuota: atoi("TRANSCRIBE_DAILY_QUOTA", 100),
+		GuestParseDailyQuota: atoi("GUEST_PARSE_DAILY_QUOTA", 30),
+		GuestTranscribeDailyQuota: atoi("GUEST_TRANSCRIBE_DAILY_QUOTA", 10),
 		ReqTimeoutSec:  atoi("REQUEST_TIMEOUT_SECONDS", 30),
 		RateLimitRPS:   atof("RATE_LIMIT_RPS", 5),
 		RateLimitBurst: atoi("RATE_LIMIT_BURST", 10),
 		MaxUploadMB:    int64(atoi("MAX_UPLOAD_MB", 15)),
+		AudioMaxSeconds: atoi("AUDIO_MAX_SECONDS", 120),
+		AudioTranscoder: getenv("AUDIO_TRANSCODER", "none"),
+		FFmpegPath:     getenv("FFMPEG_PATH", "ffmpeg"),
 	}
 }
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"finance-parser-go/internal/models"
+)
+
+// runOnce runs fn unless a migration called name has already completed, and
+// records it once fn succeeds.
+func runOnce(name string, fn func() error) {
+	var count int64
+	DB.Model(&models.Migration{}).Where("name = ?", name).Count(&count)
+	if count > 0 {
+		return
+	}
+	if err := fn(); err != nil {
+		log.Printf("[WARN] migration %s: %v", name, err)
+		return
+	}
+	DB.Create(&models.Migration{Name: name, RanAt: time.Now()})
+}
+
+// BackfillOccurredAt fills entries.occurred_at for rows written before the
+// column existed, using each owner's timezone (or defaultTZ). It runs once;
+// rows whose date string cannot be parsed are left NULL and logged that once.
+func BackfillOccurredAt(defaultTZ string) {
+	runOnce("backfill_occurred_at", func() error {
+		return backfillOccurredAt(defaultTZ)
+	})
+}
+
+func backfillOccurredAt(defaultTZ string) error {
+	var users []models.User
+	DB.Select("id", "timezone").Find(&users)
+	zones := make(map[uint]*time.Location, len(users))
+	for _, u := range users {
+		if loc, err := time.LoadLocation(u.Timezone); err == nil && u.Timezone != "" {
+			zones[u.ID] = loc
+		}
+	}
+	fallback, err := time.LoadLocation(defaultTZ)
+	if err != nil {
+		fallback = time.FixedZone("IST", 5*3600+1800)
+	}
+
+	var batch []models.Entry
+	filled, skipped := 0, 0
+	err = DB.Where("occurred_at IS NULL AND date <> ''").FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		for _, e := range batch {
+			loc := zones[e.UserID]
+			if loc == nil {
+				loc = fallback
+			}
+			at, err := models.ParseOccurredAt(e.Date, e.Time, loc)
+			if err != nil {
+				skipped++
+				log.Printf("[WARN] backfill occurred_at: entry %d has unparseable date %q time %q", e.ID, e.Date, e.Time)
+				continue
+			}
+			if err := DB.Model(&models.Entry{}).Where("id = ?", e.ID).Update("occurred_at", at).Error; err != nil {
+				return err
+			}
+			filled++
+		}
+		return nil
+	}).Error
+	if filled > 0 || skipped > 0 {
+		log.Printf("backfilled occurred_at on %d entries (%d skipped)", filled, skipped)
+	}
+	return err
+}
@@ -0,0 +1,177 @@
+// Package email decodes raw RFC 822 messages and pulls order totals out of
+// receipt emails.
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+type Message struct {
+	ID      string    `json:"id"` // Message-ID header
+	From    string    `json:"from"`
+	Domain  string    `json:"domain"`
+	Subject string    `json:"subject"`
+	Date    time.Time `json:"date"`
+	Text    string    `json:"text"` // text/plain, or text/html flattened to text
+}
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// charsetReader converts text in the named charset (ISO-8859-1,
+// Windows-1252, Shift_JIS, ...) to UTF-8.
+func charsetReader(charset string, r io.Reader) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "", "utf-8", "utf8", "us-ascii":
+		return r, nil
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	return enc.NewDecoder().Reader(r), nil
+}
+
+// Parse decodes a raw message, walking multipart bodies, undoing
+// quoted-printable/base64 transfer encodings and converting text parts from
+// their declared charset to UTF-8. Plain text parts are preferred over HTML.
+func Parse(raw []byte) (*Message, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid email: %w", err)
+	}
+
+	msg := &Message{ID: strings.Trim(m.Header.Get("Message-Id"), "<> ")}
+	if subj, err := wordDecoder.DecodeHeader(m.Header.Get("Subject")); err == nil {
+		msg.Subject = subj
+	} else {
+		msg.Subject = m.Header.Get("Subject")
+	}
+	if addr, err := mail.ParseAddress(m.Header.Get("From")); err == nil {
+		msg.From = addr.Address
+		if i := strings.LastIndex(addr.Address, "@"); i >= 0 {
+			msg.Domain = strings.ToLower(addr.Address[i+1:])
+		}
+	}
+	if d, err := m.Header.Date(); err == nil {
+		msg.Date = d
+	}
+
+	var plain, htmlBody string
+	if err := walk(m.Header.Get("Content-Type"), m.Header.Get("Content-Transfer-Encoding"), m.Body, &plain, &htmlBody); err != nil {
+		return nil, err
+	}
+	msg.Text = plain
+	if strings.TrimSpace(msg.Text) == "" {
+		msg.Text = HTMLToText(htmlBody)
+	}
+	return msg, nil
+}
+
+func walk(contentType, encoding string, body io.Reader, plain, htmlBody *string) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("invalid multipart body: %w", err)
+			}
+			if err := walk(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part, plain, htmlBody); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decode(encoding, body))
+	if err != nil {
+		return fmt.Errorf("failed to decode body: %w", err)
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		// A charset we can't convert is kept as is rather than failing the
+		// whole message.
+		if r, err := charsetReader(params["charset"], bytes.NewReader(data)); err == nil {
+			if utf, err := io.ReadAll(r); err == nil {
+				data = utf
+			}
+		}
+	}
+	switch mediaType {
+	case "text/plain":
+		if *plain == "" {
+			*plain = string(data)
+		}
+	case "text/html":
+		if *htmlBody == "" {
+			*htmlBody = string(data)
+		}
+	}
+	return nil
+}
+
+func decode(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: r})
+	}
+	return r
+}
+
+// newlineStripper drops CR/LF so base64 bodies wrapped at 76 columns decode.
+type newlineStripper struct{ r io.Reader }
+
+func (n *newlineStripper) Read(p []byte) (int, error) {
+	buf := make([]byte, len(p))
+	c, err := n.r.Read(buf)
+	j := 0
+	for _, b := range buf[:c] {
+		if b != '\r' && b != '\n' {
+			p[j] = b
+			j++
+		}
+	}
+	return j, err
+}
+
+var (
+	dropBlocks = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>`)
+	blockTags  = regexp.MustCompile(`(?i)</?(br|p|div|tr|li|h[1-6]|table)[^>]*>`)
+	cellTags   = regexp.MustCompile(`(?i)</?(td|th)[^>]*>`)
+	anyTag     = regexp.MustCompile(`<[^>]+>`)
+	spaces     = regexp.MustCompile(`[ \t\x{00a0}]+`)
+	blankLines = regexp.MustCompile(`\n\s*\n+`)
+)
+
+// HTMLToText flattens an HTML email into lines of text, keeping table cells
+// on one line so "Total | ₹450" stays together.
+func HTMLToText(s string) string {
+	s = dropBlocks.ReplaceAllString(s, "")
+	s = blockTags.ReplaceAllString(s, "\n")
+	s = cellTags.ReplaceAllString(s, " ")
+	s = anyTag.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = spaces.ReplaceAllString(s, " ")
+	s = blankLines.ReplaceAllString(s, "\n")
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,140 @@
+package email
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type LineItem struct {
+	Name     string  `json:"name"`
+	Quantity int     `json:"quantity"`
+	Amount   float64 `json:"amount"`
+}
+
+type Receipt struct {
+	Merchant string     `json:"merchant"`
+	Category string     `json:"category,omitempty"`
+	Total    float64    `json:"total"`
+	Date     string     `json:"date"` // YYYY-MM-DD
+	Items    []LineItem `json:"items"`
+	OrderID  string     `json:"order_id,omitempty"`
+}
+
+// extractor knows how one merchant words its receipts. Totals are tried in
+// order; the first capture wins.
+type extractor struct {
+	merchant string
+	category string
+	domains  []string
+	totals   []*regexp.Regexp
+	orderID  *regexp.Regexp
+}
+
+const money = `(?:₹|rs\.?|inr)\s*([0-9][0-9,]*(?:\.[0-9]{1,2})?)`
+
+var (
+	genericTotals = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)(?:grand total|order total|total paid|amount paid|total amount|bill total|total charged|you paid)[^0-9₹]{0,20}` + money),
+		regexp.MustCompile(`(?i)\btotal\b[^0-9₹]{0,20}` + money),
+	}
+	genericOrderID = regexp.MustCompile(`(?i)order\s*(?:id|no\.?|number|#)[:\s#]*([A-Z0-9-]{5,})`)
+	itemLine       = regexp.MustCompile(`(?im)^\s*(?:(\d+)\s*[x×]\s*)?([A-Za-z][^\n₹]{1,60}?)\s+(?:[x×]\s*(\d+)\s+)?` + money + `\s*$`)
+	skipItem       = regexp.MustCompile(`(?i)total|subtotal|tax|gst|discount|delivery|fee|charges|savings|paid|amount|tip|round`)
+)
+
+var extractors = []extractor{
+	{
+		merchant: "Amazon", category: "Shopping",
+		domains: []string{"amazon.in", "amazon.com"},
+		totals:  []*regexp.Regexp{regexp.MustCompile(`(?i)(?:order total|grand total)[:\s]*` + money)},
+		orderID: regexp.MustCompile(`(?i)order\s*#?\s*(\d{3}-\d{7}-\d{7})`),
+	},
+	{
+		merchant: "Swiggy", category: "Food",
+		domains: []string{"swiggy.in", "swiggy.com"},
+		totals:  []*regexp.Regexp{regexp.MustCompile(`(?i)(?:order total|grand total|total paid|paid via [a-z ]+)[:\s]*` + money)},
+		orderID: regexp.MustCompile(`(?i)order\s*(?:id|no)[:\s#]*(\d{6,})`),
+	},
+	{
+		merchant: "Zomato", category: "Food",
+		domains: []string{"zomato.com"},
+		totals:  []*regexp.Regexp{regexp.MustCompile(`(?i)(?:total paid|grand total|bill total)[:\s]*` + money)},
+		orderID: regexp.MustCompile(`(?i)order\s*(?:id|no)[:\s#]*(\d{6,})`),
+	},
+	{
+		merchant: "Uber", category: "Travel",
+		domains: []string{"uber.com"},
+		totals:  []*regexp.Regexp{regexp.MustCompile(`(?i)(?:^|\n)\s*total\s*` + money), regexp.MustCompile(`(?i)amount charged[:\s]*` + money)},
+	},
+	{
+		merchant: "Flipkart", category: "Shopping",
+		domains: []string{"flipkart.com"},
+		totals:  []*regexp.Regexp{regexp.MustCompile(`(?i)(?:amount paid|order total|total amount)[:\s]*` + money)},
+		orderID: regexp.MustCompile(`(?i)order\s*(?:id)?[:\s#]*(OD\d{10,})`),
+	},
+}
+
+// Extract runs the extractor for the sender's domain, falling back to
+// generic total patterns. It reports false when no total could be found, in
+// which case the caller should fall back to the LLM.
+func Extract(msg *Message) (*Receipt, bool) {
+	r := &Receipt{Items: []LineItem{}}
+	if !msg.Date.IsZero() {
+		r.Date = msg.Date.Format("2006-01-02")
+	}
+
+	totals := genericTotals
+	orderID := genericOrderID
+	if ex := findExtractor(msg.Domain); ex != nil {
+		r.Merchant = ex.merchant
+		r.Category = ex.category
+		totals = append(append([]*regexp.Regexp{}, ex.totals...), genericTotals...)
+		if ex.orderID != nil {
+			orderID = ex.orderID
+		}
+	}
+
+	for _, re := range totals {
+		if m := re.FindStringSubmatch(msg.Text); m != nil {
+			if v, err := strconv.ParseFloat(strings.ReplaceAll(m[1], ",", ""), 64); err == nil && v > 0 {
+				r.Total = v
+				break
+			}
+		}
+	}
+	if m := orderID.FindStringSubmatch(msg.Text); m != nil {
+		r.OrderID = m[1]
+	}
+
+	for _, m := range itemLine.FindAllStringSubmatch(msg.Text, -1) {
+		name := strings.TrimSpace(m[2])
+		if skipItem.MatchString(name) {
+			continue
+		}
+		qty := 1
+		for _, q := range []string{m[1], m[3]} {
+			if n, err := strconv.Atoi(q); err == nil && n > 0 {
+				qty = n
+			}
+		}
+		amt, err := strconv.ParseFloat(strings.ReplaceAll(m[4], ",", ""), 64)
+		if err != nil {
+			continue
+		}
+		r.Items = append(r.Items, LineItem{Name: name, Quantity: qty, Amount: amt})
+	}
+
+	return r, r.Total > 0 && r.Merchant != ""
+}
+
+func findExtractor(domain string) *extractor {
+	for i := range extractors {
+		for _, d := range extractors[i].domains {
+			if domain == d || strings.HasSuffix(domain, "."+d) {
+				return &extractors[i]
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,319 @@
+// Package export writes entries out as CSV, XLSX or JSON. Writers receive
+// one entry at a time so callers can stream straight from a database cursor.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance-parser-go/internal/models"
+)
+
+// Column is one exportable entry field. Value returns the cell text for CSV;
+// Number is set for columns that XLSX should store as numbers.
+type Column struct {
+	Key    string
+	Header string
+	Value  func(e *models.Entry, l Locale) string
+	Number func(e *models.Entry) (float64, bool)
+}
+
+var columns = []Column{
+	{Key: "id", Header: "ID", Value: func(e *models.Entry, _ Locale) string { return strconv.FormatUint(uint64(e.ID), 10) },
+		Number: func(e *models.Entry) (float64, bool) { return float64(e.ID), true }},
+	{Key: "date", Header: "Date", Value: func(e *models.Entry, l Locale) string { return l.FormatDate(e.Date) }},
+	{Key: "time", Header: "Time", Value: func(e *models.Entry, _ Locale) string { return e.Time }},
+	{Key: "title", Header: "Title", Value: func(e *models.Entry, _ Locale) string { return e.Title }},
+	{Key: "type", Header: "Type", Value: func(e *models.Entry, _ Locale) string { return e.Type }},
+	{Key: "amount", Header: "Amount", Value: func(e *models.Entry, l Locale) string { return l.FormatAmount(e.Amount) },
+		Number: func(e *models.Entry) (float64, bool) { return e.Amount, true }},
+	{Key: "signed_amount", Header: "Signed Amount", Value: func(e *models.Entry, l Locale) string { return l.FormatAmount(signed(e)) },
+		Number: func(e *models.Entry) (float64, bool) { return signed(e), true }},
+	{Key: "currency", Header: "Currency", Value: func(e *models.Entry, _ Locale) string { return e.Currency }},
+	{Key: "category", Header: "Category", Value: func(e *models.Entry, _ Locale) string { return e.Category }},
+	{Key: "sub_category", Header: "Sub Category", Value: func(e *models.Entry, _ Locale) string { return e.SubCategory }},
+	{Key: "merchant", Header: "Merchant", Value: func(e *models.Entry, _ Locale) string { return e.Merchant }},
+	{Key: "mode", Header: "Mode", Value: func(e *models.Entry, _ Locale) string { return e.Mode }},
+	{Key: "card_network", Header: "Card Network", Value: func(e *models.Entry, _ Locale) string { return e.CardNetwork }},
+	{Key: "purpose_type", Header: "Purpose", Value: func(e *models.Entry, _ Locale) string { return e.PurposeType }},
+	{Key: "tags", Header: "Tags", Value: func(e *models.Entry, _ Locale) string { return FlattenTags(e) }},
+	{Key: "account_id", Header: "Account ID", Value: func(e *models.Entry, _ Locale) string {
+		if e.AccountID == nil {
+			return ""
+		}
+		return strconv.FormatUint(uint64(*e.AccountID), 10)
+	}, Number: func(e *models.Entry) (float64, bool) {
+		if e.AccountID == nil {
+			return 0, false
+		}
+		return float64(*e.AccountID), true
+	}},
+	{Key: "notes", Header: "Notes", Value: func(e *models.Entry, _ Locale) string { return e.Notes }},
+	{Key: "draft", Header: "Draft", Value: func(e *models.Entry, _ Locale) string { return strconv.FormatBool(e.Draft) }},
+	{Key: "external_id", Header: "External ID", Value: func(e *models.Entry, _ Locale) string { return e.ExternalID }},
+}
+
+// DefaultColumns is used when the request does not pick any.
+var DefaultColumns = []string{"date", "time", "title", "type", "amount", "currency", "category", "sub_category", "merchant", "mode", "tags", "notes"}
+
+// Columns resolves column keys in the order given. Unknown keys are an error
+// so a typo doesn't silently drop data from the file.
+func Columns(keys []string) ([]Column, error) {
+	if len(keys) == 0 {
+		keys = DefaultColumns
+	}
+	var out []Column
+	for _, k := range keys {
+		k = strings.TrimSpace(strings.ToLower(k))
+		if k == "" {
+			continue
+		}
+		col, ok := findColumn(k)
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", k)
+		}
+		out = append(out, col)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no columns selected")
+	}
+	return out, nil
+}
+
+func findColumn(key string) (Column, bool) {
+	for _, c := range columns {
+		if c.Key == key {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+// FlattenTags merges the legacy single Tag with Tags, lower-cased, de-duplicated
+// and sorted, joined with "; " so the cell reads the same on every export.
+func FlattenTags(e *models.Entry) string {
+	seen := map[string]bool{}
+	var tags []string
+	for _, t := range append([]string{e.Tag}, e.Tags...) {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		tags = append(tags, t)
+	}
+	sort.Strings(tags)
+	return strings.Join(tags, "; ")
+}
+
+func signed(e *models.Entry) float64 {
+	if strings.EqualFold(e.Type, "expense") {
+		return -e.Amount
+	}
+	return e.Amount
+}
+
+// Locale controls how dates and amounts are written in CSV files.
+type Locale struct {
+	Name      string
+	DateFmt   string // Go layout
+	Decimal   string
+	Thousands string
+	Indian    bool // group as 12,34,567 rather than 1,234,567
+}
+
+var locales = map[string]Locale{
+	"en-in": {Name: "en-IN", DateFmt: "02/01/2006", Decimal: ".", Thousands: ",", Indian: true},
+	"en-us": {Name: "en-US", DateFmt: "01/02/2006", Decimal: ".", Thousands: ","},
+	"en-gb": {Name: "en-GB", DateFmt: "02/01/2006", Decimal: ".", Thousands: ","},
+	"de-de": {Name: "de-DE", DateFmt: "02.01.2006", Decimal: ",", Thousands: "."},
+	"fr-fr": {Name: "fr-FR", DateFmt: "02/01/2006", Decimal: ",", Thousands: " "},
+	// ISO dates and plain numbers, for spreadsheets and scripts.
+	"iso": {Name: "iso", DateFmt: "2006-01-02", Decimal: "."},
+}
+
+// LookupLocale returns the named locale; an empty name means "iso".
+func LookupLocale(name string) (Locale, bool) {
+	if name == "" {
+		name = "iso"
+	}
+	l, ok := locales[strings.ToLower(strings.ReplaceAll(name, "_", "-"))]
+	return l, ok
+}
+
+// FormatDate rewrites a stored YYYY-MM-DD date in the locale's layout,
+// passing anything unparseable through unchanged.
+func (l Locale) FormatDate(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.Format(l.DateFmt)
+}
+
+// FormatAmount writes an amount with two decimals and the locale's grouping.
+func (l Locale) FormatAmount(v float64) string {
+	neg := v < 0
+	s := strconv.FormatFloat(math.Abs(v), 'f', 2, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+
+	if l.Thousands != "" && len(whole) > 3 {
+		head, tail := whole[:len(whole)-3], whole[len(whole)-3:]
+		size := 3
+		if l.Indian {
+			size = 2
+		}
+		var groups []string
+		for len(head) > size {
+			groups = append([]string{head[len(head)-size:]}, groups...)
+			head = head[:len(head)-size]
+		}
+		groups = append([]string{head}, groups...)
+		whole = strings.Join(append(groups, tail), l.Thousands)
+	}
+
+	out := whole + l.Decimal + frac
+	if neg {
+		out = "-" + out
+	}
+	return out
+}
+
+// Writer receives entries one at a time. Close flushes any trailing bytes
+// (the JSON closing bracket, the XLSX zip directory).
+type Writer interface {
+	Write(e *models.Entry) error
+	Close() error
+}
+
+// ContentType maps each supported format to its MIME type.
+var ContentType = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"json": "application/json",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// NewWriter starts an export in the given format and writes any header.
+func NewWriter(format string, w io.Writer, cols []Column, l Locale) (Writer, error) {
+	switch format {
+	case "csv":
+		return newCSVWriter(w, cols, l)
+	case "json":
+		return newJSONWriter(w, cols), nil
+	case "xlsx":
+		return newXLSXWriter(w, cols)
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+type csvWriter struct {
+	w    *csv.Writer
+	cols []Column
+	l    Locale
+}
+
+func newCSVWriter(w io.Writer, cols []Column, l Locale) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w), cols: cols, l: l}
+	// Locales with a decimal comma conventionally use ';' between fields.
+	if l.Decimal == "," {
+		cw.w.Comma = ';'
+	}
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = c.Header
+	}
+	return cw, cw.w.Write(header)
+}
+
+func (cw *csvWriter) Write(e *models.Entry) error {
+	row := make([]string, len(cw.cols))
+	for i, c := range cw.cols {
+		row[i] = c.Value(e, cw.l)
+		// Numeric columns are written by us and a leading "-" is a sign.
+		if c.Number == nil {
+			row[i] = escapeFormula(row[i])
+		}
+	}
+	return cw.w.Write(row)
+}
+
+// escapeFormula stops spreadsheets from running text that starts like a
+// formula (a title such as "=HYPERLINK(...)") by prefixing it with a quote.
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// jsonWriter emits an array of objects keyed by column. Values keep their
+// raw form (ISO dates, numeric amounts, tag arrays) since JSON consumers are
+// programs rather than people.
+type jsonWriter struct {
+	w     io.Writer
+	cols  []Column
+	count int
+}
+
+func newJSONWriter(w io.Writer, cols []Column) *jsonWriter {
+	return &jsonWriter{w: w, cols: cols}
+}
+
+func (jw *jsonWriter) Write(e *models.Entry) error {
+	obj := make(map[string]any, len(jw.cols))
+	for _, c := range jw.cols {
+		switch {
+		case c.Key == "tags":
+			tags := FlattenTags(e)
+			obj[c.Key] = []string{}
+			if tags != "" {
+				obj[c.Key] = strings.Split(tags, "; ")
+			}
+		case c.Key == "draft":
+			obj[c.Key] = e.Draft
+		case c.Number != nil:
+			if v, ok := c.Number(e); ok {
+				obj[c.Key] = v
+			} else {
+				obj[c.Key] = nil
+			}
+		default:
+			obj[c.Key] = c.Value(e, locales["iso"])
+		}
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	prefix := ",\n"
+	if jw.count == 0 {
+		prefix = "[\n"
+	}
+	jw.count++
+	if _, err := io.WriteString(jw.w, prefix); err != nil {
+		return err
+	}
+	_, err = jw.w.Write(data)
+	return err
+}
+
+func (jw *jsonWriter) Close() error {
+	if jw.count == 0 {
+		_, err := io.WriteString(jw.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(jw.w, "\n]\n")
+	return err
+}
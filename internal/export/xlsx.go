@@ -0,0 +1,117 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"finance-parser-go/internal/models"
+)
+
+// xlsxWriter streams a single-sheet workbook. The sheet XML is written into
+// the zip as rows arrive and the remaining package parts are added on Close,
+// so memory use does not grow with the number of entries. Strings are
+// written inline rather than through a shared-strings table for the same
+// reason.
+type xlsxWriter struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	cols  []Column
+	row   int
+}
+
+func newXLSXWriter(w io.Writer, cols []Column) (*xlsxWriter, error) {
+	xw := &xlsxWriter{zw: zip.NewWriter(w), cols: cols}
+	sheet, err := xw.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	xw.sheet = sheet
+	if _, err := io.WriteString(sheet, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return nil, err
+	}
+
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = inlineCell(cellRef(i, 1), c.Header)
+	}
+	return xw, xw.writeRow(header)
+}
+
+func (xw *xlsxWriter) writeRow(cells []string) error {
+	xw.row++
+	_, err := fmt.Fprintf(xw.sheet, `<row r="%d">%s</row>`, xw.row, strings.Join(cells, ""))
+	return err
+}
+
+func (xw *xlsxWriter) Write(e *models.Entry) error {
+	r := xw.row + 1
+	cells := make([]string, len(xw.cols))
+	for i, c := range xw.cols {
+		ref := cellRef(i, r)
+		if c.Number != nil {
+			if v, ok := c.Number(e); ok {
+				cells[i] = fmt.Sprintf(`<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64))
+			}
+			continue
+		}
+		// Dates stay ISO so spreadsheet apps recognise them in any locale.
+		cells[i] = inlineCell(ref, c.Value(e, locales["iso"]))
+	}
+	return xw.writeRow(cells)
+}
+
+func (xw *xlsxWriter) Close() error {
+	if _, err := io.WriteString(xw.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	for _, part := range xlsxParts {
+		f, err := xw.zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, xml.Header+part.body); err != nil {
+			return err
+		}
+	}
+	return xw.zw.Close()
+}
+
+func inlineCell(ref, s string) string {
+	if s == "" {
+		return ""
+	}
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return fmt.Sprintf(`<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, b.String())
+}
+
+// cellRef converts a zero-based column and one-based row to "A1" notation.
+func cellRef(col, row int) string {
+	name := ""
+	for col >= 0 {
+		name = string(rune('A'+col%26)) + name
+		col = col/26 - 1
+	}
+	return fmt.Sprintf("%s%d", name, row)
+}
+
+var xlsxParts = []struct{ name, body string }{
+	{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Entries" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
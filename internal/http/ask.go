@@ -0,0 +1,200 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xeipuuv/gojsonschema"
+	"gorm.io/gorm"
+
+	"finance-parser-go/internal/ai"
+	"finance-parser-go/internal/database"
+	"finance-parser-go/internal/models"
+	"finance-parser-go/internal/numwords"
+)
+
+// The query structure only ever reaches SQL through these fixed fragments;
+// values from the question are always bound as parameters.
+var (
+	askMetricSQL = map[string]string{
+		"sum":     "COALESCE(SUM(amount), 0)",
+		"count":   "COUNT(*)",
+		"average": "COALESCE(AVG(amount), 0)",
+		"max":     "COALESCE(MAX(amount), 0)",
+		"min":     "COALESCE(MIN(amount), 0)",
+	}
+	askGroupSQL = map[string]string{
+		"category": "category",
+		"merchant": "merchant",
+		"mode":     "mode",
+		"month":    "to_char(occurred_at AT TIME ZONE ?, 'YYYY-MM')",
+	}
+	askSortSQL = map[string]string{
+		"date_desc":   "occurred_at desc nulls last, id desc",
+		"date_asc":    "occurred_at asc nulls last, id asc",
+		"amount_desc": "amount desc, id desc",
+		"amount_asc":  "amount asc, id asc",
+	}
+)
+
+type askGroup struct {
+	Key    string  `json:"key"`
+	Amount float64 `json:"amount"`
+	Count  int64   `json:"count"`
+}
+
+// POST /v1/ask
+// Answers a question like "how much did I spend on food in March?". The
+// question is translated into an ai.Query, validated, and run against the
+// user's saved (non-draft) entries.
+func (s *Server) ask(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input struct {
+		Question string `json:"question" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if over := s.quotaExceeded(c, userID, quotaParse); over != nil {
+		c.JSON(429, over)
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(s.cfg.ReqTimeoutSec)*time.Second)
+	defer cancel()
+
+	loc := s.userLocation(c)
+	pc := loadParseContext(userID, loc.String())
+	mctx, done := s.metered(ctx, c, userID)
+	raw, err := ai.Translate(mctx, s.parser, numwords.Normalize(input.Question).Text, loc.String(), pc.profile)
+	done()
+	if err != nil {
+		c.JSON(422, gin.H{"error": "could_not_understand", "question": input.Question})
+		return
+	}
+
+	res, err := s.queryValidator.Validate(gojsonschema.NewBytesLoader(raw))
+	if err != nil {
+		c.JSON(422, gin.H{"error": "could_not_understand", "question": input.Question})
+		return
+	}
+	if !res.Valid() {
+		d := []string{}
+		for _, e := range res.Errors() {
+			d = append(d, e.String())
+		}
+		c.JSON(422, gin.H{"error": "query_invalid", "details": d, "question": input.Question})
+		return
+	}
+	var q ai.Query
+	if err := json.Unmarshal(raw, &q); err != nil {
+		c.JSON(422, gin.H{"error": "query_invalid", "details": []string{err.Error()}, "question": input.Question})
+		return
+	}
+	if err := q.Normalize(pc.profile.Categories); err != nil {
+		c.JSON(422, gin.H{"error": "query_invalid", "details": []string{err.Error()}, "question": input.Question})
+		return
+	}
+
+	scoped := func() *gorm.DB { return askScope(userID, &q, loc) }
+
+	var answer float64
+	if err := scoped().Select(askMetricSQL[q.Metric]).Scan(&answer).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	var matches int64
+	if err := scoped().Count(&matches).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	groups := []askGroup{}
+	if q.GroupBy != "" {
+		key := askGroupSQL[q.GroupBy]
+		var args []any
+		if q.GroupBy == "month" {
+			args = append(args, loc.String())
+		}
+		err := scoped().
+			Select(key+" AS key, "+askMetricSQL[q.Metric]+" AS amount, COUNT(*) AS count", args...).
+			Group("key").
+			Order("amount desc").
+			Scan(&groups).Error
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	entries := []models.Entry{}
+	if err := scoped().Order(askSortSQL[q.Sort]).Limit(q.Limit).Find(&entries).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"question":    input.Question,
+		"answer":      answer,
+		"metric":      q.Metric,
+		"currency":    "INR",
+		"explanation": q.Explain(),
+		"query":       q,
+		"matches":     matches,
+		"groups":      groups,
+		"entries":     entries,
+	})
+}
+
+// askScope applies the query's filters to the user's saved entries.
+func askScope(userID uint, q *ai.Query, loc *time.Location) *gorm.DB {
+	db := database.DB.Model(&models.Entry{}).Where("user_id = ? AND draft = ?", userID, false)
+
+	if q.Type != "" {
+		db = db.Where("LOWER(type) = ?", q.Type)
+	}
+	if len(q.Categories) > 0 {
+		db = db.Where("LOWER(category) IN ?", lowerAll(q.Categories))
+	}
+	if len(q.Merchants) > 0 {
+		db = db.Where("LOWER(merchant) IN ?", lowerAll(q.Merchants))
+	}
+	if len(q.Modes) > 0 {
+		db = db.Where("LOWER(mode) IN ?", lowerAll(q.Modes))
+	}
+	for _, tag := range q.Tags {
+		if tagFilter, err := json.Marshal([]string{tag}); err == nil {
+			db = db.Where("tags @> ?", string(tagFilter))
+		}
+	}
+	if q.Text != "" {
+		like := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q.Text) + "%"
+		db = db.Where("(title ILIKE ? OR merchant ILIKE ? OR notes ILIKE ?)", like, like, like)
+	}
+	if q.MinAmount != nil {
+		db = db.Where("amount >= ?", *q.MinAmount)
+	}
+	if q.MaxAmount != nil {
+		db = db.Where("amount <= ?", *q.MaxAmount)
+	}
+	if t, err := time.ParseInLocation("2006-01-02", q.StartDate, loc); err == nil {
+		db = db.Where("occurred_at >= ?", t)
+	}
+	if t, err := time.ParseInLocation("2006-01-02", q.EndDate, loc); err == nil {
+		db = db.Where("occurred_at < ?", t.AddDate(0, 0, 1))
+	}
+	return db
+}
+
+func lowerAll(list []string) []string {
+	out := make([]string, len(list))
+	for i, s := range list {
+		out[i] = strings.ToLower(strings.TrimSpace(s))
+	}
+	return out
+}
@@ -34,6 +34,9 @@ func generateToken(user *models.User) string {
 }
 
 // POST /v1/auth/guest
+// With a device_id the device keeps one guest account: calling again
+// returns it rather than creating another. Without one a new guest is made
+// each time.
 func (s *Server) authGuest(c *gin.Context) {
 	var input struct {
 		DeviceID string `json:"device_id"`
@@ -42,6 +45,7 @@ func (s *Server) authGuest(c *gin.Context) {
 		c.JSON(400, gin.H{"error": "invalid_request"})
 		return
 	}
+	input.DeviceID = strings.TrimSpace(input.DeviceID)
 
 	var user models.User
 	if input.DeviceID != "" {
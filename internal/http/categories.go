@@ -0,0 +1,299 @@
+package http
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"finance-parser-go/internal/database"
+	"finance-parser-go/internal/models"
+)
+
+// loadCategories returns the user's categories (with sub-categories), seeding
+// the defaults the first time. Seeding claims users.categories_seeded with a
+// conditional update, so concurrent first reads seed once and a user who
+// deletes every category isn't given the defaults back.
+func loadCategories(userID uint) ([]models.Category, error) {
+	var categories []models.Category
+	load := func() error {
+		return database.DB.Preload("SubCategories").Where("user_id = ?", userID).Order("sort_order asc, id asc").Find(&categories).Error
+	}
+	if err := load(); err != nil {
+		return nil, err
+	}
+	if len(categories) > 0 {
+		return categories, nil
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		claim := tx.Model(&models.User{}).Where("id = ? AND categories_seeded = ?", userID, false).Update("categories_seeded", true)
+		if claim.Error != nil || claim.RowsAffected == 0 {
+			return claim.Error
+		}
+		for i, d := range models.DefaultCategories {
+			cat := d
+			cat.UserID = userID
+			cat.SortOrder = i
+			if err := tx.Create(&cat).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := load(); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// userCategoryNames is the list handed to the parser and used to validate its output.
+func userCategoryNames(userID uint) []string {
+	categories, err := loadCategories(userID)
+	if err != nil {
+		categories = models.DefaultCategories
+	}
+	names := make([]string, 0, len(categories))
+	for _, cat := range categories {
+		names = append(names, cat.Name)
+	}
+	return names
+}
+
+// matchCategory returns the user's spelling of category, or "" if it is not one of theirs.
+func matchCategory(category string, names []string) string {
+	category = strings.TrimSpace(category)
+	for _, n := range names {
+		if strings.EqualFold(n, category) {
+			return n
+		}
+	}
+	return ""
+}
+
+// GET /v1/categories
+func (s *Server) listCategories(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	categories, err := loadCategories(userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, categories)
+}
+
+// POST /v1/categories
+func (s *Server) saveCategory(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var category models.Category
+	if err := c.BindJSON(&category); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		c.JSON(400, gin.H{"error": "name is required"})
+		return
+	}
+
+	var count int64
+	database.DB.Model(&models.Category{}).Where("user_id = ? AND LOWER(name) = LOWER(?)", userID, category.Name).Count(&count)
+	if count > 0 {
+		c.JSON(409, gin.H{"error": "category already exists"})
+		return
+	}
+
+	category.ID = 0
+	category.UserID = userID
+	category.SubCategories = nil
+	if err := database.DB.Create(&category).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(201, category)
+}
+
+// PUT /v1/categories/:id
+// Renaming a category also renames it on the user's existing entries.
+func (s *Server) updateCategory(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var category models.Category
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&category).Error; err != nil {
+		c.JSON(404, gin.H{"error": "category not found"})
+		return
+	}
+	oldName := category.Name
+
+	if err := c.BindJSON(&category); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		c.JSON(400, gin.H{"error": "name is required"})
+		return
+	}
+
+	category.ID = uint(id)
+	category.UserID = userID
+	category.SubCategories = nil
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if !strings.EqualFold(oldName, category.Name) {
+			var count int64
+			tx.Model(&models.Category{}).Where("user_id = ? AND LOWER(name) = LOWER(?) AND id != ?", userID, category.Name, id).Count(&count)
+			if count > 0 {
+				return &httpError{status: 409, msg: "category already exists"}
+			}
+		}
+		if err := tx.Save(&category).Error; err != nil {
+			return err
+		}
+		if oldName != category.Name {
+			return tx.Model(&models.Entry{}).
+				Where("user_id = ? AND category = ?", userID, oldName).
+				Update("category", category.Name).Error
+		}
+		return nil
+	})
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(200, category)
+}
+
+// DELETE /v1/categories/:id
+func (s *Server) deleteCategory(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("category_id = ? AND user_id = ?", id, userID).Delete(&models.SubCategory{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Category{}).Error
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "category deleted"})
+}
+
+// POST /v1/categories/:id/subcategories
+func (s *Server) saveSubCategory(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var category models.Category
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&category).Error; err != nil {
+		c.JSON(404, gin.H{"error": "category not found"})
+		return
+	}
+
+	var sub models.SubCategory
+	if err := c.BindJSON(&sub); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	sub.Name = strings.TrimSpace(sub.Name)
+	if sub.Name == "" {
+		c.JSON(400, gin.H{"error": "name is required"})
+		return
+	}
+
+	sub.ID = 0
+	sub.UserID = userID
+	sub.CategoryID = category.ID
+	if err := database.DB.Create(&sub).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(201, sub)
+}
+
+// PUT /v1/subcategories/:id
+func (s *Server) updateSubCategory(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var sub models.SubCategory
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&sub).Error; err != nil {
+		c.JSON(404, gin.H{"error": "sub-category not found"})
+		return
+	}
+	oldName := sub.Name
+	categoryID := sub.CategoryID
+
+	if err := c.BindJSON(&sub); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub.ID = uint(id)
+	sub.UserID = userID
+	sub.CategoryID = categoryID
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&sub).Error; err != nil {
+			return err
+		}
+		if oldName != sub.Name {
+			return tx.Model(&models.Entry{}).
+				Where("user_id = ? AND sub_category = ?", userID, oldName).
+				Update("sub_category", sub.Name).Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, sub)
+}
+
+// DELETE /v1/subcategories/:id
+func (s *Server) deleteSubCategory(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.SubCategory{}).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "sub-category deleted"})
+}
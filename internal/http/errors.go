@@ -0,0 +1,23 @@
+package http
+
+import "github.com/gin-gonic/gin"
+
+// httpError lets code running inside a DB transaction report a specific
+// status back to the handler.
+type httpError struct {
+	status int
+	msg    string
+}
+
+func (e *httpError) Error() string { return e.msg }
+
+func errBadRequest(msg string) error { return &httpError{status: 400, msg: msg} }
+func errNotFound(msg string) error   { return &httpError{status: 404, msg: msg} }
+
+func writeError(c *gin.Context, err error) {
+	if he, ok := err.(*httpError); ok {
+		c.JSON(he.status, gin.H{"error": he.msg})
+		return
+	}
+	c.JSON(500, gin.H{"error": err.Error()})
+}
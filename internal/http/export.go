@@ -0,0 +1,89 @@
+package http
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"finance-parser-go/internal/database"
+	"finance-parser-go/internal/export"
+	"finance-parser-go/internal/models"
+)
+
+// GET /v1/entries/export?format=csv|xlsx|json
+// Accepts the same filters as listEntries plus columns (comma separated keys)
+// and locale (en-IN, en-US, de-DE, ...; CSV only). Rows are read through a
+// cursor and written as they arrive so large exports don't sit in memory.
+func (s *Server) exportEntries(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	format := strings.ToLower(c.DefaultQuery("format", "csv"))
+	contentType, ok := export.ContentType[format]
+	if !ok {
+		c.JSON(400, gin.H{"error": "format must be csv, xlsx or json"})
+		return
+	}
+
+	var keys []string
+	if cols := c.Query("columns"); cols != "" {
+		keys = strings.Split(cols, ",")
+	}
+	cols, err := export.Columns(keys)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	locale, ok := export.LookupLocale(c.Query("locale"))
+	if !ok {
+		c.JSON(400, gin.H{"error": "unknown locale"})
+		return
+	}
+
+	query, err := s.filterEntries(c, userID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	rows, err := query.Model(&models.Entry{}).Rows()
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	filename := fmt.Sprintf("entries_%s.%s", time.Now().In(s.userLocation(c)).Format("2006-01-02"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(200)
+
+	// From here on the status is sent; failures can only be logged.
+	w, err := export.NewWriter(format, c.Writer, cols, locale)
+	if err != nil {
+		log.Printf("export: %v", err)
+		return
+	}
+	count := 0
+	for rows.Next() {
+		var entry models.Entry
+		if err := database.DB.ScanRows(rows, &entry); err != nil {
+			log.Printf("export: scan failed: %v", err)
+			return
+		}
+		if err := w.Write(&entry); err != nil {
+			log.Printf("export: write failed: %v", err)
+			return
+		}
+		count++
+		if count%500 == 0 {
+			c.Writer.Flush()
+		}
+	}
+	if err := w.Close(); err != nil {
+		log.Printf("export: %v", err)
+	}
+}
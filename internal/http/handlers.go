@@ -4,28 +4,39 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 	timepkg "time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/xeipuuv/gojsonschema"
+	"gorm.io/gorm"
 
 	"finance-parser-go/internal/ai"
+	"finance-parser-go/internal/audio"
 	"finance-parser-go/internal/config"
 	"finance-parser-go/internal/database"
 	"finance-parser-go/internal/models"
+	"finance-parser-go/internal/rules"
 )
 
 type Server struct {
-	cfg       *config.Config
-	validator *gojsonschema.Schema
-	openai    *ai.OpenAIClient
+	cfg            *config.Config
+	validator      *gojsonschema.Schema
+	queryValidator *gojsonschema.Schema
+	parser         ai.Parser
+	transcriber    ai.Transcriber
+	imageParser    ai.ImageParser   // nil when VISION_PROVIDER=none
+	transcoder     audio.Transcoder // nil when AUDIO_TRANSCODER=none
 }
 
 func NewServer(cfg *config.Config) *gin.Engine {
@@ -42,8 +53,20 @@ func NewServer(cfg *config.Config) *gin.Engine {
 				strings.HasPrefix(c.Request.URL.Path, "/v1/quick-prompts") ||
 				strings.HasPrefix(c.Request.URL.Path, "/v1/user") ||
 				strings.HasPrefix(c.Request.URL.Path, "/v1/insights") ||
+				strings.HasPrefix(c.Request.URL.Path, "/v1/reports") ||
 				strings.HasPrefix(c.Request.URL.Path, "/v1/accounts") ||
-				strings.HasPrefix(c.Request.URL.Path, "/v1/parse") {
+				strings.HasPrefix(c.Request.URL.Path, "/v1/people") ||
+				strings.HasPrefix(c.Request.URL.Path, "/v1/loans") ||
+				strings.HasPrefix(c.Request.URL.Path, "/v1/categories") ||
+				strings.HasPrefix(c.Request.URL.Path, "/v1/subcategories") ||
+				strings.HasPrefix(c.Request.URL.Path, "/v1/tags") ||
+				strings.HasPrefix(c.Request.URL.Path, "/v1/rules") ||
+				strings.HasPrefix(c.Request.URL.Path, "/v1/merchants") ||
+				strings.HasPrefix(c.Request.URL.Path, "/v1/import") ||
+				strings.HasPrefix(c.Request.URL.Path, "/v1/ingest") ||
+				strings.HasPrefix(c.Request.URL.Path, "/v1/parse") ||
+				strings.HasPrefix(c.Request.URL.Path, "/v1/ask") ||
+				strings.HasPrefix(c.Request.URL.Path, "/v1/admin") {
 				log.Printf("[DEBUG] Auth Skip: %s", c.Request.URL.Path)
 				c.Next()
 				return
@@ -57,15 +80,35 @@ func NewServer(cfg *config.Config) *gin.Engine {
 		})
 	}
 
-	loader := gojsonschema.NewReferenceLoader("file://./schemas/expense_entry.schema.json")
+	loader := gojsonschema.NewReferenceLoader("file://./schemas/parse_response.schema.json")
 	schema, err := gojsonschema.NewSchema(loader)
 	if err != nil {
 		panic(err)
 	}
+	querySchema, err := gojsonschema.NewSchema(gojsonschema.NewReferenceLoader("file://./schemas/ask_query.schema.json"))
+	if err != nil {
+		panic(err)
+	}
 
-	openai := ai.NewOpenAIClient(cfg)
+	parser, err := ai.NewParser(cfg)
+	if err != nil {
+		panic(err)
+	}
+	transcriber, err := ai.NewTranscriber(cfg)
+	if err != nil {
+		panic(err)
+	}
 
-	s := &Server{cfg: cfg, validator: schema, openai: openai}
+	imageParser, err := ai.NewImageParser(cfg)
+	if err != nil {
+		panic(err)
+	}
+	transcoder, err := audio.NewTranscoder(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	s := &Server{cfg: cfg, validator: schema, queryValidator: querySchema, parser: parser, transcriber: transcriber, imageParser: imageParser, transcoder: transcoder}
 	// Auth
 	r.POST("/v1/auth/guest", s.authGuest)
 	r.POST("/v1/auth/identify", s.authIdentify)
@@ -79,11 +122,17 @@ func NewServer(cfg *config.Config) *gin.Engine {
 	authorized.Use(AuthMiddleware())
 	{
 		authorized.POST("/parse", s.handleParse)
+		authorized.POST("/parse/stream", s.parseStream)
+		authorized.POST("/parse/image", s.parseImage)
+		authorized.POST("/parse/:session/answer", s.answerParse)
 		authorized.POST("/entries", s.saveEntry)
+		authorized.POST("/entries/bulk", s.saveEntries)
 		authorized.GET("/entries", s.listEntries)
+		authorized.GET("/entries/export", s.exportEntries)
 		authorized.GET("/entries/:id", s.getEntry)
 		authorized.PUT("/entries/:id", s.updateEntry)
 		authorized.DELETE("/entries/:id", s.deleteEntry)
+		authorized.GET("/entries/:id/email", s.getEntryEmail)
 		authorized.GET("/quick-prompts", s.listQuickPrompts)
 		authorized.POST("/quick-prompts", s.saveQuickPrompt)
 		authorized.PUT("/quick-prompts/:id", s.updateQuickPrompt)
@@ -99,6 +148,66 @@ func NewServer(cfg *config.Config) *gin.Engine {
 
 		// Insights
 		authorized.GET("/insights", s.getInsights)
+		authorized.POST("/ask", s.ask)
+
+		// Reports
+		authorized.POST("/reports", s.createReport)
+		authorized.GET("/reports", s.listReports)
+		authorized.GET("/reports/:id/download", s.downloadReport)
+		authorized.DELETE("/reports/:id", s.deleteReport)
+
+		// People & Loans
+		authorized.GET("/people", s.listPeople)
+		authorized.POST("/people", s.savePerson)
+		authorized.GET("/people/:id", s.getPerson)
+		authorized.PUT("/people/:id", s.updatePerson)
+		authorized.DELETE("/people/:id", s.deletePerson)
+		authorized.POST("/loans", s.saveLoan)
+		authorized.GET("/loans", s.listLoans)
+		authorized.POST("/loans/:id/repayments", s.addRepayment)
+
+		// Categories & Tags
+		authorized.GET("/categories", s.listCategories)
+		authorized.POST("/categories", s.saveCategory)
+		authorized.PUT("/categories/:id", s.updateCategory)
+		authorized.DELETE("/categories/:id", s.deleteCategory)
+		authorized.POST("/categories/:id/subcategories", s.saveSubCategory)
+		authorized.PUT("/subcategories/:id", s.updateSubCategory)
+		authorized.DELETE("/subcategories/:id", s.deleteSubCategory)
+		authorized.GET("/tags", s.listTags)
+		authorized.POST("/tags/rename", s.renameTag)
+		authorized.POST("/tags/merge", s.mergeTags)
+
+		// Rules
+		authorized.GET("/rules", s.listRules)
+		authorized.POST("/rules", s.saveRule)
+		authorized.PUT("/rules/:id", s.updateRule)
+		authorized.DELETE("/rules/:id", s.deleteRule)
+		authorized.POST("/rules/apply", s.applyRulesRetroactively)
+
+		// Merchants
+		authorized.GET("/merchants", s.listMerchants)
+		authorized.POST("/merchants", s.saveMerchant)
+		authorized.PUT("/merchants/:id", s.updateMerchant)
+		authorized.DELETE("/merchants/:id", s.deleteMerchant)
+		authorized.POST("/merchants/merge", s.mergeMerchants)
+
+		// Imports
+		authorized.POST("/import/csv", s.importCSV)
+		authorized.POST("/import/statement", s.importStatement)
+		authorized.GET("/import/batches", s.listImportBatches)
+		authorized.DELETE("/import/batches/:id", s.undoImportBatch)
+
+		// Ingestion
+		authorized.POST("/ingest/sms", s.ingestSMS)
+		authorized.POST("/ingest/email", s.ingestEmail)
+	}
+
+	// Admin (ADMIN_TOKEN)
+	admin := r.Group("/v1/admin")
+	admin.Use(adminOnly(cfg.AdminToken))
+	{
+		admin.GET("/parse-quality", s.parseQuality)
 	}
 
 	r.Static("/uploads", "./uploads")
@@ -111,74 +220,331 @@ func (s *Server) handleParse(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), timepkg.Duration(s.cfg.ReqTimeoutSec)*timepkg.Second)
 	defer cancel()
 
-	tz := c.PostForm("tz")
-	if tz == "" {
-		tz = s.cfg.TZDefault
+	tz := s.parseTZ(c)
+	version, ok := s.promptVersion(c)
+	if !ok {
+		return
 	}
-
-	var transcript string
-	file, header, err := c.Request.FormFile("audio")
-	if err == nil {
-		defer file.Close()
-		if header.Size > s.cfg.MaxUploadMB*1024*1024 {
-			c.JSON(413, gin.H{"error": "file too large"})
-			return
-		}
-		buf := &bytes.Buffer{}
-		if _, err := io.Copy(buf, file); err != nil {
-			c.JSON(400, gin.H{"error": "failed to read file"})
-			return
-		}
-		if t, err := s.openai.Transcribe(ctx, header.Filename, buf.Bytes()); err == nil {
-			transcript = t
-		} else {
-			log.Printf("stt error: %v", err)
-		}
+	ctx = ai.WithPromptVersion(ctx, version)
+	language, ok := s.sttLanguage(c)
+	if !ok {
+		return
 	}
-
-	if transcript == "" {
-		transcript = c.PostForm("hint_text")
+	clip, ok := s.readParseAudio(ctx, c)
+	if !ok {
+		return
+	}
+	userID := c.MustGet("userID").(uint)
+	transcript, over, err := s.transcribeMetered(ctx, c, userID, clip, language, c.PostForm("hint_text"), false)
+	if over != nil {
+		c.JSON(429, over)
+		return
+	}
+	if err != nil {
+		status, body := providerFailure(c, err)
+		c.JSON(status, body)
+		return
 	}
-	if strings.TrimSpace(transcript) == "" {
+	if transcript == "" {
 		c.JSON(400, gin.H{"error": "no audio or hint_text provided"})
 		return
 	}
 
-	parsed, err := s.openai.ParseText(ctx, transcript, tz)
+	pc := loadParseContext(userID, tz)
+	source := "text"
+	if clip != nil {
+		source = "audio"
+	}
+	trace := s.startTrace(ctx, userID, source, transcript)
+	trace.audio(clip)
+	parsed, over, err := s.parseTranscript(ctx, c, pc, transcript, trace, nil)
+	if over != nil {
+		c.JSON(429, over)
+		return
+	}
 	if err != nil {
-		c.JSON(422, gin.H{"error": "could_not_parse", "transcript": transcript})
+		s.saveTrace(trace, nil)
+		status, body := providerFailure(c, err)
+		body["transcript"] = transcript
+		c.JSON(status, body)
 		return
 	}
 
-	var parsedObj map[string]any
-	if err := json.Unmarshal(parsed, &parsedObj); err != nil {
+	drafts, err := ai.SplitDrafts(parsed)
+	if err != nil {
+		trace.fail(err)
+		s.saveTrace(trace, nil)
 		c.JSON(500, gin.H{"error": "invalid_parse_response"})
 		return
 	}
 
-	if s.ensureDate(parsedObj, transcript, tz) {
-		parsed, err = json.Marshal(parsedObj)
+	// Each draft is post-processed on its own; one utterance can mention
+	// several transactions.
+	for _, draft := range drafts {
+		s.finishDraft(pc, draft)
+	}
+	s.saveTrace(trace, drafts)
+	if !s.validateDrafts(c, drafts, transcript) {
+		return
+	}
+
+	sessionID, err := s.startParseSession(userID, transcript, tz, version, drafts)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("X-Parse-Session", sessionID)
+	c.JSON(200, drafts)
+}
+
+func (s *Server) parseTZ(c *gin.Context) string {
+	if tz := c.PostForm("tz"); tz != "" {
+		return tz
+	}
+	return s.userLocation(c).String()
+}
+
+// promptVersion reads the optional "prompt_version" form field, defaulting
+// to PROMPT_VERSION, and echoes it in X-Prompt-Version. It writes a 400
+// response and returns false for a version that doesn't exist.
+func (s *Server) promptVersion(c *gin.Context) (string, bool) {
+	version := c.PostForm("prompt_version")
+	if version == "" {
+		version = s.cfg.PromptVersion
+	}
+	if !ai.KnownPromptVersion(version) {
+		c.JSON(400, gin.H{"error": "unknown_prompt_version", "available": ai.PromptVersions()})
+		return "", false
+	}
+	c.Header("X-Prompt-Version", version)
+	return version, true
+}
+
+var languageRe = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+// sttLanguage reads the optional "language" form field, an ISO-639-1 code
+// passed to speech-to-text, defaulting to STT_LANGUAGE. It writes a 400
+// response and returns false for a malformed code.
+func (s *Server) sttLanguage(c *gin.Context) (string, bool) {
+	language := strings.ToLower(strings.TrimSpace(c.PostForm("language")))
+	if language == "" {
+		return s.cfg.STTLanguage, true
+	}
+	if !languageRe.MatchString(language) {
+		c.JSON(400, gin.H{"error": "invalid language"})
+		return "", false
+	}
+	return language, true
+}
+
+// readParseAudio reads the optional "audio" upload of a parse request and
+// prepares it for speech-to-text: the format is sniffed, converted when the
+// model can't read it, and the clip checked for length and silence. The
+// clip is nil without an upload, or with an empty one next to hint_text.
+// It writes an error response and returns false when the upload is unusable.
+func (s *Server) readParseAudio(ctx context.Context, c *gin.Context) (*audio.Clip, bool) {
+	file, header, err := c.Request.FormFile("audio")
+	if err != nil {
+		return nil, true
+	}
+	defer file.Close()
+	if header.Size > s.cfg.MaxUploadMB*1024*1024 {
+		c.JSON(413, gin.H{"error": "file too large"})
+		return nil, false
+	}
+	buf := &bytes.Buffer{}
+	if _, err := io.Copy(buf, file); err != nil {
+		c.JSON(400, gin.H{"error": "failed to read file"})
+		return nil, false
+	}
+	data := buf.Bytes()
+	if len(data) == 0 && strings.TrimSpace(c.PostForm("hint_text")) != "" {
+		return nil, true
+	}
+	// The fake transcriber reads plain text as the spoken words.
+	if strings.EqualFold(s.cfg.STTProvider, "fake") && len(data) > 0 && utf8.Valid(data) {
+		return &audio.Clip{Filename: header.Filename, Data: data}, true
+	}
+
+	limits := audio.Limits{MaxDuration: timepkg.Duration(s.cfg.AudioMaxSeconds) * timepkg.Second}
+	clip, err := audio.Prepare(ctx, header.Filename, data, s.transcoder, limits)
+	if err != nil {
+		log.Printf("audio rejected: %s: %v", header.Filename, err)
+		c.JSON(audioFailure(err, clip, s.cfg.AudioMaxSeconds))
+		return nil, false
+	}
+	return clip, true
+}
+
+// audioFailure maps an audio.Prepare error to a response.
+func audioFailure(err error, clip *audio.Clip, maxSeconds int) (int, gin.H) {
+	body := gin.H{"detail": err.Error()}
+	status := 400
+	switch {
+	case errors.Is(err, audio.ErrEmpty):
+		body["error"] = "empty_audio"
+	case errors.Is(err, audio.ErrUnsupported):
+		status, body["error"] = 415, "unsupported_audio_format"
+	case errors.Is(err, audio.ErrTranscode):
+		status, body["error"] = 422, "audio_transcode_failed"
+	case errors.Is(err, audio.ErrTooLong):
+		status, body["error"] = 413, "audio_too_long"
+		body["max_seconds"] = maxSeconds
+	case errors.Is(err, audio.ErrTooShort):
+		status, body["error"] = 422, "audio_too_short"
+	case errors.Is(err, audio.ErrSilent):
+		status, body["error"] = 422, "silent_audio"
+	case errors.Is(err, audio.ErrUnmeasured):
+		status, body["error"] = 422, "audio_length_unknown"
+		body["max_seconds"] = maxSeconds
+	default:
+		body["error"] = "invalid_audio"
+	}
+	if clip != nil {
+		body["format"] = clip.Source
+		if clip.Duration > 0 {
+			body["duration_seconds"] = math.Round(clip.Duration.Seconds()*10) / 10
+		}
+	}
+	return status, body
+}
+
+// transcribe returns the transcript of clip, falling back to hint when
+// there is no clip or speech-to-text fails. It is "" when neither is usable,
+// with the speech-to-text error when there was one.
+func (s *Server) transcribe(ctx context.Context, clip *audio.Clip, language, hint string) (string, error) {
+	hint = strings.TrimSpace(hint)
+	if clip != nil {
+		start := timepkg.Now()
+		t, err := s.transcriber.Transcribe(ctx, clip.Filename, clip.Data, language)
+		log.Printf("stt: %s audio, %s long, %dms", clip.Format, clip.Duration.Round(timepkg.Millisecond), timepkg.Since(start).Milliseconds())
+		if err == nil && strings.TrimSpace(t) != "" {
+			return t, nil
+		}
 		if err != nil {
-			c.JSON(500, gin.H{"error": "serialization_failed"})
-			return
+			log.Printf("stt error: %v", err)
+			if hint == "" {
+				return "", err
+			}
 		}
 	}
+	return hint, nil
+}
+
+// providerFailure maps a parser or speech-to-text error to a response: the
+// provider's state when it is to blame, could_not_parse otherwise. The
+// provider's Retry-After is passed on.
+func providerFailure(c *gin.Context, err error) (int, gin.H) {
+	status, code := 422, "could_not_parse"
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, ai.ErrTimeout):
+		status, code = 504, "timeout"
+	case errors.Is(err, ai.ErrRateLimited):
+		status, code = 503, "provider_rate_limited"
+	case errors.Is(err, ai.ErrUnavailable):
+		status, code = 503, "provider_unavailable"
+	case errors.Is(err, ai.ErrNotConfigured):
+		status, code = 503, "provider_not_configured"
+	case errors.Is(err, ai.ErrBadResponse):
+		status, code = 502, "provider_bad_response"
+	}
+	if wait := ai.RetryAfter(err); wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	}
+	return status, gin.H{"error": code}
+}
+
+// finishDraft runs the server-side clean-up shared by every parse path.
+// Rules run last so they can override the resolved account.
+func (s *Server) finishDraft(pc *parseContext, draft map[string]any) {
+	pc.restoreSource(draft)
+	s.ensureDate(draft, pc.tz)
+	normalizeDraftMerchant(pc.merchants, draft)
+	ensureCategory(draft, pc.profile.Categories)
+	applyDefaultMode(pc.profile, draft)
+	resolveDraftAccount(pc.accounts, draft)
+	applyRulesToDraft(pc.userID, draft)
+}
+
+// validateDrafts checks drafts against schemas/parse_response.schema.json,
+// writing a 422 response and returning false when they don't conform.
+func (s *Server) validateDrafts(c *gin.Context, drafts []map[string]any, transcript string) bool {
+	d, err := s.schemaErrors(drafts)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return false
+	}
+	if len(d) > 0 {
+		c.JSON(422, gin.H{"error": "schema_invalid", "details": d, "transcript": transcript})
+		return false
+	}
+	return true
+}
+
+// schemaErrors lists how drafts violate the parse response schema; it is
+// empty when they conform.
+func (s *Server) schemaErrors(drafts []map[string]any) ([]string, error) {
+	parsed, err := json.Marshal(gin.H{"entries": drafts})
+	if err != nil {
+		return nil, errors.New("serialization_failed")
+	}
 
 	res, err := s.validator.Validate(gojsonschema.NewBytesLoader(parsed))
 	if err != nil {
-		c.JSON(500, gin.H{"error": "validation_failed"})
+		return nil, errors.New("validation_failed")
+	}
+	d := []string{}
+	for _, e := range res.Errors() {
+		d = append(d, e.String())
+	}
+	return d, nil
+}
+
+// POST /v1/entries/bulk
+// Saves several entries at once, e.g. all drafts returned by one /v1/parse
+// call. Either every entry is created or none are.
+func (s *Server) saveEntries(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input struct {
+		Entries []models.Entry `json:"entries" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if len(input.Entries) == 0 {
+		c.JSON(400, gin.H{"error": "no entries provided"})
 		return
 	}
-	if !res.Valid() {
-		d := []string{}
-		for _, e := range res.Errors() {
-			d = append(d, e.String())
+
+	loc := s.userLocation(c)
+	directory := loadMerchants(userID)
+	ruleList := loadRules(userID)
+	for i := range input.Entries {
+		entry := &input.Entries[i]
+		entry.ID = 0
+		entry.UserID = userID
+		if !accountBelongsToUser(database.DB, entry.AccountID, userID) {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("entry %d: account not found", i), "index": i})
+			return
 		}
-		c.JSON(422, gin.H{"error": "schema_invalid", "details": d, "transcript": transcript})
+		if err := setOccurredAt(entry, loc); err != nil {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("entry %d: %s", i, err.Error()), "index": i})
+			return
+		}
+		normalizeMerchant(directory, entry)
+		rules.Apply(ruleList, entry)
+	}
+	parseLogs := loadParseLogs(userID, input.Entries)
+
+	if err := database.DB.Create(&input.Entries).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
-	fmt.Print("parsedData", parsed)
-	c.Data(200, "application/json", parsed)
+	recordCorrections(parseLogs, input.Entries)
+
+	c.JSON(201, input.Entries)
 }
 
 func (s *Server) saveEntry(c *gin.Context) {
@@ -197,11 +563,25 @@ func (s *Server) saveEntry(c *gin.Context) {
 	}
 
 	entry.UserID = userID
+	if !accountBelongsToUser(database.DB, entry.AccountID, userID) {
+		c.JSON(400, gin.H{"error": "account not found"})
+		return
+	}
+	if err := setOccurredAt(&entry, s.userLocation(c)); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	normalizeMerchant(loadMerchants(userID), &entry)
+	applyRules(userID, &entry)
+	saved := []models.Entry{entry}
+	parseLogs := loadParseLogs(userID, saved)
+	entry.ParseLogID = saved[0].ParseLogID
 
 	if err := database.DB.Create(&entry).Error; err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
+	recordCorrections(parseLogs, []models.Entry{entry})
 
 	c.JSON(201, entry)
 }
@@ -216,7 +596,32 @@ func (s *Server) listEntries(c *gin.Context) {
 
 	var entries []models.Entry
 
-	query := database.DB.Where("user_id = ?", userID).Order("date desc, created_at desc")
+	query, err := s.filterEntries(c, userID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	log.Printf("[DEBUG] listEntries Filters | Type: %s | Cat: %s | Mode: %s | Min: %s | Max: %s | Start: %s | End: %s | Tag: %s",
+		c.Query("type"), c.Query("category"), c.Query("mode"),
+		c.Query("min_amount"), c.Query("max_amount"),
+		c.Query("start_date"), c.Query("end_date"), c.Query("tag"),
+	)
+
+	if err := query.Find(&entries).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, entries)
+	log.Printf("[DEBUG] listEntries: Found %d entries", len(entries))
+}
+
+// filterEntries builds the entry query from the listEntries query
+// parameters, newest first. It is shared with the export endpoint.
+func (s *Server) filterEntries(c *gin.Context, userID uint) (*gorm.DB, error) {
+	loc := s.userLocation(c)
+	query := database.DB.Where("user_id = ?", userID).Order("occurred_at desc nulls last, created_at desc")
 
 	if t := strings.TrimSpace(c.Query("type")); t != "" && t != "All" {
 		query = query.Where("LOWER(type) = LOWER(?)", t)
@@ -243,11 +648,23 @@ func (s *Server) listEntries(c *gin.Context) {
 	}
 
 	if start := c.Query("start_date"); start != "" {
-		query = query.Where("date >= ?", start)
+		t, err := timepkg.ParseInLocation("2006-01-02", start, loc)
+		if err != nil {
+			return nil, errBadRequest("invalid start_date, expected YYYY-MM-DD")
+		}
+		query = query.Where("occurred_at >= ?", t)
 	}
 
 	if end := c.Query("end_date"); end != "" {
-		query = query.Where("date <= ?", end)
+		t, err := timepkg.ParseInLocation("2006-01-02", end, loc)
+		if err != nil {
+			return nil, errBadRequest("invalid end_date, expected YYYY-MM-DD")
+		}
+		query = query.Where("occurred_at < ?", t.AddDate(0, 0, 1))
+	}
+
+	if draft := c.Query("draft"); draft != "" {
+		query = query.Where("draft = ?", draft == "true")
 	}
 
 	if tag := strings.TrimSpace(c.Query("tag")); tag != "" {
@@ -256,19 +673,7 @@ func (s *Server) listEntries(c *gin.Context) {
 		}
 	}
 
-	log.Printf("[DEBUG] listEntries Filters | Type: %s | Cat: %s | Mode: %s | Min: %s | Max: %s | Start: %s | End: %s | Tag: %s",
-		c.Query("type"), c.Query("category"), c.Query("mode"),
-		c.Query("min_amount"), c.Query("max_amount"),
-		c.Query("start_date"), c.Query("end_date"), c.Query("tag"),
-	)
-
-	if err := query.Find(&entries).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(200, entries)
-	log.Printf("[DEBUG] listEntries: Found %d entries", len(entries))
+	return query, nil
 }
 
 func (s *Server) getEntry(c *gin.Context) {
@@ -307,6 +712,7 @@ func (s *Server) updateEntry(c *gin.Context) {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
+	wasDraft := entry.Draft
 
 	// Update fields if present in input
 	if v, ok := input["title"].(string); ok {
@@ -324,29 +730,61 @@ func (s *Server) updateEntry(c *gin.Context) {
 	if v, ok := input["category"].(string); ok {
 		entry.Category = v
 	}
+	if v, ok := input["sub_category"].(string); ok {
+		entry.SubCategory = v
+	}
 	if v, ok := input["notes"].(string); ok {
 		entry.Notes = v
 	}
 	if v, ok := input["merchant"].(string); ok {
 		entry.Merchant = v
+		normalizeMerchant(loadMerchants(userID), &entry)
 	}
+	_, dateSet := input["date"]
+	_, timeSet := input["time"]
 	if v, ok := input["date"].(string); ok {
 		entry.Date = v
 	}
 	if v, ok := input["time"].(string); ok {
 		entry.Time = v
 	}
+	if dateSet || timeSet || entry.OccurredAt == nil {
+		if err := setOccurredAt(&entry, s.userLocation(c)); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
 	if v, ok := input["tag"].(string); ok {
 		entry.Tag = v
 	}
 	if v, ok := input["attachment"].(string); ok {
 		entry.Attachment = v
 	}
+	if v, ok := input["draft"].(bool); ok {
+		entry.Draft = v
+	}
+	if v, ok := input["account_id"]; ok {
+		if id, ok := v.(float64); ok {
+			accountID := uint(id)
+			if !accountBelongsToUser(database.DB, &accountID, userID) {
+				c.JSON(400, gin.H{"error": "account not found"})
+				return
+			}
+			entry.AccountID = &accountID
+		} else if v == nil {
+			entry.AccountID = nil
+		}
+	}
 
 	if err := database.DB.Save(&entry).Error; err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
+	// Confirming an ingested draft is when its parse gets compared.
+	if wasDraft && !entry.Draft && entry.ParseLogID != nil {
+		confirmed := []models.Entry{entry}
+		recordCorrections(loadParseLogs(userID, confirmed), confirmed)
+	}
 
 	c.JSON(200, entry)
 }
@@ -379,9 +817,9 @@ func (s *Server) listQuickPrompts(c *gin.Context) {
 	// Seed default prompts if none exist
 	if len(prompts) == 0 {
 		defaults := []models.QuickPrompt{
-			{UserID: userID, Title: "Morning Coffee", Amount: 150, Mode: "Cash", Category: "Food & Drinks", Icon: "coffee-outline"},
+			{UserID: userID, Title: "Morning Coffee", Amount: 150, Mode: "Cash", Category: "Food", Icon: "coffee-outline"},
 			{UserID: userID, Title: "Metro Recharge", Amount: 500, Mode: "UPI", Category: "Travel", Icon: "train"},
-			{UserID: userID, Title: "Car Fuel", Amount: 3000, Mode: "Credit Card", Category: "Transport", Icon: "gas-station-outline"},
+			{UserID: userID, Title: "Car Fuel", Amount: 3000, Mode: "Credit Card", Category: "Travel", Icon: "gas-station-outline"},
 		}
 		for _, p := range defaults {
 			database.DB.Create(&p)
@@ -468,6 +906,7 @@ func (s *Server) updateProfile(c *gin.Context) {
 		Username   string `json:"username"`
 		Email      string `json:"email"`
 		Phone      string `json:"phone"`
+		Timezone   string `json:"timezone"`
 		ClaimToken string `json:"claim_token"`
 	}
 
@@ -517,6 +956,14 @@ func (s *Server) updateProfile(c *gin.Context) {
 		user.Phone = &payload.Phone
 	}
 
+	if payload.Timezone != "" {
+		if _, err := timepkg.LoadLocation(payload.Timezone); err != nil {
+			c.JSON(400, gin.H{"error": "Invalid timezone"})
+			return
+		}
+		user.Timezone = payload.Timezone
+	}
+
 	user.Username = payload.Username
 
 	if err := database.DB.Save(&user).Error; err != nil {
@@ -533,6 +980,7 @@ func cors(cfg *config.Config) gin.HandlerFunc {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", cfg.AllowOrigins)
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Parse-Session, X-Prompt-Version")
 		if c.Request.Method == http.MethodOptions {
 			c.AbortWithStatus(204)
 			return
@@ -549,41 +997,76 @@ func logging() gin.HandlerFunc {
 	}
 }
 
-func (s *Server) ensureDate(entry map[string]any, transcript, tz string) bool {
-	loc := loadLocationOrIndia(tz, s.cfg.TZDefault)
-	now := timepkg.Now().In(loc)
-
-	var desired string
-
+func (s *Server) ensureDate(entry map[string]any, tz string) bool {
 	dateStr, _ := entry["date"].(string)
-	dateStr = strings.TrimSpace(dateStr)
-	_, parsedErr := timepkg.Parse("2006-01-02", dateStr)
-	needsDateConfirmation := needsDateConfirmation(entry)
-
-	switch {
-	case needsDateConfirmation:
-		desired = now.Format("2006-01-02")
-	case dateStr == "" || parsedErr != nil:
-		desired = now.Format("2006-01-02")
+	if _, err := timepkg.Parse("2006-01-02", strings.TrimSpace(dateStr)); err == nil {
+		// A valid date is kept even when it awaits confirmation; the user
+		// confirms or corrects it through /v1/parse/:session/answer.
+		return false
 	}
 
-	if desired == "" {
+	loc := loadLocationOrIndia(tz, s.cfg.TZDefault)
+	entry["date"] = timepkg.Now().In(loc).Format("2006-01-02")
+	return true
+}
+
+// ensureCategory validates the parsed category against the user's own list.
+// Known categories are normalised to the user's spelling; anything else is
+// cleared and flagged for confirmation rather than rejected outright.
+func ensureCategory(entry map[string]any, categories []string) bool {
+	raw, ok := entry["category"].(string)
+	if !ok || strings.TrimSpace(raw) == "" {
 		return false
 	}
+	if match := matchCategory(raw, categories); match != "" {
+		if match == raw {
+			return false
+		}
+		entry["category"] = match
+		return true
+	}
 
-	entry["date"] = desired
+	entry["category"] = nil
+	nc, _ := entry["needs_confirmation"].(map[string]any)
+	if nc == nil {
+		nc = map[string]any{}
+	}
+	nc["category"] = true
+	entry["needs_confirmation"] = nc
+	clarifications, _ := entry["clarifications"].([]any)
+	entry["clarifications"] = append(clarifications, fmt.Sprintf("Which category should this go under? (%s)", strings.Join(categories, ", ")))
 	return true
 }
 
-func needsDateConfirmation(entry map[string]any) bool {
-	raw, ok := entry["needs_confirmation"].(map[string]any)
-	if !ok {
-		return false
+// userLocation is the authenticated user's timezone, or the server default.
+func (s *Server) userLocation(c *gin.Context) *timepkg.Location {
+	tz := ""
+	if u, ok := c.Get("user"); ok {
+		tz = u.(*models.User).Timezone
 	}
-	if val, ok := raw["date"].(bool); ok {
-		return val
+	return loadLocationOrIndia(tz, s.cfg.TZDefault)
+}
+
+// setOccurredAt validates entry.Date/Time and derives OccurredAt from them in
+// loc. An entry without a date is stamped with the current time.
+func setOccurredAt(entry *models.Entry, loc *timepkg.Location) error {
+	if strings.TrimSpace(entry.Date) == "" {
+		now := timepkg.Now().In(loc)
+		entry.Date = now.Format("2006-01-02")
+		if strings.TrimSpace(entry.Time) == "" {
+			entry.OccurredAt = &now
+			return nil
+		}
 	}
-	return false
+	if _, err := timepkg.Parse("2006-01-02", strings.TrimSpace(entry.Date)); err != nil {
+		return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", entry.Date)
+	}
+	at, err := models.ParseOccurredAt(entry.Date, entry.Time, loc)
+	if err != nil {
+		return fmt.Errorf("invalid time %q, expected HH:MM or HH:MM:SS", entry.Time)
+	}
+	entry.OccurredAt = &at
+	return nil
 }
 
 func loadLocationOrIndia(requested, fallback string) *timepkg.Location {
@@ -620,14 +1103,16 @@ func (s *Server) handleUpload(c *gin.Context) {
 		return
 	}
 
-	// build full url using host header
+	c.JSON(200, gin.H{"url": publicURL(c, path)})
+}
+
+// publicURL builds the full URL of a file under ./uploads using the host header.
+func publicURL(c *gin.Context, path string) string {
 	scheme := "http"
 	if c.Request.TLS != nil {
 		scheme = "https"
 	}
-	fullURL := fmt.Sprintf("%s://%s/%s", scheme, c.Request.Host, path)
-
-	c.JSON(200, gin.H{"url": fullURL})
+	return fmt.Sprintf("%s://%s/%s", scheme, c.Request.Host, path)
 }
 func (s *Server) saveAccount(c *gin.Context) {
 	userID := c.MustGet("userID").(uint)
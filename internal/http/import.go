@@ -0,0 +1,429 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"finance-parser-go/internal/database"
+	"finance-parser-go/internal/importer"
+	"finance-parser-go/internal/models"
+	"finance-parser-go/internal/rules"
+)
+
+type ImportSummary struct {
+	Rows       int `json:"rows"`
+	Valid      int `json:"valid"`
+	Invalid    int `json:"invalid"`
+	Duplicates int `json:"duplicates"`
+}
+
+// readImportFile reads the multipart "file" field, enforcing MaxUploadMB.
+func (s *Server) readImportFile(c *gin.Context) ([]byte, string, bool) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(400, gin.H{"error": "no file provided"})
+		return nil, "", false
+	}
+	defer file.Close()
+	if header.Size > s.cfg.MaxUploadMB*1024*1024 {
+		c.JSON(413, gin.H{"error": "file too large"})
+		return nil, "", false
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "failed to read file"})
+		return nil, "", false
+	}
+	return data, header.Filename, true
+}
+
+// prepareImportRows turns parsed rows into the entries that would be saved:
+// owner and account are set, categories matched to the user's, merchants
+// normalised, rules applied and occurred_at derived. Rows that match an existing entry (or an earlier row
+// in the same file) are marked as duplicates.
+func (s *Server) prepareImportRows(c *gin.Context, userID uint, accountID *uint, rows []importer.Row) {
+	loc := s.userLocation(c)
+	directory := loadMerchants(userID)
+	ruleList := loadRules(userID)
+	categories := userCategoryNames(userID)
+
+	for i := range rows {
+		r := &rows[i]
+		if len(r.Errors) > 0 {
+			continue
+		}
+		e := &r.Entry
+		if e.Category != "" {
+			// Other apps name their categories differently: one that isn't
+			// the user's own is dropped, for merchant defaults and rules to
+			// fill in.
+			if match := matchCategory(e.Category, categories); match != "" {
+				e.Category = match
+			} else {
+				r.Warnings = append(r.Warnings, fmt.Sprintf("category %q is not one of yours; left empty", e.Category))
+				e.Category, e.SubCategory = "", ""
+			}
+		}
+		e.UserID = userID
+		if e.AccountID == nil {
+			e.AccountID = accountID
+		}
+		normalizeMerchant(directory, e)
+		rules.Apply(ruleList, e)
+		if err := setOccurredAt(e, loc); err != nil {
+			r.Errors = append(r.Errors, err.Error())
+		}
+	}
+
+	markDuplicates(userID, rows, loc)
+}
+
+// markDuplicates flags rows already present for the user. Rows carrying a bank
+// transaction ID are matched on that ID alone, since the same payee can be
+// charged the same amount twice in a day; the rest fall back to
+// importer.DedupeKey, compared on occurred_at in loc rather than the date
+// string.
+func markDuplicates(userID uint, rows []importer.Row, loc *time.Location) {
+	var externalIDs []string
+	for _, r := range rows {
+		if r.Entry.ExternalID != "" && len(r.Errors) == 0 {
+			externalIDs = append(externalIDs, r.Entry.ExternalID)
+		}
+	}
+	seenExternal := map[string]bool{}
+	if len(externalIDs) > 0 {
+		var existing []models.Entry
+		database.DB.Select("external_id", "account_id").
+			Where("user_id = ? AND external_id IN ?", userID, externalIDs).
+			Find(&existing)
+		for _, e := range existing {
+			seenExternal[externalKey(&e)] = true
+		}
+	}
+
+	seen := map[string]bool{}
+	if from, to, ok := importer.DateRange(rows, loc); ok {
+		var existing []models.Entry
+		database.DB.Select("date", "occurred_at", "amount", "title", "merchant").
+			Where("user_id = ? AND occurred_at >= ? AND occurred_at < ?", userID, from, to).
+			Find(&existing)
+		for i := range existing {
+			seen[importer.DedupeKey(&existing[i], loc)] = true
+		}
+	}
+
+	for i := range rows {
+		r := &rows[i]
+		if len(r.Errors) > 0 {
+			continue
+		}
+		if r.Entry.ExternalID != "" {
+			key := externalKey(&r.Entry)
+			r.Duplicate = seenExternal[key]
+			seenExternal[key] = true
+			continue
+		}
+		key := importer.DedupeKey(&r.Entry, loc)
+		if seen[key] {
+			r.Duplicate = true
+			continue
+		}
+		seen[key] = true
+	}
+}
+
+// externalKey scopes a bank transaction ID to its account; IDs are only
+// unique per account.
+func externalKey(e *models.Entry) string {
+	if e.AccountID == nil {
+		return "-|" + e.ExternalID
+	}
+	return strconv.FormatUint(uint64(*e.AccountID), 10) + "|" + e.ExternalID
+}
+
+func summarize(rows []importer.Row) ImportSummary {
+	sum := ImportSummary{Rows: len(rows)}
+	for _, r := range rows {
+		switch {
+		case len(r.Errors) > 0:
+			sum.Invalid++
+		case r.Duplicate:
+			sum.Duplicates++
+		default:
+			sum.Valid++
+		}
+	}
+	return sum
+}
+
+// commitImport stores the valid rows as one batch.
+func commitImport(userID uint, source, filename string, rows []importer.Row) (*models.ImportBatch, error) {
+	sum := summarize(rows)
+	batch := &models.ImportBatch{
+		UserID:   userID,
+		Source:   source,
+		Filename: filename,
+		Rows:     sum.Rows,
+		Skipped:  sum.Invalid + sum.Duplicates,
+		Status:   "committed",
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+		var entries []models.Entry
+		for _, r := range rows {
+			if !r.Valid() {
+				continue
+			}
+			e := r.Entry
+			e.ImportBatchID = &batch.ID
+			entries = append(entries, e)
+		}
+		if len(entries) > 0 {
+			if err := tx.CreateInBatches(entries, 200).Error; err != nil {
+				return err
+			}
+		}
+		batch.Imported = len(entries)
+		return tx.Model(batch).Update("imported", batch.Imported).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// respondImport either previews the rows or, when commit is set, stores them.
+func respondImport(c *gin.Context, userID uint, source, filename string, rows []importer.Row, commit bool, extra gin.H) {
+	res := gin.H{"summary": summarize(rows)}
+	for k, v := range extra {
+		res[k] = v
+	}
+
+	if !commit {
+		res["rows"] = rows
+		c.JSON(200, res)
+		return
+	}
+
+	batch, err := commitImport(userID, source, filename, rows)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	res["batch"] = batch
+	c.JSON(201, res)
+}
+
+func parseAccountID(c *gin.Context, userID uint) (*uint, bool) {
+	raw := c.PostForm("account_id")
+	if raw == "" {
+		return nil, true
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid account_id"})
+		return nil, false
+	}
+	var count int64
+	database.DB.Model(&models.Account{}).Where("id = ? AND user_id = ?", id, userID).Count(&count)
+	if count == 0 {
+		c.JSON(404, gin.H{"error": "account not found"})
+		return nil, false
+	}
+	accountID := uint(id)
+	return &accountID, true
+}
+
+// POST /v1/import/csv
+// Multipart fields: file, mapping (JSON object of Entry field -> column),
+// delimiter, date_format, account_id and commit. Without commit=true the
+// parsed rows are only previewed.
+func (s *Server) importCSV(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	data, filename, ok := s.readImportFile(c)
+	if !ok {
+		return
+	}
+	accountID, ok := parseAccountID(c, userID)
+	if !ok {
+		return
+	}
+
+	opts := importer.CSVOptions{DateFormat: c.PostForm("date_format")}
+	if _, err := importer.LayoutFromPattern(opts.DateFormat); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if d := c.PostForm("delimiter"); d != "" {
+		if d == `\t` || d == "tab" {
+			d = "\t"
+		}
+		r, _ := utf8.DecodeRuneInString(d)
+		opts.Delimiter = r
+	}
+	if m := c.PostForm("mapping"); m != "" {
+		if err := json.Unmarshal([]byte(m), &opts.Mapping); err != nil {
+			c.JSON(400, gin.H{"error": "invalid mapping"})
+			return
+		}
+		if err := importer.ValidateMapping(opts.Mapping); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	parsed, err := importer.ParseCSV(data, opts)
+	if err != nil {
+		c.JSON(422, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.prepareImportRows(c, userID, accountID, parsed.Rows)
+	respondImport(c, userID, "csv", filename, parsed.Rows, c.PostForm("commit") == "true", gin.H{
+		"delimiter":   parsed.Delimiter,
+		"date_format": parsed.DateFormat,
+		"headers":     parsed.Headers,
+		"mapping":     parsed.Mapping,
+	})
+}
+
+// POST /v1/import/statement
+// Imports OFX/QFX, QIF or camt.053 statements. The format is detected from
+// the file unless "format" is given; date_format fixes the QIF date layout.
+// Without account_id each statement account number is matched against the
+// last four digits stored in Account.Identifier. Preview and commit work as
+// for CSV.
+func (s *Server) importStatement(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	data, filename, ok := s.readImportFile(c)
+	if !ok {
+		return
+	}
+	accountID, ok := parseAccountID(c, userID)
+	if !ok {
+		return
+	}
+
+	format := strings.ToLower(c.PostForm("format"))
+	if format == "" {
+		format = importer.DetectFormat(filename, data)
+	}
+	if format == "" {
+		c.JSON(400, gin.H{"error": "unknown statement format; pass format=ofx|qif|camt"})
+		return
+	}
+
+	opts := importer.StatementOptions{DateFormat: c.PostForm("date_format")}
+	if _, err := importer.LayoutFromPattern(opts.DateFormat); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	st, err := importer.ParseStatement(format, data, opts)
+	if err != nil {
+		c.JSON(422, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Without account_id, each statement account in the file is matched on
+	// its own; rows of an unmatched account stay without one.
+	responseAccount := accountID
+	if accountID == nil {
+		matched := map[string]*uint{}
+		for i := range st.Rows {
+			ref := st.Rows[i].AccountRef
+			if ref == "" {
+				continue
+			}
+			id, ok := matched[ref]
+			if !ok {
+				id = matchStatementAccount(userID, ref)
+				matched[ref] = id
+			}
+			st.Rows[i].Entry.AccountID = id
+		}
+		responseAccount = matched[st.AccountRef]
+	}
+
+	s.prepareImportRows(c, userID, accountID, st.Rows)
+	respondImport(c, userID, st.Format, filename, st.Rows, c.PostForm("commit") == "true", gin.H{
+		"format":      st.Format,
+		"account_ref": st.AccountRef,
+		"account_id":  responseAccount,
+		"currency":    st.Currency,
+	})
+}
+
+// matchStatementAccount finds the user's account whose Identifier ends in
+// the last four digits of a statement account reference.
+func matchStatementAccount(userID uint, ref string) *uint {
+	last4 := importer.Last4(ref)
+	if last4 == "" {
+		return nil
+	}
+	var account models.Account
+	if database.DB.Where("user_id = ? AND identifier LIKE ?", userID, "%"+last4).First(&account).Error != nil {
+		return nil
+	}
+	return &account.ID
+}
+
+// GET /v1/import/batches
+func (s *Server) listImportBatches(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var batches []models.ImportBatch
+	if err := database.DB.Where("user_id = ?", userID).Order("created_at desc").Find(&batches).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, batches)
+}
+
+// DELETE /v1/import/batches/:id
+// Undoes an import by deleting every entry it created.
+func (s *Server) undoImportBatch(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var batch models.ImportBatch
+	var removed int64
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND user_id = ?", id, userID).First(&batch).Error; err != nil {
+			return errNotFound("import batch not found")
+		}
+		if batch.Status == "undone" {
+			return errBadRequest("import batch already undone")
+		}
+		res := tx.Where("import_batch_id = ? AND user_id = ?", batch.ID, userID).Delete(&models.Entry{})
+		if res.Error != nil {
+			return res.Error
+		}
+		removed = res.RowsAffected
+		batch.Status = "undone"
+		return tx.Model(&batch).Update("status", batch.Status).Error
+	})
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{"batch": batch, "entries_removed": removed})
+}
@@ -0,0 +1,400 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"finance-parser-go/internal/ai"
+	"finance-parser-go/internal/database"
+	"finance-parser-go/internal/email"
+	"finance-parser-go/internal/importer"
+	"finance-parser-go/internal/models"
+	"finance-parser-go/internal/rules"
+	"finance-parser-go/internal/sms"
+)
+
+type IngestSkip struct {
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+}
+
+// POST /v1/ingest/sms
+// Accepts a batch of bank/UPI alert SMSes and stores each transaction as a
+// draft entry for the user to review. Messages that are not transactions,
+// or whose reference number was already ingested, are reported as skipped.
+func (s *Server) ingestSMS(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input struct {
+		Messages []struct {
+			Text       string    `json:"text"`
+			Sender     string    `json:"sender"`
+			ReceivedAt time.Time `json:"received_at"`
+		} `json:"messages" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc := s.userLocation(c)
+	directory := loadMerchants(userID)
+	ruleList := loadRules(userID)
+
+	var accounts []models.Account
+	database.DB.Where("user_id = ?", userID).Find(&accounts)
+
+	created := []models.Entry{}
+	skipped := []IngestSkip{}
+	seenRefs := map[string]bool{}
+
+	for i, m := range input.Messages {
+		received := m.ReceivedAt
+		if received.IsZero() {
+			received = time.Now()
+		}
+		msg, err := sms.Parse(m.Text, received.In(loc))
+		if err != nil {
+			skipped = append(skipped, IngestSkip{Index: i, Reason: err.Error()})
+			continue
+		}
+
+		entry := models.Entry{
+			UserID:     userID,
+			Type:       "expense",
+			Amount:     msg.Amount,
+			Currency:   msg.Currency,
+			Mode:       msg.Mode,
+			Merchant:   msg.Merchant,
+			Date:       msg.Date,
+			SourceText: m.Text,
+			Draft:      true,
+			Tags:       models.StringArray{},
+		}
+		if msg.Direction == "credit" {
+			entry.Type = "income"
+		}
+		if msg.Reference != "" {
+			entry.ExternalID = "sms:" + msg.Reference
+		}
+		if msg.VPA != "" {
+			entry.Notes = "VPA: " + msg.VPA
+		}
+		if msg.AccountLast4 != "" {
+			for _, a := range accounts {
+				if importer.Last4(a.Identifier) == msg.AccountLast4 || strings.HasSuffix(a.Identifier, msg.AccountLast4) {
+					id := a.ID
+					entry.AccountID = &id
+					break
+				}
+			}
+		}
+
+		normalizeMerchant(directory, &entry)
+		entry.Title = entry.Merchant
+		if entry.Title == "" {
+			entry.Title = strings.TrimSpace(msg.Mode + " " + msg.Direction)
+		}
+		rules.Apply(ruleList, &entry)
+		if err := setOccurredAt(&entry, loc); err != nil {
+			skipped = append(skipped, IngestSkip{Index: i, Reason: err.Error()})
+			continue
+		}
+
+		if entry.ExternalID != "" {
+			var count int64
+			database.DB.Model(&models.Entry{}).Where("user_id = ? AND external_id = ?", userID, entry.ExternalID).Count(&count)
+			if count > 0 || seenRefs[entry.ExternalID] {
+				skipped = append(skipped, IngestSkip{Index: i, Reason: "duplicate"})
+				continue
+			}
+			seenRefs[entry.ExternalID] = true
+		}
+
+		if err := database.DB.Create(&entry).Error; err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		created = append(created, entry)
+	}
+
+	c.JSON(201, gin.H{"created": created, "skipped": skipped})
+}
+
+// draftToEntry copies the fields of a parsed draft onto a new entry.
+func draftToEntry(draft map[string]any) models.Entry {
+	str := func(key string) string {
+		v, _ := draft[key].(string)
+		return strings.TrimSpace(v)
+	}
+	e := models.Entry{
+		Type:        str("type"),
+		Title:       str("title"),
+		Currency:    str("currency"),
+		Mode:        str("mode"),
+		CardNetwork: str("card_network"),
+		Category:    str("category"),
+		Merchant:    str("merchant"),
+		PurposeType: str("purpose_type"),
+		Tag:         str("tag"),
+		Notes:       str("notes"),
+		Date:        str("date"),
+		Time:        str("time"),
+		SourceText:  str("source_text"),
+		Tags:        models.StringArray{},
+	}
+	e.Amount, _ = draft["amount"].(float64)
+	switch id := draft["account_id"].(type) {
+	case float64:
+		accountID := uint(id)
+		e.AccountID = &accountID
+	case uint:
+		e.AccountID = &id
+	}
+	if tags, ok := draft["tags"].([]any); ok {
+		for _, t := range tags {
+			if s, ok := t.(string); ok {
+				e.Tags = append(e.Tags, s)
+			}
+		}
+	}
+	if e.Type == "" {
+		e.Type = "expense"
+	}
+	if e.Currency == "" {
+		e.Currency = "INR"
+	}
+	return e
+}
+
+// readRawEmail accepts either a multipart "file" field or the raw message
+// as the request body (Content-Type: message/rfc822).
+func (s *Server) readRawEmail(c *gin.Context) ([]byte, error) {
+	limit := s.cfg.MaxUploadMB * 1024 * 1024
+	if file, header, err := c.Request.FormFile("file"); err == nil {
+		defer file.Close()
+		if header.Size > limit {
+			return nil, &httpError{status: 413, msg: "file too large"}
+		}
+		return io.ReadAll(file)
+	}
+	raw, err := io.ReadAll(io.LimitReader(c.Request.Body, limit+1))
+	if err != nil {
+		return nil, errBadRequest("failed to read body")
+	}
+	if int64(len(raw)) > limit {
+		return nil, &httpError{status: 413, msg: "file too large"}
+	}
+	if len(raw) == 0 {
+		return nil, errBadRequest("no email provided")
+	}
+	return raw, nil
+}
+
+// Ingested emails hold order and payment details, so like reports they live
+// outside the public uploads directory, one folder per user, and are only
+// served through GET /v1/entries/:id/email.
+const emailsDir = "emails"
+
+// emailPath is where the source email of an ingested entry is stored.
+func emailPath(userID, entryID uint) string {
+	return filepath.Join(emailsDir, strconv.FormatUint(uint64(userID), 10), strconv.FormatUint(uint64(entryID), 10)+".eml")
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// POST /v1/ingest/email
+// Turns an order confirmation email into a draft entry. Known merchants
+// (Amazon, Swiggy, Zomato, Uber, Flipkart) are read with dedicated
+// extractors; anything else goes through the LLM parser and the same draft
+// clean-up and schema check as /v1/parse. The .eml is kept privately and
+// linked as the entry's attachment.
+func (s *Server) ingestEmail(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	raw, err := s.readRawEmail(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	msg, err := email.Parse(raw)
+	if err != nil {
+		c.JSON(422, gin.H{"error": err.Error()})
+		return
+	}
+
+	if msg.ID != "" {
+		var count int64
+		database.DB.Model(&models.Entry{}).Where("user_id = ? AND external_id = ?", userID, "email:"+msg.ID).Count(&count)
+		if count > 0 {
+			c.JSON(409, gin.H{"error": "email already ingested"})
+			return
+		}
+	}
+
+	pc := loadParseContext(userID, s.userLocation(c).String())
+	categories := pc.profile.Categories
+	receipt, known := email.Extract(msg)
+	var entry models.Entry
+	source := "extractor"
+
+	if known {
+		entry = models.Entry{
+			Type:     "expense",
+			Title:    receipt.Merchant,
+			Amount:   receipt.Total,
+			Currency: "INR",
+			Merchant: receipt.Merchant,
+			Category: matchCategory(receipt.Category, categories),
+			Date:     receipt.Date,
+			Tags:     models.StringArray{},
+		}
+	} else {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(s.cfg.ReqTimeoutSec)*time.Second)
+		defer cancel()
+
+		text := truncateUTF8(msg.Text, 4000)
+		transcript := fmt.Sprintf("Email receipt from %s, subject %q, sent %s:\n%s", msg.From, msg.Subject, receipt.Date, text)
+		trace := s.startTrace(ctx, userID, "email", transcript)
+		parsed, over, err := s.parseTranscript(ctx, c, pc, transcript, trace, nil)
+		if over != nil {
+			c.JSON(429, over)
+			return
+		}
+
+		var drafts []map[string]any
+		if err == nil {
+			drafts, err = ai.SplitDrafts(parsed)
+		}
+		if err != nil {
+			trace.fail(err)
+			s.saveTrace(trace, nil)
+		}
+		if err == nil {
+			// A receipt is one transaction; extra drafts are ignored.
+			drafts = drafts[:1]
+			s.finishDraft(pc, drafts[0])
+			s.saveTrace(trace, drafts)
+			if !s.validateDrafts(c, drafts, transcript) {
+				return
+			}
+			entry = draftToEntry(drafts[0])
+			if !accountBelongsToUser(database.DB, entry.AccountID, userID) {
+				// The account came from the model, not the user; drop it.
+				entry.AccountID = nil
+			}
+			entry.Category = matchCategory(entry.Category, categories)
+			if trace.log.ID != 0 {
+				entry.ParseLogID = &trace.log.ID
+			}
+			source = "llm"
+		} else if receipt.Total > 0 {
+			// The LLM is unavailable but a generic total was found.
+			entry = models.Entry{Type: "expense", Amount: receipt.Total, Currency: "INR", Date: receipt.Date, Merchant: msg.Domain, Tags: models.StringArray{}}
+		} else {
+			c.JSON(422, gin.H{"error": "could_not_parse", "subject": msg.Subject})
+			return
+		}
+		if entry.Date == "" {
+			entry.Date = receipt.Date
+		}
+	}
+
+	entry.UserID = userID
+	entry.Draft = true
+	entry.SourceText = msg.Subject
+	if msg.ID != "" {
+		entry.ExternalID = "email:" + msg.ID
+	}
+	if entry.Title == "" {
+		entry.Title = entry.Merchant
+	}
+	var notes []string
+	if entry.Notes != "" {
+		notes = append(notes, entry.Notes)
+	}
+	if receipt.OrderID != "" {
+		notes = append(notes, "Order "+receipt.OrderID)
+	}
+	for _, item := range receipt.Items {
+		notes = append(notes, fmt.Sprintf("%d x %s: %.2f", item.Quantity, item.Name, item.Amount))
+	}
+	entry.Notes = strings.Join(notes, "\n")
+
+	normalizeMerchant(pc.merchants, &entry)
+	applyRules(userID, &entry)
+	if err := setOccurredAt(&entry, s.userLocation(c)); err != nil {
+		// An unreadable receipt date falls back to today.
+		entry.Date = ""
+		if err := setOccurredAt(&entry, s.userLocation(c)); err != nil {
+			c.JSON(422, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// The file is named after the entry, so it is written once the entry
+	// exists; a failed write rolls the entry back.
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		path := emailPath(userID, entry.ID)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, raw, 0o600); err != nil {
+			return err
+		}
+		entry.Attachment = fmt.Sprintf("/v1/entries/%d/email", entry.ID)
+		return tx.Model(&entry).Update("attachment", entry.Attachment).Error
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to save email"})
+		return
+	}
+
+	c.JSON(201, gin.H{"entry": entry, "receipt": receipt, "source": source})
+}
+
+// GET /v1/entries/:id/email
+// Serves the email an entry was ingested from.
+func (s *Server) getEntryEmail(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var entry models.Entry
+	if err := database.DB.Select("id").Where("id = ? AND user_id = ?", id, userID).First(&entry).Error; err != nil {
+		c.JSON(404, gin.H{"error": "entry not found"})
+		return
+	}
+	path := emailPath(userID, entry.ID)
+	if _, err := os.Stat(path); err != nil {
+		c.JSON(404, gin.H{"error": "entry has no email"})
+		return
+	}
+	c.FileAttachment(path, fmt.Sprintf("entry_%d.eml", entry.ID))
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"finance-parser-go/internal/database"
+	"finance-parser-go/internal/merchants"
 	"finance-parser-go/internal/models"
 	"fmt"
 	"math"
@@ -89,18 +90,34 @@ type InsightsResponse struct {
 
 func (s *Server) getInsights(c *gin.Context) {
 	userId := c.MustGet("userID").(uint)
-	now := time.Now()
-	thisMonthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Format("2006-01-02")
-	lastMonthStart := now.AddDate(0, -1, 0)
-	lastMonthStartStr := time.Date(lastMonthStart.Year(), lastMonthStart.Month(), 1, 0, 0, 0, 0, now.Location()).Format("2006-01-02")
-	lastMonthEndStr := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, 0, -1).Format("2006-01-02")
+	now := time.Now().In(s.userLocation(c))
+	thisMonthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	res, _ := buildInsights(userId, thisMonthStart, now)
+	c.JSON(http.StatusOK, res)
+}
+
+// buildInsights aggregates the month starting at thisMonthStart against the
+// month before it. asOf is the day the month is seen from: today for the
+// current month, its last day for a closed one. The month's own entries are
+// returned in date order for callers that list them.
+func buildInsights(userId uint, thisMonthStart, asOf time.Time) (InsightsResponse, []models.Entry) {
+	now := asOf
+	lastMonthStart := thisMonthStart.AddDate(0, -1, 0)
+	nextMonthStart := thisMonthStart.AddDate(0, 1, 0)
+	inThisMonth := func(e models.Entry) bool {
+		return e.OccurredAt != nil && !e.OccurredAt.Before(thisMonthStart)
+	}
 
 	var entries []models.Entry
-	database.DB.Where("user_id = ? AND date >= ?", userId, lastMonthStartStr).Find(&entries)
+	database.DB.Where("user_id = ? AND occurred_at >= ? AND occurred_at < ? AND draft = ?", userId, lastMonthStart, nextMonthStart, false).
+		Order("occurred_at asc, id asc").Find(&entries)
 
 	var accounts []models.Account
 	database.DB.Where("user_id = ?", userId).Find(&accounts)
 
+	directory := loadMerchants(userId)
+
 	res := InsightsResponse{
 		CategoryBreakdown: []CategoryBreakdown{},
 		TopMerchants:      []MerchantInfo{},
@@ -120,23 +137,28 @@ func (s *Server) getInsights(c *gin.Context) {
 	dailySpend := make(map[string]float64)
 
 	for _, e := range entries {
-		if e.Date >= thisMonthStart {
+		if inThisMonth(e) {
 			if strings.ToLower(e.Type) == "income" {
 				thisMonthIncome += e.Amount
 			} else if strings.ToLower(e.Type) == "expense" {
 				thisMonthSpent += e.Amount
 				categorySpendThis[e.Category] += e.Amount
 				if e.Merchant != "" {
-					if _, ok := merchantSpend[e.Merchant]; !ok {
-						merchantSpend[e.Merchant] = &MerchantInfo{Merchant: e.Merchant}
+					// Group on the canonical name so older, un-normalised rows still line up.
+					name, icon := merchants.Tidy(e.Merchant), ""
+					if m := merchants.Resolve(directory, e.Merchant); m != nil {
+						name, icon = m.Name, m.Icon
 					}
-					merchantSpend[e.Merchant].Amount += e.Amount
-					merchantSpend[e.Merchant].TransactionCount++
+					if _, ok := merchantSpend[name]; !ok {
+						merchantSpend[name] = &MerchantInfo{Merchant: name, Icon: icon}
+					}
+					merchantSpend[name].Amount += e.Amount
+					merchantSpend[name].TransactionCount++
 				}
 				accountSpend[e.Mode] += e.Amount
-				dailySpend[e.Date] += e.Amount
+				dailySpend[e.OccurredAt.In(now.Location()).Format("2006-01-02")] += e.Amount
 			}
-		} else if e.Date >= lastMonthStartStr && e.Date <= lastMonthEndStr {
+		} else {
 			if strings.ToLower(e.Type) == "expense" {
 				lastMonthSpent += e.Amount
 				categorySpendLast[e.Category] += e.Amount
@@ -237,7 +259,7 @@ func (s *Server) getInsights(c *gin.Context) {
 		if strings.EqualFold(acc.Type, "credit") && acc.CreditLimit > 0 {
 			used := 0.0 // Needs careful logic to track specific card spend. For now using mode match.
 			for _, e := range entries {
-				if e.Date >= thisMonthStart && strings.EqualFold(e.Mode, acc.Name) {
+				if inThisMonth(e) && strings.EqualFold(e.Mode, acc.Name) {
 					used += e.Amount
 				}
 			}
@@ -258,7 +280,7 @@ func (s *Server) getInsights(c *gin.Context) {
 	var lentTotal float64
 	var lentCount int
 	for _, e := range entries {
-		if e.Date >= thisMonthStart {
+		if inThisMonth(e) {
 			if strings.Contains(strings.ToLower(e.Tag), "emi") {
 				emiTotal += e.Amount
 			}
@@ -295,7 +317,7 @@ func (s *Server) getInsights(c *gin.Context) {
 	// 8. Review Items
 	uncategorized := 0
 	for _, e := range entries {
-		if e.Date >= thisMonthStart && (e.Category == "" || strings.ToLower(e.Category) == "uncategorized" || strings.ToLower(e.Category) == "other") {
+		if inThisMonth(e) && (e.Category == "" || strings.ToLower(e.Category) == "uncategorized" || strings.ToLower(e.Category) == "other") {
 			uncategorized++
 		}
 	}
@@ -307,5 +329,11 @@ func (s *Server) getInsights(c *gin.Context) {
 		})
 	}
 
-	c.JSON(http.StatusOK, res)
+	monthEntries := []models.Entry{}
+	for _, e := range entries {
+		if inThisMonth(e) {
+			monthEntries = append(monthEntries, e)
+		}
+	}
+	return res, monthEntries
 }
@@ -0,0 +1,272 @@
+package http
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"finance-parser-go/internal/database"
+	"finance-parser-go/internal/merchants"
+	"finance-parser-go/internal/models"
+)
+
+// loadMerchants loads the user's merchant directory with its patterns
+// compiled, once per request.
+func loadMerchants(userID uint) *merchants.Directory {
+	var list []models.Merchant
+	if err := database.DB.Where("user_id = ?", userID).Find(&list).Error; err != nil {
+		return nil
+	}
+	return merchants.Compile(list)
+}
+
+// normalizeMerchant rewrites entry.Merchant to its canonical name and fills an
+// empty category from the merchant's default.
+func normalizeMerchant(directory *merchants.Directory, entry *models.Entry) {
+	if strings.TrimSpace(entry.Merchant) == "" {
+		return
+	}
+	m := merchants.Resolve(directory, entry.Merchant)
+	if m == nil {
+		entry.Merchant = merchants.Tidy(entry.Merchant)
+		return
+	}
+	entry.Merchant = m.Name
+	if entry.Category == "" && m.DefaultCategory != "" {
+		entry.Category = m.DefaultCategory
+	}
+}
+
+// normalizeDraftMerchant is normalizeMerchant for a parsed draft.
+func normalizeDraftMerchant(directory *merchants.Directory, draft map[string]any) bool {
+	raw, ok := draft["merchant"].(string)
+	if !ok || strings.TrimSpace(raw) == "" {
+		return false
+	}
+	category, _ := draft["category"].(string)
+	entry := models.Entry{Merchant: raw, Category: category}
+	normalizeMerchant(directory, &entry)
+
+	changed := false
+	if entry.Merchant != raw {
+		draft["merchant"] = entry.Merchant
+		changed = true
+	}
+	if entry.Category != category {
+		draft["category"] = entry.Category
+		changed = true
+	}
+	return changed
+}
+
+// GET /v1/merchants
+func (s *Server) listMerchants(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var directory []models.Merchant
+	if err := database.DB.Where("user_id = ?", userID).Order("name asc").Find(&directory).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"merchants": directory, "builtin": merchants.Builtin})
+}
+
+// POST /v1/merchants
+func (s *Server) saveMerchant(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var merchant models.Merchant
+	if err := c.BindJSON(&merchant); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	merchant.Name = strings.TrimSpace(merchant.Name)
+	if merchant.Name == "" {
+		c.JSON(400, gin.H{"error": "name is required"})
+		return
+	}
+	if err := merchants.Validate(merchant); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	merchant.ID = 0
+	merchant.UserID = userID
+	if err := database.DB.Create(&merchant).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(201, merchant)
+}
+
+// PUT /v1/merchants/:id
+func (s *Server) updateMerchant(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var merchant models.Merchant
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&merchant).Error; err != nil {
+		c.JSON(404, gin.H{"error": "merchant not found"})
+		return
+	}
+	oldName := merchant.Name
+
+	if err := c.BindJSON(&merchant); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	merchant.Name = strings.TrimSpace(merchant.Name)
+	if merchant.Name == "" {
+		c.JSON(400, gin.H{"error": "name is required"})
+		return
+	}
+	if err := merchants.Validate(merchant); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	merchant.ID = uint(id)
+	merchant.UserID = userID
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&merchant).Error; err != nil {
+			return err
+		}
+		if oldName != merchant.Name {
+			return tx.Model(&models.Entry{}).
+				Where("user_id = ? AND merchant = ?", userID, oldName).
+				Update("merchant", merchant.Name).Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, merchant)
+}
+
+// DELETE /v1/merchants/:id
+func (s *Server) deleteMerchant(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Merchant{}).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "merchant deleted"})
+}
+
+// POST /v1/merchants/merge
+// Folds source merchants (directory IDs and/or raw names seen on entries)
+// into the target: their names and aliases become target aliases, matching
+// entries are rewritten and the source directory rows are removed.
+func (s *Server) mergeMerchants(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input struct {
+		TargetID    uint     `json:"target_id" binding:"required"`
+		SourceIDs   []uint   `json:"source_ids"`
+		SourceNames []string `json:"source_names"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if len(input.SourceIDs) == 0 && len(input.SourceNames) == 0 {
+		c.JSON(400, gin.H{"error": "source_ids or source_names is required"})
+		return
+	}
+
+	var target models.Merchant
+	var updated int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND user_id = ?", input.TargetID, userID).First(&target).Error; err != nil {
+			return errNotFound("merchant not found")
+		}
+
+		names := append([]string{}, input.SourceNames...)
+		if len(input.SourceIDs) > 0 {
+			var sources []models.Merchant
+			if err := tx.Where("id IN ? AND user_id = ? AND id != ?", input.SourceIDs, userID, target.ID).Find(&sources).Error; err != nil {
+				return err
+			}
+			for _, src := range sources {
+				names = append(names, src.Name)
+				names = append(names, src.Aliases...)
+				target.Patterns = append(target.Patterns, src.Patterns...)
+				if target.DefaultCategory == "" {
+					target.DefaultCategory = src.DefaultCategory
+				}
+				if target.Icon == "" {
+					target.Icon = src.Icon
+				}
+			}
+			if err := tx.Where("id IN ? AND user_id = ? AND id != ?", input.SourceIDs, userID, target.ID).Delete(&models.Merchant{}).Error; err != nil {
+				return err
+			}
+		}
+
+		seen := map[string]bool{merchants.Key(target.Name): true}
+		for _, a := range target.Aliases {
+			seen[merchants.Key(a)] = true
+		}
+		for _, n := range names {
+			if k := merchants.Key(n); k != "" && !seen[k] {
+				seen[k] = true
+				target.Aliases = append(target.Aliases, n)
+			}
+		}
+		if err := tx.Save(&target).Error; err != nil {
+			return err
+		}
+
+		if len(names) == 0 {
+			return nil
+		}
+		// Entries store the raw string, so "SWIGGY*ORDER" or "swiggy " must
+		// match a source named "Swiggy": compare on the normalised key.
+		var raw []string
+		if err := tx.Model(&models.Entry{}).Where("user_id = ? AND merchant <> ''", userID).Distinct().Pluck("merchant", &raw).Error; err != nil {
+			return err
+		}
+		keys := map[string]bool{}
+		for _, n := range names {
+			keys[merchants.Key(n)] = true
+		}
+		var matched []string
+		for _, r := range raw {
+			if keys[merchants.Key(r)] {
+				matched = append(matched, r)
+			}
+		}
+		if len(matched) == 0 {
+			return nil
+		}
+		res := tx.Model(&models.Entry{}).
+			Where("user_id = ? AND merchant IN ?", userID, matched).
+			Update("merchant", target.Name)
+		updated = res.RowsAffected
+		return res.Error
+	})
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{"merchant": target, "entries_updated": updated})
+}
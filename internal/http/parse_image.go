@@ -0,0 +1,158 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"finance-parser-go/internal/ai"
+)
+
+// POST /v1/parse/image
+// Reads a receipt photo or payment screenshot (multipart "image") into
+// drafts. The image is kept under uploads and every draft carries its URL as
+// attachment, so saving the draft attaches the image to the entry.
+func (s *Server) parseImage(c *gin.Context) {
+	if s.imageParser == nil {
+		c.JSON(501, gin.H{"error": "image parsing is not configured"})
+		return
+	}
+
+	file, header, err := c.Request.FormFile("image")
+	if err != nil {
+		c.JSON(400, gin.H{"error": "no image provided"})
+		return
+	}
+	defer file.Close()
+	if header.Size > s.cfg.MaxUploadMB*1024*1024 {
+		c.JSON(413, gin.H{"error": "file too large"})
+		return
+	}
+	buf := &bytes.Buffer{}
+	if _, err := io.Copy(buf, file); err != nil {
+		c.JSON(400, gin.H{"error": "failed to read file"})
+		return
+	}
+	image := buf.Bytes()
+
+	version, ok := s.promptVersion(c)
+	if !ok {
+		return
+	}
+	userID := c.MustGet("userID").(uint)
+
+	// The fake backend reads plain-text "receipts" so the endpoint can be
+	// exercised without a vision model.
+	mimeType := http.DetectContentType(image)
+	isText := strings.HasPrefix(mimeType, "text/plain") && strings.EqualFold(s.cfg.VisionProvider, "fake")
+	if !strings.HasPrefix(mimeType, "image/") && !isText {
+		c.JSON(415, gin.H{"error": "unsupported image type", "type": mimeType})
+		return
+	}
+	// Booked only once the upload is one the model will be asked to read.
+	if over := s.quotaExceeded(c, userID, quotaParse); over != nil {
+		c.JSON(429, over)
+		return
+	}
+
+	if err := os.MkdirAll("uploads", 0o755); err != nil {
+		c.JSON(500, gin.H{"error": "failed to save file"})
+		return
+	}
+	path := fmt.Sprintf("uploads/%d_%s", time.Now().UnixNano(), filepath.Base(header.Filename))
+	if err := os.WriteFile(path, image, 0o644); err != nil {
+		c.JSON(500, gin.H{"error": "failed to save file"})
+		return
+	}
+	attachment := publicURL(c, path)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(s.cfg.ReqTimeoutSec)*time.Second)
+	defer cancel()
+	ctx = ai.WithPromptVersion(ctx, version)
+
+	tz := s.parseTZ(c)
+	pc := loadParseContext(userID, tz)
+	trace := s.startTrace(ctx, userID, "image", attachment)
+	mctx, done := s.metered(ctx, c, userID)
+	parsed, err := s.imageParser.ParseImage(mctx, image, mimeType, tz, pc.profile)
+	done()
+	trace.done(parsed, err)
+	if err != nil {
+		log.Printf("vision error: %v", err)
+		s.saveTrace(trace, nil)
+		os.Remove(path)
+		c.JSON(providerFailure(c, err))
+		return
+	}
+	drafts, err := ai.SplitDrafts(parsed)
+	if err != nil {
+		trace.fail(err)
+		s.saveTrace(trace, nil)
+		os.Remove(path)
+		c.JSON(422, gin.H{"error": "no_receipt_found"})
+		return
+	}
+
+	var described []string
+	for _, draft := range drafts {
+		draft["attachment"] = attachment
+		addLineItemNotes(draft)
+		s.finishDraft(pc, draft)
+		if text, _ := draft["source_text"].(string); text != "" {
+			described = append(described, text)
+		}
+	}
+	transcript := strings.Join(described, "; ")
+	s.saveTrace(trace, drafts)
+	if !s.validateDrafts(c, drafts, transcript) {
+		os.Remove(path)
+		return
+	}
+
+	sessionID, err := s.startParseSession(userID, transcript, tz, version, drafts)
+	if err != nil {
+		os.Remove(path)
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("X-Parse-Session", sessionID)
+	c.JSON(200, drafts)
+}
+
+// addLineItemNotes writes the line items and tax into notes, the way email
+// receipts are stored, since entries have no column for them.
+func addLineItemNotes(draft map[string]any) {
+	var notes []string
+	if n, _ := draft["notes"].(string); n != "" {
+		notes = append(notes, n)
+	}
+	items, _ := draft["line_items"].([]any)
+	for _, it := range items {
+		item, ok := it.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := item["name"].(string)
+		amount, _ := item["amount"].(float64)
+		qty, ok := item["quantity"].(float64)
+		if !ok || qty == 0 {
+			qty = 1
+		}
+		notes = append(notes, fmt.Sprintf("%g x %s: %.2f", qty, name, amount))
+	}
+	if tax, ok := draft["tax"].(float64); ok && tax > 0 {
+		notes = append(notes, fmt.Sprintf("Tax: %.2f", tax))
+	}
+	if len(notes) > 0 {
+		draft["notes"] = strings.Join(notes, "\n")
+	}
+}
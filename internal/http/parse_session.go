@@ -0,0 +1,187 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"finance-parser-go/internal/ai"
+	"finance-parser-go/internal/database"
+	"finance-parser-go/internal/models"
+	"finance-parser-go/internal/numwords"
+)
+
+// startParseSession stores the drafts of a /v1/parse call and returns the
+// session ID the client answers clarification questions against. Expired
+// sessions are cleared out here rather than by a background job.
+func (s *Server) startParseSession(userID uint, transcript, tz, promptVersion string, drafts []map[string]any) (string, error) {
+	now := time.Now()
+	database.DB.Where("expires_at < ?", now).Delete(&models.ParseSession{})
+
+	raw, err := json.Marshal(drafts)
+	if err != nil {
+		return "", err
+	}
+	session := models.ParseSession{
+		ID:            generateUUID(),
+		UserID:        userID,
+		Transcript:    transcript,
+		Timezone:      tz,
+		PromptVersion: promptVersion,
+		Drafts:        models.JSONDocument(raw),
+		ExpiresAt:     now.Add(s.parseSessionTTL()),
+	}
+	if err := database.DB.Create(&session).Error; err != nil {
+		return "", err
+	}
+	return session.ID, nil
+}
+
+func (s *Server) parseSessionTTL() time.Duration {
+	return time.Duration(s.cfg.ParseSessionTTLMin) * time.Minute
+}
+
+// POST /v1/parse/:session/answer
+// Feeds the user's answer back to the parser together with the prior
+// drafts. With an index only that draft is revised; otherwise every draft
+// still awaiting confirmation is. Returns the updated drafts.
+func (s *Server) answerParse(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input struct {
+		Answer string `json:"answer" binding:"required"`
+		Index  *int   `json:"index"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	var session models.ParseSession
+	err := database.DB.Where("id = ? AND user_id = ?", c.Param("session"), userID).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(404, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	if time.Now().After(session.ExpiresAt) {
+		c.JSON(410, gin.H{"error": "session_expired"})
+		return
+	}
+
+	var drafts []map[string]any
+	if err := json.Unmarshal(session.Drafts, &drafts); err != nil {
+		c.JSON(500, gin.H{"error": "invalid_session"})
+		return
+	}
+
+	var targets []int
+	if input.Index != nil {
+		if *input.Index < 0 || *input.Index >= len(drafts) {
+			c.JSON(400, gin.H{"error": "index out of range"})
+			return
+		}
+		targets = []int{*input.Index}
+	} else {
+		for i, draft := range drafts {
+			if ai.Pending(draft) {
+				targets = append(targets, i)
+			}
+		}
+	}
+	if len(targets) == 0 {
+		c.JSON(409, gin.H{"error": "nothing_to_confirm", "drafts": drafts})
+		return
+	}
+
+	if over := s.quotaExceeded(c, userID, quotaParse); over != nil {
+		c.JSON(429, over)
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(s.cfg.ReqTimeoutSec)*time.Second)
+	defer cancel()
+	ctx, done := s.metered(ctx, c, userID)
+	defer done()
+	// Answers go to the prompt the drafts came from, while it still exists.
+	version := session.PromptVersion
+	if !ai.KnownPromptVersion(version) {
+		version = s.cfg.PromptVersion
+	}
+	ctx = ai.WithPromptVersion(ctx, version)
+	c.Header("X-Prompt-Version", version)
+
+	pc := loadParseContext(userID, session.Timezone)
+	pc.norm = numwords.Normalize(session.Transcript)
+	answer := numwords.Normalize(input.Answer).Text
+	trace := s.startTrace(ctx, userID, "answer", session.Transcript+"\nAnswer: "+input.Answer)
+	ctx = trace.context(ctx)
+	questions := []string{}
+	var replies []json.RawMessage
+	for _, i := range targets {
+		questions = append(questions, clarifications(drafts[i])...)
+		updated, err := ai.Clarify(ctx, s.parser, pc.norm.Text, drafts[i], answer, session.Timezone, pc.profile)
+		if err != nil {
+			trace.done(nil, err)
+			s.saveTrace(trace, nil)
+			c.JSON(422, gin.H{"error": "could_not_parse", "transcript": session.Transcript})
+			return
+		}
+		reply, _ := json.Marshal(updated)
+		replies = append(replies, json.RawMessage(reply))
+		resetDraftAccount(drafts[i], updated)
+		s.finishDraft(pc, updated)
+		drafts[i] = updated
+	}
+	replied, _ := json.Marshal(gin.H{"entries": replies})
+	trace.done(replied, nil)
+	s.saveTrace(trace, drafts)
+	if !s.validateDrafts(c, drafts, session.Transcript) {
+		return
+	}
+
+	raw, err := json.Marshal(drafts)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "serialization_failed"})
+		return
+	}
+	session.Drafts = models.JSONDocument(raw)
+	session.History = append(session.History, models.ParseTurn{
+		Index:     input.Index,
+		Questions: questions,
+		Answer:    input.Answer,
+		At:        time.Now(),
+	})
+	session.ExpiresAt = time.Now().Add(s.parseSessionTTL())
+	if err := database.DB.Save(&session).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("X-Parse-Session", session.ID)
+	c.JSON(200, drafts)
+}
+
+// clarifications returns the questions a draft asked, whether it came
+// straight from the parser or back out of the session's jsonb column.
+func clarifications(draft map[string]any) []string {
+	switch qs := draft["clarifications"].(type) {
+	case []string:
+		return qs
+	case []any:
+		out := make([]string, 0, len(qs))
+		for _, q := range qs {
+			if s, ok := q.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
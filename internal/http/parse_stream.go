@@ -0,0 +1,143 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"finance-parser-go/internal/ai"
+)
+
+// POST /v1/parse/stream
+// Same form as /v1/parse, answered as Server-Sent Events so the client can
+// show progress:
+//
+//	transcript  {"transcript"}            once speech-to-text returns
+//	partial     {"index", "draft"}        each entry as the model writes it
+//	draft       {"index", "draft"}        each entry after server clean-up
+//	validation  {"valid", "details"}      schema check of the final drafts
+//	done        {"session"}               parse session for /v1/parse/:session/answer
+//	error       {"error", ...}            ends the stream; "quota_exceeded" past the daily quota
+//
+// REQUEST_TIMEOUT_SECONDS bounds the whole stream, not each step.
+func (s *Server) parseStream(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(s.cfg.ReqTimeoutSec)*time.Second)
+	defer cancel()
+
+	tz := s.parseTZ(c)
+	version, ok := s.promptVersion(c)
+	if !ok {
+		return
+	}
+	ctx = ai.WithPromptVersion(ctx, version)
+	language, ok := s.sttLanguage(c)
+	if !ok {
+		return
+	}
+	clip, ok := s.readParseAudio(ctx, c)
+	if !ok {
+		return
+	}
+	hint := c.PostForm("hint_text")
+	if clip == nil && hint == "" {
+		c.JSON(400, gin.H{"error": "no audio or hint_text provided"})
+		return
+	}
+	userID := c.MustGet("userID").(uint)
+	// Without a hint to fall back on, an exhausted quota is a 429 before
+	// the stream starts rather than an error event.
+	booked := clip != nil && hint == ""
+	if booked {
+		if over := s.quotaExceeded(c, userID, quotaTranscribe); over != nil {
+			c.JSON(429, over)
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // keep nginx from holding events back
+	c.Status(200)
+
+	send := func(event string, data any) {
+		c.SSEvent(event, data)
+		c.Writer.Flush()
+	}
+	fail := func(data gin.H) {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			data["error"] = "timeout"
+		}
+		send("error", data)
+	}
+
+	transcript, over, err := s.transcribeMetered(ctx, c, userID, clip, language, hint, booked)
+	if over != nil {
+		fail(over)
+		return
+	}
+	if err != nil {
+		_, body := providerFailure(c, err)
+		fail(body)
+		return
+	}
+	if transcript == "" {
+		fail(gin.H{"error": "no transcript"})
+		return
+	}
+	send("transcript", gin.H{"transcript": transcript})
+
+	pc := loadParseContext(userID, tz)
+	trace := s.startTrace(ctx, userID, "stream", transcript)
+	trace.audio(clip)
+	parsed, over, err := s.parseTranscript(ctx, c, pc, transcript, trace, func(i int, draft map[string]any) {
+		pc.restoreSource(draft)
+		send("partial", gin.H{"index": i, "draft": draft})
+	})
+	if over != nil {
+		fail(over)
+		return
+	}
+	if err != nil {
+		s.saveTrace(trace, nil)
+		_, body := providerFailure(c, err)
+		body["transcript"] = transcript
+		fail(body)
+		return
+	}
+	drafts, err := ai.SplitDrafts(parsed)
+	if err != nil {
+		trace.fail(err)
+		s.saveTrace(trace, nil)
+		fail(gin.H{"error": "invalid_parse_response"})
+		return
+	}
+
+	for _, draft := range drafts {
+		s.finishDraft(pc, draft)
+	}
+	s.saveTrace(trace, drafts)
+	for i, draft := range drafts {
+		send("draft", gin.H{"index": i, "draft": draft})
+	}
+
+	details, err := s.schemaErrors(drafts)
+	if err != nil {
+		fail(gin.H{"error": err.Error()})
+		return
+	}
+	send("validation", gin.H{"valid": len(details) == 0, "details": details})
+	if len(details) > 0 {
+		fail(gin.H{"error": "schema_invalid", "transcript": transcript})
+		return
+	}
+
+	sessionID, err := s.startParseSession(userID, transcript, tz, version, drafts)
+	if err != nil {
+		fail(gin.H{"error": err.Error()})
+		return
+	}
+	send("done", gin.H{"session": sessionID})
+}
@@ -0,0 +1,385 @@
+package http
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"finance-parser-go/internal/database"
+	"finance-parser-go/internal/models"
+)
+
+type PersonSummary struct {
+	models.Counterparty
+	OpenLoans int `json:"open_loans"`
+}
+
+type PeopleResponse struct {
+	People          []PersonSummary `json:"people"`
+	TotalReceivable float64         `json:"total_receivable"` // owed to the user
+	TotalPayable    float64         `json:"total_payable"`    // owed by the user
+	Net             float64         `json:"net"`
+}
+
+// loanSign maps a loan direction onto the counterparty balance: money lent
+// raises what they owe the user, money borrowed lowers it.
+func loanSign(direction string) float64 {
+	if direction == "borrowed" {
+		return -1
+	}
+	return 1
+}
+
+func entryBelongsToUser(tx *gorm.DB, entryID *uint, userID uint) bool {
+	if entryID == nil {
+		return true
+	}
+	var count int64
+	tx.Model(&models.Entry{}).Where("id = ? AND user_id = ?", *entryID, userID).Count(&count)
+	return count > 0
+}
+
+func accountBelongsToUser(tx *gorm.DB, accountID *uint, userID uint) bool {
+	if accountID == nil {
+		return true
+	}
+	var count int64
+	tx.Model(&models.Account{}).Where("id = ? AND user_id = ?", *accountID, userID).Count(&count)
+	return count > 0
+}
+
+// GET /v1/people
+func (s *Server) listPeople(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var people []models.Counterparty
+	if err := database.DB.Where("user_id = ?", userID).Order("name asc").Find(&people).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	var openCounts []struct {
+		CounterpartyID uint
+		Count          int
+	}
+	database.DB.Model(&models.Loan{}).
+		Select("counterparty_id, COUNT(*) as count").
+		Where("user_id = ? AND status = ?", userID, "open").
+		Group("counterparty_id").
+		Scan(&openCounts)
+	open := make(map[uint]int)
+	for _, oc := range openCounts {
+		open[oc.CounterpartyID] = oc.Count
+	}
+
+	res := PeopleResponse{People: []PersonSummary{}}
+	for _, p := range people {
+		res.People = append(res.People, PersonSummary{Counterparty: p, OpenLoans: open[p.ID]})
+		if p.Balance > 0 {
+			res.TotalReceivable += p.Balance
+		} else {
+			res.TotalPayable += -p.Balance
+		}
+	}
+	res.Net = res.TotalReceivable - res.TotalPayable
+
+	c.JSON(200, res)
+}
+
+// POST /v1/people
+func (s *Server) savePerson(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var person models.Counterparty
+	if err := c.BindJSON(&person); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(person.Name) == "" {
+		c.JSON(400, gin.H{"error": "name is required"})
+		return
+	}
+
+	person.ID = 0
+	person.UserID = userID
+	person.Balance = 0 // derived from loans only
+	if err := database.DB.Create(&person).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(201, person)
+}
+
+// GET /v1/people/:id
+func (s *Server) getPerson(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var person models.Counterparty
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&person).Error; err != nil {
+		c.JSON(404, gin.H{"error": "person not found"})
+		return
+	}
+
+	var loans []models.Loan
+	if err := database.DB.Preload("Repayments").
+		Where("counterparty_id = ? AND user_id = ?", person.ID, userID).
+		Order("date desc, created_at desc").
+		Find(&loans).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"person": person, "loans": loans})
+}
+
+// PUT /v1/people/:id
+func (s *Server) updatePerson(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var person models.Counterparty
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&person).Error; err != nil {
+		c.JSON(404, gin.H{"error": "person not found"})
+		return
+	}
+
+	balance := person.Balance
+	if err := c.BindJSON(&person); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	person.ID = uint(id)
+	person.UserID = userID
+	person.Balance = balance
+	if err := database.DB.Save(&person).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, person)
+}
+
+// DELETE /v1/people/:id
+func (s *Server) deletePerson(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var loanIDs []uint
+		if err := tx.Model(&models.Loan{}).Where("counterparty_id = ? AND user_id = ?", id, userID).Pluck("id", &loanIDs).Error; err != nil {
+			return err
+		}
+		if len(loanIDs) > 0 {
+			if err := tx.Where("loan_id IN ?", loanIDs).Delete(&models.LoanRepayment{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ?", loanIDs).Delete(&models.Loan{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Counterparty{}).Error
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "person deleted"})
+}
+
+// POST /v1/loans
+// Records money lent to or borrowed from a counterparty. Either counterparty_id
+// or counterparty_name must be given; an unknown name creates the counterparty.
+func (s *Server) saveLoan(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input struct {
+		CounterpartyID   uint    `json:"counterparty_id"`
+		CounterpartyName string  `json:"counterparty_name"`
+		EntryID          *uint   `json:"entry_id"`
+		Direction        string  `json:"direction"`
+		Amount           float64 `json:"amount"`
+		Date             string  `json:"date"`
+		Notes            string  `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	input.Direction = strings.ToLower(strings.TrimSpace(input.Direction))
+	if input.Direction != "lent" && input.Direction != "borrowed" {
+		c.JSON(400, gin.H{"error": "direction must be lent or borrowed"})
+		return
+	}
+	if input.Amount <= 0 {
+		c.JSON(400, gin.H{"error": "amount must be positive"})
+		return
+	}
+
+	var loan models.Loan
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var person models.Counterparty
+		switch {
+		case input.CounterpartyID != 0:
+			if err := tx.Where("id = ? AND user_id = ?", input.CounterpartyID, userID).First(&person).Error; err != nil {
+				return errNotFound("person not found")
+			}
+		case strings.TrimSpace(input.CounterpartyName) != "":
+			name := strings.TrimSpace(input.CounterpartyName)
+			err := tx.Where("user_id = ? AND LOWER(name) = LOWER(?)", userID, name).First(&person).Error
+			if err == gorm.ErrRecordNotFound {
+				person = models.Counterparty{UserID: userID, Name: name}
+				if err := tx.Create(&person).Error; err != nil {
+					return err
+				}
+			} else if err != nil {
+				return err
+			}
+		default:
+			return errBadRequest("counterparty_id or counterparty_name is required")
+		}
+
+		if !entryBelongsToUser(tx, input.EntryID, userID) {
+			return errNotFound("entry not found")
+		}
+
+		loan = models.Loan{
+			UserID:         userID,
+			CounterpartyID: person.ID,
+			EntryID:        input.EntryID,
+			Direction:      input.Direction,
+			Amount:         input.Amount,
+			Status:         "open",
+			Date:           input.Date,
+			Notes:          input.Notes,
+		}
+		if err := tx.Create(&loan).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&person).
+			Update("balance", gorm.Expr("balance + ?", loanSign(loan.Direction)*loan.Amount)).Error
+	})
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(201, loan)
+}
+
+// GET /v1/loans
+func (s *Server) listLoans(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	query := database.DB.Preload("Repayments").Where("user_id = ?", userID).Order("date desc, created_at desc")
+	if cp := c.Query("counterparty_id"); cp != "" {
+		query = query.Where("counterparty_id = ?", cp)
+	}
+	if status := strings.TrimSpace(c.Query("status")); status != "" {
+		query = query.Where("status = ?", strings.ToLower(status))
+	}
+	if dir := strings.TrimSpace(c.Query("direction")); dir != "" {
+		query = query.Where("direction = ?", strings.ToLower(dir))
+	}
+
+	var loans []models.Loan
+	if err := query.Find(&loans).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, loans)
+}
+
+// POST /v1/loans/:id/repayments
+// Records a full or partial repayment and moves the counterparty balance back
+// toward zero. Repayments larger than the outstanding amount are rejected.
+func (s *Server) addRepayment(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var input struct {
+		EntryID *uint   `json:"entry_id"`
+		Amount  float64 `json:"amount"`
+		Date    string  `json:"date"`
+		Notes   string  `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Amount <= 0 {
+		c.JSON(400, gin.H{"error": "amount must be positive"})
+		return
+	}
+
+	var loan models.Loan
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// The row lock serialises concurrent repayments of the same loan.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND user_id = ?", id, userID).First(&loan).Error; err != nil {
+			return errNotFound("loan not found")
+		}
+		// Allow for float rounding on the final settling payment.
+		if input.Amount > loan.Outstanding()+0.005 {
+			return errBadRequest("repayment exceeds outstanding amount")
+		}
+		if !entryBelongsToUser(tx, input.EntryID, userID) {
+			return errNotFound("entry not found")
+		}
+
+		repayment := models.LoanRepayment{
+			LoanID:  loan.ID,
+			EntryID: input.EntryID,
+			Amount:  input.Amount,
+			Date:    input.Date,
+			Notes:   input.Notes,
+		}
+		if err := tx.Create(&repayment).Error; err != nil {
+			return err
+		}
+
+		loan.Repaid += input.Amount
+		if math.Abs(loan.Outstanding()) < 0.005 {
+			loan.Status = "settled"
+		}
+		if err := tx.Model(&loan).Updates(map[string]any{"repaid": loan.Repaid, "status": loan.Status}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Counterparty{}).
+			Where("id = ?", loan.CounterpartyID).
+			Update("balance", gorm.Expr("balance - ?", loanSign(loan.Direction)*input.Amount)).Error
+	})
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	database.DB.Preload("Repayments").First(&loan, loan.ID)
+	c.JSON(201, loan)
+}
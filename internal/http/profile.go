@@ -0,0 +1,214 @@
+package http
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"finance-parser-go/internal/accounts"
+	"finance-parser-go/internal/ai"
+	"finance-parser-go/internal/database"
+	"finance-parser-go/internal/merchants"
+	"finance-parser-go/internal/models"
+	"finance-parser-go/internal/numwords"
+)
+
+const (
+	profileLookbackDays = 90
+	profileMerchants    = 15
+)
+
+// parseContext is what the parse handlers load once per request: the
+// profile sent to the parser and the directories used to post-process its
+// drafts.
+type parseContext struct {
+	userID    uint
+	tz        string
+	profile   ai.Profile
+	merchants *merchants.Directory
+	accounts  []models.Account
+	// norm is the transcript as sent to the parser, with spoken amounts
+	// rewritten as digits; nil until one has been parsed.
+	norm *numwords.Text
+}
+
+// restoreSource puts the user's own words back into a draft's source_text
+// after the transcript was normalised.
+func (pc *parseContext) restoreSource(draft map[string]any) {
+	if pc.norm == nil {
+		return
+	}
+	if text, ok := draft["source_text"].(string); ok {
+		draft["source_text"] = pc.norm.Source(text)
+	}
+}
+
+func loadParseContext(userID uint, tz string) *parseContext {
+	pc := &parseContext{userID: userID, tz: tz, merchants: loadMerchants(userID)}
+	database.DB.Where("user_id = ?", userID).Order("id asc").Find(&pc.accounts)
+
+	pc.profile = ai.Profile{
+		Categories:  userCategoryNames(userID),
+		Merchants:   frequentMerchants(userID, pc.merchants),
+		DefaultMode: defaultMode(userID, pc.accounts),
+	}
+	for _, a := range pc.accounts {
+		pc.profile.Accounts = append(pc.profile.Accounts, ai.ProfileAccount{
+			Name:     a.Name,
+			Type:     a.Type,
+			Provider: a.Provider,
+			Last4:    accounts.LastFour(a.Identifier),
+		})
+	}
+	return pc
+}
+
+// frequentMerchants lists the merchants the user paid most often recently,
+// each with the category they filed it under most often.
+func frequentMerchants(userID uint, directory *merchants.Directory) []ai.ProfileMerchant {
+	var rows []struct {
+		Merchant string
+		Category string
+		N        int
+	}
+	since := time.Now().AddDate(0, 0, -profileLookbackDays)
+	database.DB.Model(&models.Entry{}).
+		Select("merchant, category, count(*) as n").
+		Where("user_id = ? AND type = ? AND merchant <> '' AND occurred_at >= ?", userID, "expense", since).
+		Group("merchant, category").
+		Order("n desc").
+		Scan(&rows)
+
+	// Rows come per (merchant, category), most used first. Rank merchants
+	// on their total across categories; the first row seen for a merchant
+	// carries its name as most often written and its top category.
+	type tally struct {
+		ai.ProfileMerchant
+		n int
+	}
+	var ranked []*tally
+	byKey := map[string]*tally{}
+	for _, r := range rows {
+		key := merchants.Key(r.Merchant)
+		if t, ok := byKey[key]; ok {
+			t.n += r.N
+			continue
+		}
+		category := r.Category
+		if category == "" {
+			if m := merchants.Resolve(directory, r.Merchant); m != nil {
+				category = m.DefaultCategory
+			}
+		}
+		t := &tally{ProfileMerchant: ai.ProfileMerchant{Name: r.Merchant, Category: category}, n: r.N}
+		byKey[key] = t
+		ranked = append(ranked, t)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].n > ranked[j].n })
+
+	var out []ai.ProfileMerchant
+	for _, t := range ranked {
+		out = append(out, t.ProfileMerchant)
+		if len(out) == profileMerchants {
+			break
+		}
+	}
+	return out
+}
+
+// defaultMode is the payment mode the user used most over the lookback
+// window, or the one implied by their default account.
+func defaultMode(userID uint, list []models.Account) string {
+	var row struct {
+		Mode string
+		N    int
+	}
+	since := time.Now().AddDate(0, 0, -profileLookbackDays)
+	database.DB.Model(&models.Entry{}).
+		Select("mode, count(*) as n").
+		Where("user_id = ? AND type = ? AND mode <> '' AND occurred_at >= ?", userID, "expense", since).
+		Group("mode").
+		Order("n desc").
+		Limit(1).
+		Scan(&row)
+	if row.Mode != "" {
+		return row.Mode
+	}
+
+	for _, a := range list {
+		if !a.IsDefault {
+			continue
+		}
+		switch strings.ToLower(a.Type) {
+		case "credit":
+			return "Credit Card"
+		case "wallet":
+			return "Wallets"
+		case "upi", "bank", "debit":
+			return "UPI"
+		}
+	}
+	return ""
+}
+
+// applyDefaultMode fills a missing mode from the profile and asks the user
+// to confirm it.
+func applyDefaultMode(profile ai.Profile, draft map[string]any) bool {
+	if mode, _ := draft["mode"].(string); mode != "" || profile.DefaultMode == "" {
+		return false
+	}
+	draft["mode"] = profile.DefaultMode
+	needs, _ := draft["needs_confirmation"].(map[string]any)
+	if needs == nil {
+		needs = map[string]any{}
+	}
+	needs["mode"] = true
+	draft["needs_confirmation"] = needs
+	return true
+}
+
+// resolveDraftAccount sets account_id from account_hint (or the mode, when
+// there is no hint) and records how sure the match is in confidence. An
+// account the server resolved earlier (one with confidence.account_id) is
+// resolved again, since the hint or mode may have changed since; one the
+// user chose is kept.
+func resolveDraftAccount(list []models.Account, draft map[string]any) bool {
+	conf, _ := draft["confidence"].(map[string]any)
+	if _, resolved := conf["account_id"]; draft["account_id"] != nil && !resolved {
+		return false
+	}
+	hint, _ := draft["account_hint"].(string)
+	mode, _ := draft["mode"].(string)
+	account, confidence := accounts.Resolve(list, hint, mode)
+	if account == nil {
+		delete(draft, "account_id")
+		delete(conf, "account_id")
+		return false
+	}
+	draft["account_id"] = account.ID
+	if conf == nil {
+		conf = map[string]any{}
+	}
+	conf["account_id"] = confidence
+	draft["confidence"] = conf
+	return true
+}
+
+// resetDraftAccount prepares a draft revised from prev for
+// resolveDraftAccount: the parser echoes prev's account_id but not always
+// its confidence, so an account the server picked for prev, or any account
+// once the mode or account hint changed, is cleared to be resolved afresh.
+func resetDraftAccount(prev, revised map[string]any) {
+	prevConf, _ := prev["confidence"].(map[string]any)
+	_, resolved := prevConf["account_id"]
+	str := func(d map[string]any, key string) string {
+		v, _ := d[key].(string)
+		return v
+	}
+	if resolved || str(prev, "mode") != str(revised, "mode") || str(prev, "account_hint") != str(revised, "account_hint") {
+		delete(revised, "account_id")
+		if conf, ok := revised["confidence"].(map[string]any); ok {
+			delete(conf, "account_id")
+		}
+	}
+}
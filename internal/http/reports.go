@@ -0,0 +1,252 @@
+package http
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"finance-parser-go/internal/database"
+	"finance-parser-go/internal/models"
+	"finance-parser-go/internal/report"
+)
+
+// Reports hold financial data, so they live outside the public uploads
+// directory and are only served through the authenticated download route.
+const reportsDir = "reports"
+
+// POST /v1/reports
+// Renders the statement for one month (YYYY-MM, default last month) as html
+// or pdf and stores it for later download.
+func (s *Server) createReport(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input struct {
+		Month  string `json:"month"`
+		Format string `json:"format"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc := s.userLocation(c)
+	now := time.Now().In(loc)
+	thisMonthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+
+	monthStart := thisMonthStart.AddDate(0, -1, 0)
+	if input.Month != "" {
+		t, err := time.ParseInLocation("2006-01", input.Month, loc)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid month, expected YYYY-MM"})
+			return
+		}
+		monthStart = t
+	}
+	if monthStart.After(thisMonthStart) {
+		c.JSON(400, gin.H{"error": "month is in the future"})
+		return
+	}
+
+	format := strings.ToLower(input.Format)
+	if format == "" {
+		format = "pdf"
+	}
+	if format != "pdf" && format != "html" {
+		c.JSON(400, gin.H{"error": "format must be pdf or html"})
+		return
+	}
+
+	// A closed month is seen from its last day; the current one from today.
+	asOf := monthStart.AddDate(0, 1, -1)
+	if monthStart.Equal(thisMonthStart) {
+		asOf = now
+	}
+
+	data := buildReportData(userID, monthStart, asOf)
+	data.GeneratedAt = now
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err == nil {
+		data.UserName = user.Username
+	}
+
+	var content []byte
+	var err error
+	if format == "pdf" {
+		content, err = report.PDF(data)
+	} else {
+		content, err = report.HTML(data)
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to render report"})
+		return
+	}
+
+	dir := filepath.Join(reportsDir, strconv.FormatUint(uint64(userID), 10))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		c.JSON(500, gin.H{"error": "failed to save report"})
+		return
+	}
+	month := monthStart.Format("2006-01")
+	filename := fmt.Sprintf("statement_%s.%s", month, format)
+	path := filepath.Join(dir, fmt.Sprintf("%d_%s", now.UnixNano(), filename))
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		c.JSON(500, gin.H{"error": "failed to save report"})
+		return
+	}
+
+	rep := models.Report{
+		UserID:   userID,
+		Month:    month,
+		Format:   format,
+		Filename: filename,
+		Path:     path,
+		Size:     int64(len(content)),
+	}
+	if err := database.DB.Create(&rep).Error; err != nil {
+		os.Remove(path)
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(201, rep)
+}
+
+// buildReportData reuses the insights aggregation for the month and adds
+// the per-account summary and the full transaction list.
+func buildReportData(userID uint, monthStart, asOf time.Time) *report.Data {
+	insights, entries := buildInsights(userID, monthStart, asOf)
+
+	data := &report.Data{
+		Month:        monthStart,
+		Income:       insights.MonthlyHealth.Income,
+		Spent:        insights.MonthlyHealth.Spent,
+		Savings:      insights.MonthlyHealth.Savings,
+		SavingsRate:  insights.MonthlyHealth.SavingsRate,
+		Categories:   []report.Line{},
+		Merchants:    []report.Line{},
+		Accounts:     []report.AccountSummary{},
+		Transactions: []report.Transaction{},
+	}
+	for _, cat := range insights.CategoryBreakdown {
+		label := cat.Category
+		if label == "" {
+			label = "Uncategorized"
+		}
+		data.Categories = append(data.Categories, report.Line{Label: label, Amount: cat.Amount, Percent: cat.Percentage})
+	}
+	for _, m := range insights.TopMerchants {
+		data.Merchants = append(data.Merchants, report.Line{Label: m.Merchant, Amount: m.Amount, Count: m.TransactionCount})
+	}
+
+	var accounts []models.Account
+	database.DB.Where("user_id = ?", userID).Find(&accounts)
+	accountNames := map[uint]models.Account{}
+	for _, a := range accounts {
+		accountNames[a.ID] = a
+	}
+
+	summaries := map[string]*report.AccountSummary{}
+	for _, e := range entries {
+		name, kind := "Unassigned", ""
+		if e.AccountID != nil {
+			if a, ok := accountNames[*e.AccountID]; ok {
+				name, kind = a.Name, a.Type
+			}
+		} else if e.Mode != "" {
+			name = e.Mode
+		}
+		sum, ok := summaries[name]
+		if !ok {
+			sum = &report.AccountSummary{Name: name, Type: kind}
+			summaries[name] = sum
+		}
+		sum.Count++
+		switch strings.ToLower(e.Type) {
+		case "income":
+			sum.Income += e.Amount
+		case "expense":
+			sum.Spent += e.Amount
+		}
+
+		data.Transactions = append(data.Transactions, report.Transaction{
+			Date:     e.OccurredAt.In(monthStart.Location()).Format("2006-01-02"),
+			Title:    e.Title,
+			Category: e.Category,
+			Merchant: e.Merchant,
+			Account:  name,
+			Type:     e.Type,
+			Amount:   e.Amount,
+		})
+	}
+	for _, sum := range summaries {
+		data.Accounts = append(data.Accounts, *sum)
+	}
+	sort.Slice(data.Accounts, func(i, j int) bool {
+		return data.Accounts[i].Spent+data.Accounts[i].Income > data.Accounts[j].Spent+data.Accounts[j].Income
+	})
+
+	return data
+}
+
+// GET /v1/reports
+func (s *Server) listReports(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var reports []models.Report
+	if err := database.DB.Where("user_id = ?", userID).Order("created_at desc").Find(&reports).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, reports)
+}
+
+func findReport(c *gin.Context) (*models.Report, bool) {
+	userID := c.MustGet("userID").(uint)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+
+	var rep models.Report
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&rep).Error; err != nil {
+		c.JSON(404, gin.H{"error": "report not found"})
+		return nil, false
+	}
+	return &rep, true
+}
+
+// GET /v1/reports/:id/download
+func (s *Server) downloadReport(c *gin.Context) {
+	rep, ok := findReport(c)
+	if !ok {
+		return
+	}
+	if _, err := os.Stat(rep.Path); err != nil {
+		c.JSON(410, gin.H{"error": "report file is no longer available"})
+		return
+	}
+	c.FileAttachment(rep.Path, rep.Filename)
+}
+
+// DELETE /v1/reports/:id
+func (s *Server) deleteReport(c *gin.Context) {
+	rep, ok := findReport(c)
+	if !ok {
+		return
+	}
+	if err := database.DB.Delete(rep).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	os.Remove(rep.Path)
+
+	c.JSON(200, gin.H{"message": "report deleted"})
+}
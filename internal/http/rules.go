@@ -0,0 +1,261 @@
+package http
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"finance-parser-go/internal/database"
+	"finance-parser-go/internal/models"
+	"finance-parser-go/internal/rules"
+)
+
+type RuleDiff struct {
+	EntryID uint                    `json:"entry_id"`
+	Title   string                  `json:"title"`
+	Date    string                  `json:"date"`
+	Rules   []uint                  `json:"rules"`
+	Changes map[string]rules.Change `json:"changes"`
+}
+
+func loadRules(userID uint) []models.Rule {
+	var list []models.Rule
+	if err := database.DB.Where("user_id = ? AND enabled = ?", userID, true).Find(&list).Error; err != nil {
+		return nil
+	}
+	return list
+}
+
+// applyRules runs the user's rules against an entry before it is stored.
+func applyRules(userID uint, entry *models.Entry) {
+	rules.Apply(loadRules(userID), entry)
+}
+
+// applyRulesToDraft runs the user's rules against a parsed draft, writing back
+// only the fields the rules changed. It reports whether the draft changed.
+func applyRulesToDraft(userID uint, draft map[string]any) bool {
+	list := loadRules(userID)
+	if len(list) == 0 {
+		return false
+	}
+
+	str := func(key string) string {
+		v, _ := draft[key].(string)
+		return v
+	}
+	entry := models.Entry{
+		Title:       str("title"),
+		Merchant:    str("merchant"),
+		Mode:        str("mode"),
+		Category:    str("category"),
+		PurposeType: str("purpose_type"),
+	}
+	entry.Amount, _ = draft["amount"].(float64)
+	switch id := draft["account_id"].(type) {
+	case float64:
+		accountID := uint(id)
+		entry.AccountID = &accountID
+	case uint:
+		entry.AccountID = &id
+	}
+	if tags, ok := draft["tags"].([]any); ok {
+		for _, t := range tags {
+			if s, ok := t.(string); ok {
+				entry.Tags = append(entry.Tags, s)
+			}
+		}
+	}
+
+	before := entry
+	before.Tags = append(models.StringArray(nil), entry.Tags...)
+	if len(rules.Apply(list, &entry)) == 0 {
+		return false
+	}
+
+	changes := rules.Diff(&before, &entry)
+	for field := range changes {
+		switch field {
+		case "category":
+			draft["category"] = entry.Category
+		case "merchant":
+			draft["merchant"] = entry.Merchant
+		case "purpose_type":
+			draft["purpose_type"] = entry.PurposeType
+		case "account_id":
+			draft["account_id"] = *entry.AccountID
+		case "tags":
+			draft["tags"] = []string(entry.Tags)
+		}
+	}
+	return len(changes) > 0
+}
+
+// ruleAccountsOwned reports whether the accounts a rule matches on and
+// moves entries to belong to the user.
+func ruleAccountsOwned(rule models.Rule, userID uint) bool {
+	return accountBelongsToUser(database.DB, rule.AccountID, userID) && accountBelongsToUser(database.DB, rule.SetAccountID, userID)
+}
+
+// GET /v1/rules
+func (s *Server) listRules(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var list []models.Rule
+	if err := database.DB.Where("user_id = ?", userID).Order("priority asc, id asc").Find(&list).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, list)
+}
+
+// POST /v1/rules
+func (s *Server) saveRule(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	rule := models.Rule{Enabled: true}
+	if err := c.BindJSON(&rule); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule.ID = 0
+	rule.UserID = userID
+	if !ruleAccountsOwned(rule, userID) {
+		c.JSON(400, gin.H{"error": "account not found"})
+		return
+	}
+	if err := database.DB.Select("*").Create(&rule).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(201, rule)
+}
+
+// PUT /v1/rules/:id
+func (s *Server) updateRule(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var rule models.Rule
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&rule).Error; err != nil {
+		c.JSON(404, gin.H{"error": "rule not found"})
+		return
+	}
+
+	if err := c.BindJSON(&rule); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule.ID = uint(id)
+	rule.UserID = userID
+	if !ruleAccountsOwned(rule, userID) {
+		c.JSON(400, gin.H{"error": "account not found"})
+		return
+	}
+	if err := database.DB.Save(&rule).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, rule)
+}
+
+// DELETE /v1/rules/:id
+func (s *Server) deleteRule(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Rule{}).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "rule deleted"})
+}
+
+// POST /v1/rules/apply
+// Runs the rules over existing entries. Defaults to a dry run that only
+// returns the diff; send {"dry_run": false} to write the changes.
+func (s *Server) applyRulesRetroactively(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input struct {
+		DryRun  *bool  `json:"dry_run"`
+		RuleIDs []uint `json:"rule_ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	dryRun := input.DryRun == nil || *input.DryRun
+
+	query := database.DB.Where("user_id = ? AND enabled = ?", userID, true)
+	if len(input.RuleIDs) > 0 {
+		query = query.Where("id IN ?", input.RuleIDs)
+	}
+	var list []models.Rule
+	if err := query.Find(&list).Error; err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Either every matching entry is rewritten or none is.
+	diffs := []RuleDiff{}
+	var batch []models.Entry
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return tx.Where("user_id = ?", userID).Order("id asc").FindInBatches(&batch, 200, func(_ *gorm.DB, _ int) error {
+			return rewriteBatch(tx, list, batch, dryRun, &diffs)
+		}).Error
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"dry_run": dryRun, "count": len(diffs), "changes": diffs})
+}
+
+// rewriteBatch applies the rules to one batch of entries, collecting the
+// diffs and, unless dryRun, writing the changes with tx.
+func rewriteBatch(tx *gorm.DB, list []models.Rule, batch []models.Entry, dryRun bool, diffs *[]RuleDiff) error {
+	for i := range batch {
+		e := &batch[i]
+		before := *e
+		before.Tags = append(models.StringArray(nil), e.Tags...)
+		applied := rules.Apply(list, e)
+		if len(applied) == 0 {
+			continue
+		}
+		changes := rules.Diff(&before, e)
+		if len(changes) == 0 {
+			continue
+		}
+		*diffs = append(*diffs, RuleDiff{EntryID: e.ID, Title: e.Title, Date: e.Date, Rules: applied, Changes: changes})
+		if dryRun {
+			continue
+		}
+		err := tx.Model(&models.Entry{}).Where("id = ?", e.ID).Updates(map[string]any{
+			"category":     e.Category,
+			"merchant":     e.Merchant,
+			"purpose_type": e.PurposeType,
+			"account_id":   e.AccountID,
+			"tags":         e.Tags,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
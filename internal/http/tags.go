@@ -0,0 +1,156 @@
+package http
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"finance-parser-go/internal/database"
+	"finance-parser-go/internal/models"
+)
+
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// rewriteTags replaces every tag in sources with target on the user's entries,
+// dropping duplicates, and returns the number of entries changed.
+func rewriteTags(tx *gorm.DB, userID uint, sources []string, target string) (int, error) {
+	query := tx.Where("user_id = ?", userID)
+	cond := tx
+	for i, src := range sources {
+		filter, err := json.Marshal([]string{src})
+		if err != nil {
+			return 0, err
+		}
+		if i == 0 {
+			cond = cond.Where("tags @> ?", string(filter))
+		} else {
+			cond = cond.Or("tags @> ?", string(filter))
+		}
+	}
+
+	var entries []models.Entry
+	if err := query.Where(cond).Find(&entries).Error; err != nil {
+		return 0, err
+	}
+
+	replace := make(map[string]bool, len(sources))
+	for _, src := range sources {
+		replace[src] = true
+	}
+
+	for _, e := range entries {
+		seen := make(map[string]bool)
+		tags := models.StringArray{}
+		for _, t := range e.Tags {
+			if replace[t] {
+				t = target
+			}
+			if !seen[t] {
+				seen[t] = true
+				tags = append(tags, t)
+			}
+		}
+		updates := map[string]any{"tags": tags}
+		if replace[e.Tag] {
+			updates["tag"] = target
+		}
+		if err := tx.Model(&models.Entry{}).Where("id = ?", e.ID).Updates(updates).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(entries), nil
+}
+
+// GET /v1/tags
+func (s *Server) listTags(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	tags := []TagCount{}
+	err := database.DB.Raw(`
+		SELECT t AS name, COUNT(*) AS count
+		FROM entries, jsonb_array_elements_text(entries.tags) AS t
+		WHERE entries.user_id = ?
+		GROUP BY t
+		ORDER BY count DESC, name ASC`, userID).Scan(&tags).Error
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, tags)
+}
+
+// POST /v1/tags/rename
+func (s *Server) renameTag(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input struct {
+		From string `json:"from" binding:"required"`
+		To   string `json:"to" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	input.From, input.To = strings.TrimSpace(input.From), strings.TrimSpace(input.To)
+	if input.From == "" || input.To == "" {
+		c.JSON(400, gin.H{"error": "from and to cannot be empty"})
+		return
+	}
+
+	var updated int
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		updated, err = rewriteTags(tx, userID, []string{input.From}, input.To)
+		return err
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"updated": updated})
+}
+
+// POST /v1/tags/merge
+func (s *Server) mergeTags(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input struct {
+		Sources []string `json:"sources" binding:"required"`
+		Target  string   `json:"target" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	input.Target = strings.TrimSpace(input.Target)
+	var sources []string
+	for _, src := range input.Sources {
+		if src = strings.TrimSpace(src); src != "" {
+			sources = append(sources, src)
+		}
+	}
+	if input.Target == "" || len(sources) == 0 {
+		c.JSON(400, gin.H{"error": "sources and target are required"})
+		return
+	}
+
+	var updated int
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		updated, err = rewriteTags(tx, userID, sources, input.Target)
+		return err
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"updated": updated})
+}
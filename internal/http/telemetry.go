@@ -0,0 +1,414 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"finance-parser-go/internal/ai"
+	"finance-parser-go/internal/audio"
+	"finance-parser-go/internal/database"
+	"finance-parser-go/internal/models"
+)
+
+// correctionFields are the draft fields compared with the saved entry.
+// Notes are left out: they hold line items and free text the user adds.
+var correctionFields = []string{
+	"type", "title", "amount", "mode", "card_network", "category", "merchant",
+	"purpose_type", "tags", "date", "time", "account_id",
+}
+
+// parseTrace times one parser call and collects its ParseLog.
+type parseTrace struct {
+	log      models.ParseLog
+	start    time.Time
+	answered ai.AnsweredBy
+}
+
+// context returns ctx set up to report a reply that came from the offline
+// parser instead of the configured model; pass it to the parser call.
+func (t *parseTrace) context(ctx context.Context) context.Context {
+	return ai.WithAnsweredBy(ctx, &t.answered)
+}
+
+func (s *Server) startTrace(ctx context.Context, userID uint, source, transcript string) *parseTrace {
+	provider := s.cfg.LLMProvider
+	if source == "image" {
+		provider = s.cfg.VisionProvider
+	}
+	return &parseTrace{
+		start: time.Now(),
+		log: models.ParseLog{
+			UserID:        userID,
+			Source:        source,
+			Transcript:    transcript,
+			Provider:      provider,
+			Model:         s.modelName(provider, source == "image"),
+			PromptVersion: ai.PromptVersionFrom(ctx, s.cfg.PromptVersion),
+		},
+	}
+}
+
+// modelName is the model a provider is configured with; the fake and
+// offline parsers are named after themselves.
+func (s *Server) modelName(provider string, vision bool) string {
+	switch strings.ToLower(provider) {
+	case "", "openai":
+		if vision {
+			return s.cfg.OpenAIVision
+		}
+		return s.cfg.OpenAILlmModel
+	case "compatible", "ollama", "llamacpp":
+		if vision {
+			return s.cfg.LLMVision
+		}
+		return s.cfg.LLMModel
+	}
+	return strings.ToLower(provider)
+}
+
+// audio records the length of the clip the transcript came from.
+func (t *parseTrace) audio(clip *audio.Clip) {
+	if clip != nil {
+		t.log.AudioMs = clip.Duration.Milliseconds()
+	}
+}
+
+// done records the parser's reply, or its error, and the time it took.
+// Provider and model name the offline parser when it answered.
+func (t *parseTrace) done(raw []byte, err error) {
+	t.log.LatencyMs = time.Since(t.start).Milliseconds()
+	if t.answered.Backend != "" {
+		t.log.Provider, t.log.Model = "offline", t.answered.Backend
+	}
+	switch {
+	case len(raw) == 0:
+	case json.Valid(raw):
+		t.log.RawOutput = models.JSONDocument(raw)
+	default:
+		t.log.RawOutput, _ = json.Marshal(string(raw))
+	}
+	t.fail(err)
+}
+
+// fail records why the reply could not be used.
+func (t *parseTrace) fail(err error) {
+	if err != nil {
+		t.log.Error = err.Error()
+	}
+}
+
+// saveTrace stores the log with the finished drafts and their schema
+// errors, then stamps the drafts with parse_id. drafts is nil when the
+// parse failed. Telemetry never fails the request.
+func (s *Server) saveTrace(t *parseTrace, drafts []map[string]any) {
+	if drafts != nil {
+		if raw, err := json.Marshal(drafts); err == nil {
+			t.log.Drafts = models.JSONDocument(raw)
+		}
+		t.log.ValidationErrors, _ = s.schemaErrors(drafts)
+	}
+	s.pruneParseLogs()
+	if err := database.DB.Create(&t.log).Error; err != nil {
+		return
+	}
+	for _, draft := range drafts {
+		draft["parse_id"] = t.log.ID
+	}
+}
+
+// pruneParseLogs drops parse logs older than PARSE_LOG_RETENTION_DAYS along
+// with their field results. Entries keep their parse_id; links to logs that
+// are gone are ignored.
+func (s *Server) pruneParseLogs() {
+	if s.cfg.ParseLogRetentionDays <= 0 {
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -s.cfg.ParseLogRetentionDays)
+	old := database.DB.Model(&models.ParseLog{}).Select("id").Where("created_at < ?", cutoff)
+	database.DB.Where("parse_log_id IN (?)", old).Delete(&models.ParseFieldResult{})
+	database.DB.Where("created_at < ?", cutoff).Delete(&models.ParseLog{})
+}
+
+// loadParseLogs returns the parse logs the entries link to, keyed by ID.
+// Links to logs that don't exist or belong to someone else are dropped.
+func loadParseLogs(userID uint, entries []models.Entry) map[uint]models.ParseLog {
+	var ids []uint
+	for _, e := range entries {
+		if e.ParseLogID != nil {
+			ids = append(ids, *e.ParseLogID)
+		}
+	}
+	logs := map[uint]models.ParseLog{}
+	if len(ids) == 0 {
+		return logs
+	}
+	var rows []models.ParseLog
+	database.DB.Where("id IN ? AND user_id = ?", ids, userID).Find(&rows)
+	for _, l := range rows {
+		logs[l.ID] = l
+	}
+	for i := range entries {
+		if id := entries[i].ParseLogID; id != nil {
+			if _, ok := logs[*id]; !ok {
+				entries[i].ParseLogID = nil
+			}
+		}
+	}
+	return logs
+}
+
+// recordCorrections compares saved entries with the drafts they came from
+// and stores one ParseFieldResult per field either side filled in.
+func recordCorrections(logs map[uint]models.ParseLog, entries []models.Entry) {
+	var results []models.ParseFieldResult
+	for _, e := range entries {
+		if e.ParseLogID == nil || e.ID == 0 {
+			continue
+		}
+		l, ok := logs[*e.ParseLogID]
+		if !ok {
+			continue
+		}
+		draft := draftFor(l, e)
+		if draft == nil {
+			continue
+		}
+		var exists int64
+		database.DB.Model(&models.ParseFieldResult{}).Where("entry_id = ?", e.ID).Count(&exists)
+		if exists > 0 {
+			continue
+		}
+
+		var saved map[string]any
+		raw, _ := json.Marshal(e)
+		if err := json.Unmarshal(raw, &saved); err != nil {
+			continue
+		}
+		for _, field := range correctionFields {
+			parsed, final := comparable(draft[field]), comparable(saved[field])
+			if parsed == "" && final == "" {
+				continue
+			}
+			results = append(results, models.ParseFieldResult{
+				ParseLogID: l.ID,
+				EntryID:    e.ID,
+				UserID:     e.UserID,
+				Field:      field,
+				Corrected:  parsed != final,
+				Parsed:     parsed,
+				Saved:      final,
+			})
+		}
+	}
+	if len(results) > 0 {
+		database.DB.Create(&results)
+	}
+}
+
+// draftFor finds the draft an entry was saved from: the one with the same
+// source_text, or the only draft.
+func draftFor(l models.ParseLog, e models.Entry) map[string]any {
+	var drafts []map[string]any
+	if err := json.Unmarshal(l.Drafts, &drafts); err != nil || len(drafts) == 0 {
+		return nil
+	}
+	for _, d := range drafts {
+		if text, _ := d["source_text"].(string); text != "" && strings.EqualFold(strings.TrimSpace(text), strings.TrimSpace(e.SourceText)) {
+			return d
+		}
+	}
+	if len(drafts) == 1 {
+		return drafts[0]
+	}
+	return nil
+}
+
+// comparable renders a draft or entry value so that equal values compare
+// equal: case-insensitive strings, plain numbers, tags in any order.
+func comparable(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.ToLower(strings.TrimSpace(x))
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case []any:
+		parts := make([]string, 0, len(x))
+		for _, item := range x {
+			if p := comparable(item); p != "" {
+				parts = append(parts, p)
+			}
+		}
+		sort.Strings(parts)
+		return strings.Join(parts, ",")
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// adminOnly guards operator endpoints with ADMIN_TOKEN, sent as
+// X-Admin-Token. Without a configured token they don't exist.
+func adminOnly(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(404, gin.H{"error": "not found"})
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Admin-Token")), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(401, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
+type qualityRow struct {
+	Key           string   `json:"key"`
+	Parses        int64    `json:"parses"`
+	Failures      int64    `json:"failures"`
+	SchemaInvalid int64    `json:"schema_invalid"`
+	CacheHits     int64    `json:"cache_hits"`
+	AvgLatencyMs  float64  `json:"avg_latency_ms"`
+	Compared      int64    `json:"compared"`
+	Corrected     int64    `json:"corrected"`
+	Accuracy      *float64 `json:"accuracy"` // share of compared fields the user kept; null before any are saved
+}
+
+// GET /v1/admin/parse-quality
+// Parser accuracy over time across all users: parse counts, failures,
+// schema errors and latency from ParseLog, and how many draft fields users
+// corrected before saving. Filters: days (default 30), interval (day or
+// week), prompt_version, source.
+func (s *Server) parseQuality(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days <= 0 {
+		c.JSON(400, gin.H{"error": "invalid days"})
+		return
+	}
+	interval := c.DefaultQuery("interval", "day")
+	if interval != "day" && interval != "week" {
+		c.JSON(400, gin.H{"error": "interval must be day or week"})
+		return
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	logs := func() *gorm.DB {
+		db := database.DB.Table("parse_logs AS l").Where("l.created_at >= ?", since)
+		if v := c.Query("prompt_version"); v != "" {
+			db = db.Where("l.prompt_version = ?", v)
+		}
+		if v := c.Query("source"); v != "" {
+			db = db.Where("l.source = ?", v)
+		}
+		return db
+	}
+	results := func() *gorm.DB {
+		return logs().Joins("JOIN parse_field_results r ON r.parse_log_id = l.id")
+	}
+	const parseStats = "COUNT(*) AS parses, " +
+		"COALESCE(SUM(CASE WHEN l.error <> '' THEN 1 ELSE 0 END), 0) AS failures, " +
+		"COALESCE(SUM(CASE WHEN jsonb_array_length(COALESCE(l.validation_errors, '[]'::jsonb)) > 0 THEN 1 ELSE 0 END), 0) AS schema_invalid, " +
+		"COALESCE(SUM(CASE WHEN l.cache_hit THEN 1 ELSE 0 END), 0) AS cache_hits, " +
+		"COALESCE(AVG(l.latency_ms), 0) AS avg_latency_ms"
+	const fieldStats = "COUNT(*) AS compared, COALESCE(SUM(CASE WHEN r.corrected THEN 1 ELSE 0 END), 0) AS corrected"
+
+	// grouped runs both aggregates under one key expression and merges
+	// them; an empty key gives the overall totals.
+	grouped := func(key string, args ...any) ([]qualityRow, error) {
+		sel, group := "'' AS key, ", ""
+		if key != "" {
+			sel, group = key+" AS key, ", "key"
+		}
+		var parses, fields []qualityRow
+		q := logs().Select(sel+parseStats, args...)
+		if group != "" {
+			q = q.Group(group)
+		}
+		if err := q.Scan(&parses).Error; err != nil {
+			return nil, err
+		}
+		q = results().Select(sel+fieldStats, args...)
+		if group != "" {
+			q = q.Group(group)
+		}
+		if err := q.Scan(&fields).Error; err != nil {
+			return nil, err
+		}
+
+		byKey := map[string]*qualityRow{}
+		for i := range parses {
+			byKey[parses[i].Key] = &parses[i]
+		}
+		var unmatched []qualityRow
+		for _, f := range fields {
+			if row, ok := byKey[f.Key]; ok {
+				row.Compared, row.Corrected = f.Compared, f.Corrected
+			} else {
+				unmatched = append(unmatched, f)
+			}
+		}
+		rows := append(parses, unmatched...)
+		for i := range rows {
+			if rows[i].Compared > 0 {
+				acc := 1 - float64(rows[i].Corrected)/float64(rows[i].Compared)
+				rows[i].Accuracy = &acc
+			}
+		}
+		sort.Slice(rows, func(i, j int) bool { return rows[i].Key < rows[j].Key })
+		return rows, nil
+	}
+
+	summary, err := grouped("")
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	periods, err := grouped("to_char(date_trunc(?, l.created_at), 'YYYY-MM-DD')", interval)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	prompts, err := grouped("l.prompt_version")
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	var fields []struct {
+		Field     string  `json:"field"`
+		Compared  int64   `json:"compared"`
+		Corrected int64   `json:"corrected"`
+		Rate      float64 `json:"correction_rate"`
+	}
+	err = results().
+		Select("r.field AS field, " + fieldStats + ", AVG(CASE WHEN r.corrected THEN 1.0 ELSE 0.0 END) AS rate").
+		Group("r.field").
+		Order("rate desc, field").
+		Scan(&fields).Error
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	total := qualityRow{}
+	if len(summary) > 0 {
+		total = summary[0]
+	}
+	c.JSON(200, gin.H{
+		"since":    since,
+		"interval": interval,
+		"summary":  total,
+		"fields":   fields,
+		"periods":  periods,
+		"prompts":  prompts,
+	})
+}
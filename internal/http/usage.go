@@ -0,0 +1,244 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"finance-parser-go/internal/ai"
+	"finance-parser-go/internal/audio"
+	"finance-parser-go/internal/database"
+	"finance-parser-go/internal/models"
+	"finance-parser-go/internal/numwords"
+)
+
+// Quota kinds. Parses cover every LLM-backed request (text, stream, image,
+// answers and questions); transcriptions cover speech-to-text.
+const (
+	quotaParse      = "parse"
+	quotaTranscribe = "transcription"
+)
+
+func (s *Server) quotaLimit(c *gin.Context, kind string) int {
+	guest := false
+	if u, ok := c.Get("user"); ok {
+		if user, ok := u.(*models.User); ok {
+			guest = user.IsGuest
+		}
+	}
+	switch {
+	case kind == quotaParse && guest:
+		return s.cfg.GuestParseDailyQuota
+	case kind == quotaParse:
+		return s.cfg.ParseDailyQuota
+	case guest:
+		return s.cfg.GuestTranscribeDailyQuota
+	}
+	return s.cfg.TranscribeDailyQuota
+}
+
+// quotaExceeded books one call of kind against today's allowance and
+// returns nil, or returns the quota_exceeded error body when the allowance
+// is used up. The check and the booking are one conditional UPDATE, so
+// concurrent requests cannot both take the last call. Quotas reset at
+// midnight in the user's timezone.
+func (s *Server) quotaExceeded(c *gin.Context, userID uint, kind string) gin.H {
+	limit := s.quotaLimit(c, kind)
+	loc := s.userLocation(c)
+	now := time.Now().In(loc)
+	today := now.Format("2006-01-02")
+	col, delta := "parses", models.UsageDay{Parses: 1}
+	if kind == quotaTranscribe {
+		col, delta = "transcriptions", models.UsageDay{Transcriptions: 1}
+	}
+	if limit <= 0 {
+		recordUsage(userID, today, delta)
+		return nil
+	}
+
+	database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.UsageDay{UserID: userID, Day: today})
+	res := database.DB.Model(&models.UsageDay{}).
+		Where("user_id = ? AND day = ? AND "+col+" < ?", userID, today, limit).
+		Update(col, gorm.Expr(col+" + 1"))
+	if res.Error != nil {
+		log.Printf("quota: %v", res.Error)
+		return nil
+	}
+	if res.RowsAffected == 1 {
+		return nil
+	}
+
+	var day models.UsageDay
+	database.DB.Where("user_id = ? AND day = ?", userID, today).Limit(1).Find(&day)
+	used := day.Parses
+	if kind == quotaTranscribe {
+		used = day.Transcriptions
+	}
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, loc)
+	return gin.H{
+		"error":     "quota_exceeded",
+		"quota":     kind,
+		"limit":     limit,
+		"used":      used,
+		"resets_at": tomorrow,
+	}
+}
+
+// metered returns a context that collects token usage for an LLM-backed
+// call, and a func that books its tokens. The call itself was booked by
+// quotaExceeded.
+func (s *Server) metered(ctx context.Context, c *gin.Context, userID uint) (context.Context, func()) {
+	u := &ai.Usage{}
+	day := time.Now().In(s.userLocation(c)).Format("2006-01-02")
+	return ai.WithUsage(ctx, u), func() {
+		recordUsage(userID, day, models.UsageDay{
+			LLMCalls:         u.Calls,
+			PromptTokens:     u.PromptTokens,
+			CompletionTokens: u.CompletionTokens,
+		})
+	}
+}
+
+// recordUsage adds delta's counters to the user's row for day.
+func recordUsage(userID uint, day string, delta models.UsageDay) {
+	delta.UserID, delta.Day = userID, day
+	add := func(col string, n int) any { return gorm.Expr("usage_days."+col+" + ?", n) }
+	database.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "day"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"parses":            add("parses", delta.Parses),
+			"transcriptions":    add("transcriptions", delta.Transcriptions),
+			"cache_hits":        add("cache_hits", delta.CacheHits),
+			"llm_calls":         add("llm_calls", delta.LLMCalls),
+			"prompt_tokens":     add("prompt_tokens", delta.PromptTokens),
+			"completion_tokens": add("completion_tokens", delta.CompletionTokens),
+			"updated_at":        time.Now(),
+		}),
+	}).Create(&delta)
+}
+
+// normalizeTranscript makes "Morning coffee, 150 cash." and "morning coffee
+// 150 cash" the same cache key.
+func normalizeTranscript(t string) string {
+	t = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, t)
+	return strings.Trim(strings.Join(strings.Fields(t), " "), ".")
+}
+
+// parseCacheKey covers everything the reply depends on: the user (their
+// profile is in the prompt), the words, the date relative words resolve
+// against, and the prompt selected on ctx.
+func (s *Server) parseCacheKey(ctx context.Context, userID uint, transcript, tz string) string {
+	loc := loadLocationOrIndia(tz, s.cfg.TZDefault)
+	prompt := ai.PromptFingerprint(ai.PromptVersionFrom(ctx, s.cfg.PromptVersion))
+	raw := fmt.Sprintf("%d|%s|%s|%s|%s|%s", userID, normalizeTranscript(transcript),
+		time.Now().In(loc).Format("2006-01-02"), tz, s.cfg.LLMProvider, prompt)
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Server) cachedParse(key string) ([]byte, bool) {
+	if s.cfg.ParseCacheTTLMin <= 0 {
+		return nil, false
+	}
+	var hit models.ParseCacheEntry
+	err := database.DB.Where("key = ? AND expires_at > ?", key, time.Now()).Limit(1).Find(&hit).Error
+	if err != nil || hit.Key == "" {
+		return nil, false
+	}
+	return hit.Response, true
+}
+
+func (s *Server) storeParse(userID uint, key string, raw []byte) {
+	if s.cfg.ParseCacheTTLMin <= 0 {
+		return
+	}
+	now := time.Now()
+	database.DB.Where("expires_at < ?", now).Delete(&models.ParseCacheEntry{})
+	database.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&models.ParseCacheEntry{
+		Key:       key,
+		UserID:    userID,
+		Response:  models.JSONDocument(raw),
+		ExpiresAt: now.Add(time.Duration(s.cfg.ParseCacheTTLMin) * time.Minute),
+	})
+}
+
+// parseTranscript is ParseText behind the cache and the parse quota. It
+// returns the quota_exceeded body, with a nil reply, when the user is over.
+// onDraft, when set, streams the drafts as with ai.ParseStream. The reply
+// and its latency are recorded on trace. Spoken amounts ("dhai hazaar",
+// "1.5 lakh", Devanagari numerals) are rewritten as digits first; see
+// parseContext.restoreSource.
+func (s *Server) parseTranscript(ctx context.Context, c *gin.Context, pc *parseContext, transcript string, trace *parseTrace, onDraft func(int, map[string]any)) ([]byte, gin.H, error) {
+	pc.norm = numwords.Normalize(transcript)
+	transcript = pc.norm.Text
+	key := s.parseCacheKey(ctx, pc.userID, transcript, pc.tz)
+	if raw, ok := s.cachedParse(key); ok {
+		recordUsage(pc.userID, time.Now().In(s.userLocation(c)).Format("2006-01-02"), models.UsageDay{CacheHits: 1})
+		trace.log.CacheHit = true
+		trace.done(raw, nil)
+		if onDraft != nil {
+			if drafts, err := ai.SplitDrafts(raw); err == nil {
+				for i, d := range drafts {
+					onDraft(i, d)
+				}
+			}
+		}
+		return raw, nil, nil
+	}
+
+	if body := s.quotaExceeded(c, pc.userID, quotaParse); body != nil {
+		return nil, body, nil
+	}
+	mctx, done := s.metered(trace.context(ctx), c, pc.userID)
+	var raw []byte
+	var err error
+	if onDraft != nil {
+		raw, err = ai.ParseStream(mctx, s.parser, transcript, pc.tz, pc.profile, onDraft)
+	} else {
+		raw, err = s.parser.ParseText(mctx, transcript, pc.tz, pc.profile)
+	}
+	done()
+	trace.done(raw, err)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := ai.SplitDrafts(raw); err == nil {
+		s.storeParse(pc.userID, key, raw)
+	}
+	return raw, nil, nil
+}
+
+// transcribeMetered is transcribe behind the transcription quota. Over
+// quota, it falls back to hint; the body is returned when there is none.
+// booked says the caller already took the call from the quota.
+func (s *Server) transcribeMetered(ctx context.Context, c *gin.Context, userID uint, clip *audio.Clip, language, hint string, booked bool) (string, gin.H, error) {
+	if clip == nil {
+		return strings.TrimSpace(hint), nil, nil
+	}
+	if !booked {
+		if body := s.quotaExceeded(c, userID, quotaTranscribe); body != nil {
+			if strings.TrimSpace(hint) != "" {
+				return strings.TrimSpace(hint), nil, nil
+			}
+			return "", body, nil
+		}
+	}
+	mctx, done := s.metered(ctx, c, userID)
+	defer done()
+	transcript, err := s.transcribe(mctx, clip, language, hint)
+	return transcript, nil, err
+}
@@ -0,0 +1,140 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// camtDocument is the subset of ISO 20022 camt.053 (bank-to-customer
+// statement) that maps onto entries. Element names are matched without
+// namespaces so camt.053.001.02 through .08 all decode.
+type camtDocument struct {
+	Statements []struct {
+		Acct struct {
+			IBAN  string `xml:"Id>IBAN"`
+			Other string `xml:"Id>Othr>Id"`
+			Ccy   string `xml:"Ccy"`
+		} `xml:"Acct"`
+		Entries []camtEntry `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtEntry struct {
+	Amt struct {
+		Value string `xml:",chardata"`
+		Ccy   string `xml:"Ccy,attr"`
+	} `xml:"Amt"`
+	CdtDbtInd   string `xml:"CdtDbtInd"`
+	BookgDt     string `xml:"BookgDt>Dt"`
+	BookgDtTm   string `xml:"BookgDt>DtTm"`
+	ValDt       string `xml:"ValDt>Dt"`
+	NtryRef     string `xml:"NtryRef"`
+	AcctSvcrRef string `xml:"AcctSvcrRef"`
+	AddtlInfo   string `xml:"AddtlNtryInf"`
+	Details     []struct {
+		EndToEndID   string   `xml:"Refs>EndToEndId"`
+		TxID         string   `xml:"Refs>TxId"`
+		AcctSvcrRef  string   `xml:"Refs>AcctSvcrRef"`
+		Creditor     string   `xml:"RltdPties>Cdtr>Nm"`
+		CreditorPty  string   `xml:"RltdPties>Cdtr>Pty>Nm"`
+		Debtor       string   `xml:"RltdPties>Dbtr>Nm"`
+		DebtorPty    string   `xml:"RltdPties>Dbtr>Pty>Nm"`
+		Unstructured []string `xml:"RmtInf>Ustrd"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+// ParseCAMT parses ISO 20022 camt.053 statements. CdtDbtInd decides the
+// direction (DBIT is an expense, CRDT income); AcctSvcrRef, falling back to
+// NtryRef or the transaction references, becomes the ExternalID.
+func ParseCAMT(data []byte) (*Statement, error) {
+	var doc camtDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid camt.053 document: %w", err)
+	}
+	if len(doc.Statements) == 0 {
+		return nil, fmt.Errorf("no statements found in camt.053 document")
+	}
+
+	st := &Statement{Format: "camt"}
+	line := 0
+	for i, stmt := range doc.Statements {
+		accountRef := firstNonEmpty(stmt.Acct.IBAN, stmt.Acct.Other)
+		if i == 0 {
+			st.AccountRef, st.Currency = accountRef, stmt.Acct.Ccy
+		}
+		for _, n := range stmt.Entries {
+			line++
+			row := Row{Line: line, AccountRef: accountRef}
+			e := &row.Entry
+
+			e.Currency = n.Amt.Ccy
+			if e.Currency == "" {
+				e.Currency = stmt.Acct.Ccy
+			}
+
+			dateStr := n.BookgDt
+			if dateStr == "" && n.BookgDtTm != "" {
+				dateStr = n.BookgDtTm[:min(len(n.BookgDtTm), 10)]
+			}
+			if dateStr == "" {
+				dateStr = n.ValDt
+			}
+			if t, err := time.Parse("2006-01-02", dateStr); err != nil {
+				row.Errors = append(row.Errors, fmt.Sprintf("invalid booking date %q", dateStr))
+			} else {
+				e.Date = t.Format("2006-01-02")
+			}
+
+			amt, err := ParseAmount(n.Amt.Value)
+			switch {
+			case err != nil:
+				row.Errors = append(row.Errors, err.Error())
+			case strings.EqualFold(n.CdtDbtInd, "DBIT"):
+				SetAmount(e, -amt)
+			case strings.EqualFold(n.CdtDbtInd, "CRDT"):
+				SetAmount(e, amt)
+			default:
+				row.Errors = append(row.Errors, fmt.Sprintf("unknown CdtDbtInd %q", n.CdtDbtInd))
+			}
+
+			var party, remittance, ref string
+			if len(n.Details) > 0 {
+				d := n.Details[0]
+				// The counterparty is the creditor when money leaves the account.
+				if strings.EqualFold(n.CdtDbtInd, "DBIT") {
+					party = firstNonEmpty(d.Creditor, d.CreditorPty)
+				} else {
+					party = firstNonEmpty(d.Debtor, d.DebtorPty)
+				}
+				remittance = strings.Join(d.Unstructured, " ")
+				ref = firstNonEmpty(d.AcctSvcrRef, d.TxID, d.EndToEndID)
+			}
+			e.ExternalID = firstNonEmpty(n.AcctSvcrRef, n.NtryRef, ref)
+			if e.ExternalID == "NOTPROVIDED" {
+				e.ExternalID = ""
+			}
+			e.Merchant = party
+			e.Title = statementTitle(party, remittance, n.AddtlInfo)
+			if remittance != "" && remittance != e.Title {
+				e.Notes = remittance
+			}
+			st.Rows = append(st.Rows, row)
+		}
+	}
+
+	if line == 0 {
+		return nil, fmt.Errorf("no entries found in camt.053 document")
+	}
+	return st, nil
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,370 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Mapping maps an Entry field (see Fields) to the CSV column header it is read from.
+type Mapping map[string]string
+
+// Fields are the Entry fields a CSV column can be mapped to. "debit" and
+// "credit" cover statements that split the amount into two columns.
+var Fields = []string{"date", "time", "title", "amount", "debit", "credit", "type", "category", "merchant", "mode", "currency", "notes", "tags"}
+
+// headerHints are used by GuessMapping to map common column names.
+var headerHints = map[string][]string{
+	"date":     {"date", "txn date", "transaction date", "value date", "posting date", "day"},
+	"time":     {"time", "txn time"},
+	"title":    {"title", "description", "narration", "particulars", "details", "remarks", "name"},
+	"amount":   {"amount", "amt", "value", "amount (inr)", "transaction amount"},
+	"debit":    {"debit", "withdrawal", "withdrawal amt", "withdrawal amount", "dr", "paid out"},
+	"credit":   {"credit", "deposit", "deposit amt", "deposit amount", "cr", "paid in"},
+	"type":     {"type", "txn type", "transaction type", "dr/cr", "cr/dr"},
+	"category": {"category", "cat"},
+	"merchant": {"merchant", "payee", "vendor", "store"},
+	"mode":     {"mode", "payment mode", "method", "payment method"},
+	"currency": {"currency", "ccy"},
+	"notes":    {"notes", "note", "memo", "comment"},
+	"tags":     {"tags", "labels"},
+}
+
+type CSVOptions struct {
+	Delimiter  rune    // 0 to detect
+	DateFormat string  // Go layout or a pattern like DD/MM/YYYY; empty to detect
+	Mapping    Mapping // nil to guess from the headers
+}
+
+type CSVResult struct {
+	Delimiter  string   `json:"delimiter"`
+	DateFormat string   `json:"date_format"`
+	Headers    []string `json:"headers"`
+	Mapping    Mapping  `json:"mapping"`
+	Rows       []Row    `json:"rows"`
+}
+
+// DetectDelimiter picks the candidate that splits the first lines into the
+// same, largest number of columns.
+func DetectDelimiter(data []byte) rune {
+	lines := strings.SplitN(string(data), "\n", 6)
+	if len(lines) > 5 {
+		lines = lines[:5]
+	}
+	best, bestCols := ',', 0
+	for _, d := range []rune{',', ';', '\t', '|'} {
+		cols, consistent := -1, true
+		for _, l := range lines {
+			if strings.TrimSpace(l) == "" {
+				continue
+			}
+			n := strings.Count(l, string(d))
+			if cols == -1 {
+				cols = n
+			} else if n != cols {
+				consistent = false
+			}
+		}
+		if consistent && cols > bestCols {
+			best, bestCols = d, cols
+		}
+	}
+	return best
+}
+
+// GuessMapping maps headers onto Entry fields by name.
+func GuessMapping(headers []string) Mapping {
+	m := Mapping{}
+	for _, field := range Fields {
+		for _, h := range headers {
+			norm := strings.ToLower(strings.TrimSpace(h))
+			for _, hint := range headerHints[field] {
+				if norm == hint {
+					m[field] = h
+				}
+			}
+			if _, ok := m[field]; ok {
+				break
+			}
+		}
+	}
+	return m
+}
+
+// patternTokens maps the tokens LayoutFromPattern accepts to Go layout
+// elements.
+var patternTokens = map[string]string{
+	"YYYY": "2006", "yyyy": "2006",
+	"YY": "06", "yy": "06",
+	"MMMM": "January", "MMM": "Jan", "MM": "01", "M": "1",
+	"DD": "02", "dd": "02", "D": "2", "d": "2",
+	"HH": "15", "mm": "04", "ss": "05",
+}
+
+// LayoutFromPattern turns spreadsheet-style patterns (DD/MM/YYYY, MMM D, YYYY)
+// into Go layouts. Strings that already look like Go layouts pass through.
+// Any other run of letters, such as "Mon", is an error.
+func LayoutFromPattern(p string) (string, error) {
+	if strings.Contains(p, "2006") || strings.Contains(p, "06") && strings.Contains(p, "01") {
+		return p, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(p); {
+		c := p[i]
+		if !isASCIILetter(c) {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		j := i
+		for j < len(p) && isASCIILetter(p[j]) {
+			j++
+		}
+		layout, ok := patternTokens[p[i:j]]
+		if !ok {
+			return "", fmt.Errorf("unknown token %q in date format %q", p[i:j], p)
+		}
+		b.WriteString(layout)
+		i = j
+	}
+	return b.String(), nil
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// ValidateMapping reports a mapping key that is not one of Fields.
+func ValidateMapping(m Mapping) error {
+	for field := range m {
+		if !slices.Contains(Fields, field) {
+			return fmt.Errorf("unknown mapping field %q; expected one of %s", field, strings.Join(Fields, ", "))
+		}
+	}
+	return nil
+}
+
+// ParseCSV reads a CSV export into rows. Rows are returned even when they
+// have errors so the caller can preview them.
+func ParseCSV(data []byte, opts CSVOptions) (*CSVResult, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if opts.Delimiter == 0 {
+		opts.Delimiter = DetectDelimiter(data)
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = opts.Delimiter
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.LazyQuotes = true
+
+	headers, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header row: %w", err)
+	}
+	for i := range headers {
+		headers[i] = strings.TrimSpace(headers[i])
+	}
+
+	mapping := opts.Mapping
+	if len(mapping) == 0 {
+		mapping = GuessMapping(headers)
+	}
+	if err := ValidateMapping(mapping); err != nil {
+		return nil, err
+	}
+	col := map[string]int{}
+	for field, header := range mapping {
+		found := false
+		for i, h := range headers {
+			if strings.EqualFold(h, header) {
+				col[field] = i
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("mapped column %q for %s not found in header", header, field)
+		}
+	}
+	if _, ok := col["date"]; !ok {
+		return nil, fmt.Errorf("no column mapped to date")
+	}
+	_, hasAmount := col["amount"]
+	_, hasDebit := col["debit"]
+	_, hasCredit := col["credit"]
+	if !hasAmount && !hasDebit && !hasCredit {
+		return nil, fmt.Errorf("no column mapped to amount, debit or credit")
+	}
+
+	var records [][]string
+	line := 1
+	lines := []int{}
+	for {
+		rec, err := r.Read()
+		line++
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if isBlank(rec) {
+			continue
+		}
+		records = append(records, rec)
+		lines = append(lines, line)
+	}
+
+	get := func(rec []string, field string) string {
+		i, ok := col[field]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	layout, err := LayoutFromPattern(opts.DateFormat)
+	if err != nil {
+		return nil, err
+	}
+	if layout == "" {
+		samples := make([]string, 0, len(records))
+		for _, rec := range records {
+			samples = append(samples, get(rec, "date"))
+		}
+		detected, ok := DetectDateLayout(samples)
+		if !ok {
+			return nil, fmt.Errorf("could not detect date format; pass date_format")
+		}
+		layout = detected
+	}
+
+	res := &CSVResult{
+		Delimiter:  string(opts.Delimiter),
+		DateFormat: layout,
+		Headers:    headers,
+		Mapping:    mapping,
+		Rows:       make([]Row, 0, len(records)),
+	}
+
+	for i, rec := range records {
+		row := Row{Line: lines[i]}
+		e := &row.Entry
+
+		if d := get(rec, "date"); d == "" {
+			row.Errors = append(row.Errors, "missing date")
+		} else if t, err := time.Parse(layout, d); err != nil {
+			row.Errors = append(row.Errors, fmt.Sprintf("date %q does not match %s", d, layout))
+		} else {
+			e.Date = t.Format("2006-01-02")
+			if t.Hour() != 0 || t.Minute() != 0 {
+				e.Time = t.Format("15:04")
+			}
+		}
+		if tm := get(rec, "time"); tm != "" {
+			e.Time = tm
+		}
+
+		switch strings.ToLower(get(rec, "type")) {
+		case "expense", "debit", "dr", "d", "withdrawal":
+			e.Type = "expense"
+		case "income", "credit", "cr", "c", "deposit":
+			e.Type = "income"
+		}
+
+		signed, amtErr := readAmount(get(rec, "amount"), get(rec, "debit"), get(rec, "credit"))
+		if amtErr != nil {
+			row.Errors = append(row.Errors, amtErr.Error())
+		} else {
+			// An explicit type column wins over the sign of the amount.
+			switch e.Type {
+			case "expense":
+				signed = -math.Abs(signed)
+			case "income":
+				signed = math.Abs(signed)
+			}
+			SetAmount(e, signed)
+		}
+
+		e.Title = get(rec, "title")
+		e.Merchant = get(rec, "merchant")
+		e.Category = get(rec, "category")
+		e.Mode = get(rec, "mode")
+		e.Notes = get(rec, "notes")
+		e.Currency = strings.ToUpper(get(rec, "currency"))
+		if e.Currency == "" {
+			e.Currency = "INR"
+		}
+		if tags := get(rec, "tags"); tags != "" {
+			for _, t := range strings.FieldsFunc(tags, func(r rune) bool { return r == ';' || r == '|' || r == ',' }) {
+				if t = strings.TrimSpace(t); t != "" {
+					e.Tags = append(e.Tags, t)
+				}
+			}
+		}
+		if e.Title == "" {
+			e.Title = e.Merchant
+		}
+		if e.Title == "" {
+			row.Errors = append(row.Errors, "missing title or merchant")
+		}
+
+		res.Rows = append(res.Rows, row)
+	}
+
+	return res, nil
+}
+
+// readAmount returns a signed amount from either a single amount column or a
+// debit/credit pair.
+func readAmount(amount, debit, credit string) (float64, error) {
+	if amount != "" {
+		v, err := ParseAmount(amount)
+		if err != nil {
+			return 0, err
+		}
+		// A bare positive amount with no type column is treated as spend.
+		if v > 0 && !strings.HasSuffix(strings.ToUpper(strings.TrimSpace(amount)), "CR") && !strings.HasPrefix(strings.TrimSpace(amount), "+") {
+			v = -v
+		}
+		return v, nil
+	}
+	if debit != "" {
+		v, err := ParseAmount(debit)
+		if err != nil {
+			return 0, err
+		}
+		if v != 0 {
+			if v > 0 {
+				v = -v
+			}
+			return v, nil
+		}
+	}
+	if credit != "" {
+		v, err := ParseAmount(credit)
+		if err != nil {
+			return 0, err
+		}
+		if v < 0 {
+			v = -v
+		}
+		return v, nil
+	}
+	return 0, fmt.Errorf("missing amount")
+}
+
+func isBlank(rec []string) bool {
+	for _, f := range rec {
+		if strings.TrimSpace(f) != "" {
+			return false
+		}
+	}
+	return true
+}
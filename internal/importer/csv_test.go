@@ -0,0 +1,170 @@
+package importer
+
+import (
+	"testing"
+)
+
+func TestDetectDelimiter(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want rune
+	}{
+		{"comma", "Date,Description,Amount\n01/03/2024,Tea,20\n02/03/2024,Bus,15\n", ','},
+		{"semicolon with decimal commas", "Datum;Beschreibung;Betrag\n01.03.2024;Miete;-1.200,00\n02.03.2024;Kaffee;-3,50\n", ';'},
+		{"tab", "Date\tNarration\tDebit\tCredit\n01/03/2024\tATM\t500\t\n", '\t'},
+		{"pipe", "date|title|amount\n2024-03-01|Tea|20\n", '|'},
+	}
+	for _, tt := range tests {
+		if got := DetectDelimiter([]byte(tt.data)); got != tt.want {
+			t.Errorf("%s: DetectDelimiter = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDetectDateLayout(t *testing.T) {
+	tests := []struct {
+		samples []string
+		want    string
+		ok      bool
+	}{
+		{[]string{"2024-03-05", "2024-03-28"}, "2006-01-02", true},
+		{[]string{"05/03/2024", "28/03/2024"}, "02/01/2006", true},
+		// 03/28 can only be month first.
+		{[]string{"03/05/2024", "03/28/2024"}, "01/02/2006", true},
+		{[]string{"05.03.2024", ""}, "02.01.2006", true},
+		{[]string{"05-Mar-2024"}, "02-Jan-2006", true},
+		{[]string{"Mar 5, 2024"}, "Jan 2, 2006", true},
+		{[]string{"2024-03-05 14:30:00"}, "2006-01-02 15:04:05", true},
+		{[]string{"yesterday"}, "", false},
+		{[]string{"", " "}, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := DetectDateLayout(tt.samples)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("DetectDateLayout(%q) = %q, %v; want %q, %v", tt.samples, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		err  bool
+	}{
+		{in: "450", want: 450},
+		{in: "₹2,500", want: 2500},
+		{in: "1,00,000.50", want: 100000.50},
+		{in: "Rs. 1,234", want: 1234},
+		{in: "-1,234.5", want: -1234.5},
+		{in: "(450.00)", want: -450},
+		{in: "500 Dr", want: -500},
+		{in: "1,500.00 CR", want: 1500},
+		{in: "+75", want: 75},
+		{in: "1.234,56", want: 1234.56},
+		{in: "-3,50", want: -3.5},
+		{in: "€ 1.234.567", want: 1234567},
+		{in: "1 234,56", want: 1234.56},
+		{in: "", err: true},
+		{in: "abc", err: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("ParseAmount(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestReadAmount(t *testing.T) {
+	tests := []struct {
+		amount, debit, credit string
+		want                  float64
+		err                   bool
+	}{
+		{amount: "250", want: -250}, // a bare amount is spend
+		{amount: "250 Cr", want: 250},
+		{amount: "+250", want: 250},
+		{debit: "1,200.00", want: -1200},
+		{debit: "0.00", credit: "5,000", want: 5000},
+		{credit: "-75", want: 75},
+		{err: true},
+	}
+	for _, tt := range tests {
+		got, err := readAmount(tt.amount, tt.debit, tt.credit)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("readAmount(%q, %q, %q) = %v, %v; want %v", tt.amount, tt.debit, tt.credit, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		opts   CSVOptions
+		layout string
+		rows   []wantRow
+	}{
+		{
+			name:   "indian bank statement",
+			data:   "\xef\xbb\xbfTxn Date,Narration,Withdrawal Amt,Deposit Amt\n05/03/2024,SWIGGY ORDER,450.00,\n28/03/2024,SALARY MARCH,,\"1,25,000.00\"\n\n",
+			layout: "02/01/2006",
+			rows: []wantRow{
+				{date: "2024-03-05", typ: "expense", currency: "INR", amount: 450},
+				{date: "2024-03-28", typ: "income", currency: "INR", amount: 125000},
+			},
+		},
+		{
+			name:   "european semicolons",
+			data:   "Datum;Beschreibung;Betrag;Währung\n01.03.2024;Miete;-1.200,00;eur\n02.03.2024;Erstattung;+12,50;EUR\n",
+			opts:   CSVOptions{Mapping: Mapping{"date": "Datum", "title": "Beschreibung", "amount": "Betrag", "currency": "Währung"}},
+			layout: "02.01.2006",
+			rows: []wantRow{
+				{date: "2024-03-01", typ: "expense", currency: "EUR", amount: 1200},
+				{date: "2024-03-02", typ: "income", currency: "EUR", amount: 12.50},
+			},
+		},
+		{
+			name:   "date format pattern and type column",
+			data:   "date,title,amount,type\n03/05/2024,Refund,99,credit\n",
+			opts:   CSVOptions{DateFormat: "MM/DD/YYYY"},
+			layout: "01/02/2006",
+			rows:   []wantRow{{date: "2024-03-05", typ: "income", currency: "INR", amount: 99}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := ParseCSV([]byte(tt.data), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.DateFormat != tt.layout {
+				t.Errorf("DateFormat = %q, want %q", res.DateFormat, tt.layout)
+			}
+			// Only the fields wantRow compares are set above.
+			for i := range res.Rows {
+				res.Rows[i].Entry.Merchant = ""
+			}
+			checkRows(t, res.Rows, tt.rows)
+		})
+	}
+}
+
+func TestParseCSVRowErrors(t *testing.T) {
+	res, err := ParseCSV([]byte("date,title,amount\n2024-03-01,Tea,20\n2024-13-45,Bad date,20\n2024-03-02,,abc\n"), CSVOptions{DateFormat: "YYYY-MM-DD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{nil, {`date "2024-13-45" does not match 2006-01-02`}, {`invalid amount "abc"`, "missing title or merchant"}}
+	for i, w := range want {
+		if got := res.Rows[i].Errors; len(got) != len(w) || len(w) > 0 && (got[0] != w[0] || got[len(got)-1] != w[len(w)-1]) {
+			t.Errorf("row %d errors = %q, want %q", i, got, w)
+		}
+	}
+
+	if _, err := ParseCSV([]byte("when,what\n"), CSVOptions{}); err == nil {
+		t.Error("file without date or amount columns accepted")
+	}
+}
@@ -0,0 +1,177 @@
+package importer
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance-parser-go/internal/models"
+)
+
+// Row is one parsed transaction from an import file together with anything
+// that stops it being imported.
+type Row struct {
+	Line       int          `json:"line"`
+	Entry      models.Entry `json:"entry"`
+	AccountRef string       `json:"account_ref,omitempty"` // statement account the row came from, for files with several
+	Errors     []string     `json:"errors,omitempty"`
+	Warnings   []string     `json:"warnings,omitempty"` // changes made to the row; it can still be committed
+	Duplicate  bool         `json:"duplicate"`
+}
+
+// Valid reports whether the row can be committed.
+func (r *Row) Valid() bool {
+	return len(r.Errors) == 0 && !r.Duplicate
+}
+
+// DedupeKey identifies a transaction for duplicate detection: same day, same
+// amount and same description. The day is taken from OccurredAt in loc, so
+// older rows whose date string was written in another format still match;
+// Date is only used when OccurredAt is unset.
+func DedupeKey(e *models.Entry, loc *time.Location) string {
+	desc := e.Merchant
+	if desc == "" {
+		desc = e.Title
+	}
+	day := e.Date
+	if e.OccurredAt != nil {
+		day = e.OccurredAt.In(loc).Format("2006-01-02")
+	}
+	return fmt.Sprintf("%s|%.2f|%s", day, e.Amount, strings.ToLower(strings.Join(strings.Fields(desc), " ")))
+}
+
+// DateRange returns the start of the earliest day and the end of the latest
+// day, in loc, across the valid rows' OccurredAt. ok is false when no row has
+// one.
+func DateRange(rows []Row, loc *time.Location) (from, to time.Time, ok bool) {
+	for _, r := range rows {
+		at := r.Entry.OccurredAt
+		if at == nil || len(r.Errors) > 0 {
+			continue
+		}
+		if !ok || at.Before(from) {
+			from = *at
+		}
+		if !ok || at.After(to) {
+			to = *at
+		}
+		ok = true
+	}
+	if !ok {
+		return from, to, false
+	}
+	from = from.In(loc)
+	to = to.In(loc)
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	to = time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, loc)
+	return from, to, true
+}
+
+// ParseAmount reads amounts as banks and spreadsheets write them: with
+// currency symbols, Indian ("1,00,000.50") or European ("1.234,56")
+// separators, "Dr"/"Cr" suffixes or parentheses for negatives. Debits come
+// back negative.
+func ParseAmount(raw string) (float64, error) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return 0, fmt.Errorf("empty amount")
+	}
+	neg := false
+	upper := strings.ToUpper(s)
+	switch {
+	case strings.HasSuffix(upper, "DR"):
+		neg = true
+		s = s[:len(s)-2]
+	case strings.HasSuffix(upper, "CR"):
+		s = s[:len(s)-2]
+	}
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		neg = true
+		s = s[1 : len(s)-1]
+	}
+	replacer := strings.NewReplacer("₹", "", "Rs.", "", "Rs", "", "INR", "", "EUR", "", "€", "", "$", "", " ", "", "\u00a0", "")
+	s = decimalPoint(replacer.Replace(s))
+	if strings.HasPrefix(s, "-") {
+		neg = !neg
+		s = s[1:]
+	}
+	s = strings.TrimPrefix(s, "+")
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", raw)
+	}
+	if neg {
+		v = -v
+	}
+	return v, nil
+}
+
+// decimalPoint rewrites s with "." as its only separator. When both "." and
+// "," appear the later one is the decimal mark; a lone "," is one only when
+// one or two digits follow it ("12,50"), and repeated dots ("1.234.567")
+// are grouping.
+func decimalPoint(s string) string {
+	dot, comma := strings.LastIndex(s, "."), strings.LastIndex(s, ",")
+	switch {
+	case dot >= 0 && comma >= 0 && comma > dot:
+		return strings.Replace(strings.ReplaceAll(s, ".", ""), ",", ".", 1)
+	case comma >= 0 && dot < 0 && strings.Count(s, ",") == 1 && len(s)-comma-1 <= 2:
+		return strings.Replace(s, ",", ".", 1)
+	case dot >= 0 && comma < 0 && strings.Count(s, ".") > 1:
+		return strings.ReplaceAll(s, ".", "")
+	}
+	return strings.ReplaceAll(s, ",", "")
+}
+
+// SetAmount stores a signed amount on e: negatives are expenses, positives
+// income, and the stored amount is always positive.
+func SetAmount(e *models.Entry, signed float64) {
+	if signed < 0 {
+		e.Type = "expense"
+	} else {
+		e.Type = "income"
+	}
+	e.Amount = math.Round(math.Abs(signed)*100) / 100
+}
+
+// DateLayouts are tried in order when detecting the date format of a column.
+var DateLayouts = []string{
+	"2006-01-02",
+	"02/01/2006",
+	"02-01-2006",
+	"02.01.2006",
+	"01/02/2006",
+	"2/1/2006",
+	"02/01/06",
+	"02-Jan-2006",
+	"02 Jan 2006",
+	"Jan 2, 2006",
+	"2006/01/02",
+	"20060102",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// DetectDateLayout returns the first layout that parses every sample.
+func DetectDateLayout(samples []string) (string, bool) {
+	for _, layout := range DateLayouts {
+		ok, seen := true, 0
+		for _, s := range samples {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			seen++
+			if _, err := time.Parse(layout, s); err != nil {
+				ok = false
+				break
+			}
+		}
+		if ok && seen > 0 {
+			return layout, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,132 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ofxStmtBlock = regexp.MustCompile(`(?is)<(CC)?STMTRS>(.*?)</(?:CC)?STMTRS>`)
+	ofxTxnBlock  = regexp.MustCompile(`(?is)<STMTTRN>(.*?)(?:</STMTTRN>|</BANKTRANLIST>)`)
+	ofxTag       = regexp.MustCompile(`(?i)<([A-Z0-9.]+)>([^<\r\n]*)`)
+)
+
+// ofxFields collects the leaf elements of an OFX fragment. It handles both
+// OFX 1.x SGML (unclosed leaf tags) and OFX 2.x XML.
+func ofxFields(fragment string) map[string]string {
+	out := map[string]string{}
+	for _, m := range ofxTag.FindAllStringSubmatch(fragment, -1) {
+		tag := strings.ToUpper(m[1])
+		if _, ok := out[tag]; ok {
+			continue
+		}
+		if v := strings.TrimSpace(m[2]); v != "" {
+			out[tag] = v
+		}
+	}
+	return out
+}
+
+// parseOFXDate reads OFX datetimes: YYYYMMDD[HHMMSS[.XXX]][[gmt offset:tz name]].
+func parseOFXDate(raw string) (time.Time, error) {
+	s := raw
+	if i := strings.Index(s, "["); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.Index(s, "."); i >= 0 {
+		s = s[:i]
+	}
+	switch len(s) {
+	case 8:
+		return time.Parse("20060102", s)
+	case 12:
+		return time.Parse("200601021504", s)
+	case 14:
+		return time.Parse("20060102150405", s)
+	}
+	return time.Time{}, fmt.Errorf("invalid OFX date %q", raw)
+}
+
+// ParseOFX parses OFX/QFX bank and credit-card statements. TRNAMT is signed
+// from the account holder's view, so negative amounts are expenses. FITID is
+// kept as the entry's ExternalID for de-duplication. A file may hold one
+// statement per account; each row keeps its own account and currency.
+func ParseOFX(data []byte) (*Statement, error) {
+	text := string(data)
+	if !strings.Contains(strings.ToUpper(text), "<OFX>") {
+		return nil, fmt.Errorf("not an OFX document")
+	}
+
+	blocks := []string{text}
+	if ms := ofxStmtBlock.FindAllStringSubmatch(text, -1); len(ms) > 0 {
+		blocks = blocks[:0]
+		for _, m := range ms {
+			blocks = append(blocks, m[2])
+		}
+	}
+
+	st := &Statement{Format: "ofx"}
+	line := 0
+	for _, block := range blocks {
+		header := block
+		if i := strings.Index(strings.ToUpper(block), "<BANKTRANLIST>"); i >= 0 {
+			header = block[:i]
+		}
+		hf := ofxFields(header)
+		currency := hf["CURDEF"]
+		if currency == "" {
+			currency = "INR"
+		}
+		if st.Currency == "" {
+			st.AccountRef, st.Currency = hf["ACCTID"], currency
+		}
+		for _, m := range ofxTxnBlock.FindAllStringSubmatch(block, -1) {
+			line++
+			st.Rows = append(st.Rows, ofxRow(line, hf["ACCTID"], currency, ofxFields(m[1])))
+		}
+	}
+
+	if len(st.Rows) == 0 {
+		return nil, fmt.Errorf("no transactions found in OFX document")
+	}
+	return st, nil
+}
+
+// ofxRow builds the row for one STMTTRN aggregate.
+func ofxRow(line int, accountRef, currency string, f map[string]string) Row {
+	row := Row{Line: line, AccountRef: accountRef}
+	e := &row.Entry
+	e.Currency = currency
+	e.ExternalID = f["FITID"]
+	if e.ExternalID == "" {
+		e.ExternalID = f["REFNUM"]
+	}
+
+	if t, err := parseOFXDate(f["DTPOSTED"]); err != nil {
+		row.Errors = append(row.Errors, err.Error())
+	} else {
+		e.Date = t.Format("2006-01-02")
+	}
+
+	if amt, err := ParseAmount(f["TRNAMT"]); err != nil {
+		row.Errors = append(row.Errors, err.Error())
+	} else {
+		// Some issuers send unsigned amounts and rely on TRNTYPE.
+		switch strings.ToUpper(f["TRNTYPE"]) {
+		case "DEBIT", "PAYMENT", "POS", "ATM", "FEE", "SRVCHG", "CHECK":
+			if amt > 0 {
+				amt = -amt
+			}
+		}
+		SetAmount(e, amt)
+	}
+
+	e.Merchant = strings.TrimSpace(f["NAME"])
+	e.Title = statementTitle(f["NAME"], f["MEMO"], f["PAYEE"])
+	if memo := f["MEMO"]; memo != "" && memo != e.Title {
+		e.Notes = memo
+	}
+	return row
+}
@@ -0,0 +1,174 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// qifDateLayouts covers the date styles QIF exporters emit, including the
+// Quicken "1/31'24" apostrophe year. Day-first layouts come first: the
+// users this app serves write dates that way, so 03/05/2024 is 3 May unless
+// a day above 12 or date_format says otherwise.
+var qifDateLayouts = []string{
+	"02/01/2006", "2/1/2006", "02/01'06", "2/1'06", "02/01/06", "2/1/06",
+	"01/02/2006", "1/2/2006", "01/02'06", "1/2'06", "01/02/06", "1/2/06",
+	"2006-01-02", "02.01.2006", "02-01-2006",
+}
+
+// ParseQIF parses Quicken Interchange Format bank and card exports. QIF has
+// no transaction IDs, so a numeric cheque number (N) is used as the
+// ExternalID, prefixed with the !Account name when the file has one. N also
+// holds words such as ATM, DEP or Transfer that many rows share; those rows,
+// like rows without N, de-duplicate on date, amount and payee. dateFormat (see LayoutFromPattern) fixes the date layout; empty
+// detects it.
+func ParseQIF(data []byte, dateFormat string) (*Statement, error) {
+	fixed, err := LayoutFromPattern(dateFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &Statement{Format: "qif", Currency: "INR"}
+
+	type record struct {
+		line    int
+		account string
+		fields  map[byte]string
+	}
+	var records []record
+	cur := record{fields: map[byte]string{}}
+	// An !Account header is followed by one block naming the account; the
+	// transactions after it belong to that account.
+	account, inAccount := "", false
+
+	sc := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	line := 0
+	for sc.Scan() {
+		line++
+		l := strings.TrimRight(sc.Text(), "\r")
+		if l == "" {
+			continue
+		}
+		if l[0] == '!' {
+			// !Type:Bank and !Option lines carry nothing we use.
+			inAccount = strings.EqualFold(strings.TrimSpace(l), "!Account")
+			continue
+		}
+		if l[0] == '^' {
+			switch {
+			case inAccount:
+				account, inAccount = cur.fields['N'], false
+			case len(cur.fields) > 0:
+				records = append(records, cur)
+			}
+			cur = record{account: account, fields: map[byte]string{}}
+			continue
+		}
+		if cur.line == 0 {
+			cur.line = line
+		}
+		code := l[0]
+		// Split lines (S/E/$) repeat; keep the first of each for the parent transaction.
+		if _, ok := cur.fields[code]; !ok {
+			cur.fields[code] = strings.TrimSpace(l[1:])
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(cur.fields) > 0 {
+		records = append(records, cur)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no transactions found in QIF file")
+	}
+
+	samples := make([]string, 0, len(records))
+	for _, r := range records {
+		samples = append(samples, r.fields['D'])
+	}
+	layouts := qifDateLayouts
+	if fixed != "" {
+		layouts = []string{fixed}
+	}
+	layout := ""
+	for _, l := range layouts {
+		ok := true
+		for _, s := range samples {
+			if _, err := time.Parse(l, strings.TrimSpace(s)); s != "" && err != nil {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			layout = l
+			break
+		}
+	}
+
+	for _, r := range records {
+		row := Row{Line: r.line, AccountRef: r.account}
+		if st.AccountRef == "" {
+			st.AccountRef = r.account
+		}
+		e := &row.Entry
+		e.Currency = st.Currency
+
+		if layout == "" {
+			row.Errors = append(row.Errors, fmt.Sprintf("unrecognised date %q", r.fields['D']))
+		} else if t, err := time.Parse(layout, r.fields['D']); err != nil {
+			row.Errors = append(row.Errors, fmt.Sprintf("unrecognised date %q", r.fields['D']))
+		} else {
+			e.Date = t.Format("2006-01-02")
+		}
+
+		raw := r.fields['T']
+		if raw == "" {
+			raw = r.fields['U']
+		}
+		if amt, err := ParseAmount(raw); err != nil {
+			row.Errors = append(row.Errors, err.Error())
+		} else {
+			SetAmount(e, amt)
+		}
+
+		e.Merchant = r.fields['P']
+		e.Title = statementTitle(r.fields['P'], r.fields['M'])
+		if m := r.fields['M']; m != "" && m != e.Title {
+			e.Notes = m
+		}
+		if cat := r.fields['L']; cat != "" && !strings.HasPrefix(cat, "[") {
+			// "Food:Groceries" -> category Food, sub-category Groceries; "[Account]" is a transfer.
+			parts := strings.SplitN(cat, ":", 2)
+			e.Category = parts[0]
+			if len(parts) == 2 {
+				e.SubCategory = parts[1]
+			}
+		}
+		if n := r.fields['N']; isChequeNumber(n) {
+			if r.account != "" {
+				n = r.account + ":" + n
+			}
+			e.ExternalID = "qif:" + n
+		}
+		st.Rows = append(st.Rows, row)
+	}
+
+	return st, nil
+}
+
+// isChequeNumber reports whether a QIF N field is a cheque or reference
+// number rather than a label like ATM, DEP or Transfer.
+func isChequeNumber(n string) bool {
+	if n == "" {
+		return false
+	}
+	for _, c := range n {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
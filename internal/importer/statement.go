@@ -0,0 +1,86 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+var errUnknownFormat = errors.New("unknown statement format")
+
+// Statement is a bank or card statement parsed from OFX, QIF or CAMT.053.
+// A file can hold several accounts; AccountRef and Currency describe the
+// first, and each row carries the AccountRef of its own account.
+type Statement struct {
+	Format     string `json:"format"`
+	AccountRef string `json:"account_ref"` // account number or IBAN as the bank wrote it
+	Currency   string `json:"currency"`
+	Rows       []Row  `json:"rows"`
+}
+
+// StatementOptions tune parsing for formats that leave things ambiguous.
+type StatementOptions struct {
+	// DateFormat is a Go layout or a pattern like MM/DD/YYYY for QIF dates.
+	// Empty detects the format, reading ambiguous dates day first.
+	DateFormat string
+}
+
+// DetectFormat works out the statement format from the file name, falling
+// back to sniffing the content. It returns "" when the format is unknown.
+func DetectFormat(filename string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".ofx", ".qfx":
+		return "ofx"
+	case ".qif":
+		return "qif"
+	}
+	head := bytes.ToUpper(data[:min(len(data), 2048)])
+	switch {
+	case bytes.Contains(head, []byte("OFXHEADER")), bytes.Contains(head, []byte("<OFX>")):
+		return "ofx"
+	case bytes.HasPrefix(bytes.TrimSpace(head), []byte("!TYPE:")), bytes.HasPrefix(bytes.TrimSpace(head), []byte("!ACCOUNT")):
+		return "qif"
+	case bytes.Contains(head, []byte("CAMT.053")), bytes.Contains(head, []byte("<BKTOCSTMRSTMT>")):
+		return "camt"
+	}
+	return ""
+}
+
+// ParseStatement dispatches to the parser for format.
+func ParseStatement(format string, data []byte, opts StatementOptions) (*Statement, error) {
+	switch format {
+	case "ofx", "qfx":
+		return ParseOFX(data)
+	case "qif":
+		return ParseQIF(data, opts.DateFormat)
+	case "camt", "camt053":
+		return ParseCAMT(data)
+	}
+	return nil, errUnknownFormat
+}
+
+// Last4 returns the last four digits of an account reference, which is what
+// Account.Identifier usually stores.
+func Last4(ref string) string {
+	digits := make([]rune, 0, len(ref))
+	for _, r := range ref {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, r)
+		}
+	}
+	if len(digits) < 4 {
+		return string(digits)
+	}
+	return string(digits[len(digits)-4:])
+}
+
+// statementTitle picks the most descriptive of the payee/memo strings.
+func statementTitle(candidates ...string) string {
+	for _, c := range candidates {
+		if c = strings.Join(strings.Fields(c), " "); c != "" {
+			return c
+		}
+	}
+	return "Bank transaction"
+}
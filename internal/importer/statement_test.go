@@ -0,0 +1,199 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// wantRow is the part of a parsed row the fixture tests check.
+type wantRow struct {
+	date, typ, merchant, externalID, accountRef, currency string
+	amount                                                float64
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func checkRows(t *testing.T, rows []Row, want []wantRow) {
+	t.Helper()
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		r := rows[i]
+		if len(r.Errors) > 0 {
+			t.Errorf("row %d: unexpected errors %v", i, r.Errors)
+			continue
+		}
+		e := r.Entry
+		got := wantRow{e.Date, e.Type, e.Merchant, e.ExternalID, r.AccountRef, e.Currency, e.Amount}
+		if got != w {
+			t.Errorf("row %d:\n got  %+v\n want %+v", i, got, w)
+		}
+	}
+}
+
+func TestParseStatementFixtures(t *testing.T) {
+	tests := []struct {
+		file       string
+		format     string
+		opts       StatementOptions
+		accountRef string
+		rows       []wantRow
+	}{
+		{
+			file: "hdfc_savings.ofx", format: "ofx", accountRef: "50100012341234",
+			rows: []wantRow{
+				{"2024-03-05", "expense", "UPI-SWIGGY-swiggy@axis", "S12345678", "50100012341234", "INR", 450},
+				{"2024-03-01", "income", "ACME TECHNOLOGIES PVT LTD", "S12345679", "50100012341234", "INR", 85000},
+				{"2024-03-12", "expense", "AMAZON PAY INDIA", "S12345680", "50100012341234", "INR", 1299},
+			},
+		},
+		{
+			file: "card_v2.qfx", format: "ofx", accountRef: "XXXXXXXXXXXX9876",
+			rows: []wantRow{
+				{"2024-03-08", "expense", "UBER INDIA SYSTEMS", "2024030800001", "XXXXXXXXXXXX9876", "INR", 2450.50},
+				{"2024-03-20", "income", "CASHBACK", "2024032000002", "XXXXXXXXXXXX9876", "INR", 500},
+			},
+		},
+		{
+			file: "two_accounts.ofx", format: "ofx", accountRef: "000401551111",
+			rows: []wantRow{
+				{"2024-04-02", "expense", "DMART", "A1", "000401551111", "INR", 320},
+				{"2024-04-05", "income", "UPWORK ESCROW", "A1", "000405552222", "USD", 150},
+			},
+		},
+		{
+			file: "sepa_statement.camt053.xml", format: "camt", accountRef: "DE89370400440532013000",
+			rows: []wantRow{
+				{"2024-03-04", "expense", "Stadtwerke Berlin", "REF-2024-0304-01", "DE89370400440532013000", "EUR", 42.50},
+				{"2024-03-28", "income", "Example GmbH", "REF-2024-0328-07", "DE89370400440532013000", "EUR", 2500},
+			},
+		},
+		{
+			// Ambiguous dates read day first by default.
+			file: "wallet.qif", format: "qif",
+			rows: []wantRow{
+				{"2024-05-03", "expense", "Swiggy", "", "", "INR", 450},
+				{"2024-06-03", "expense", "Metro Card", "qif:000123", "", "INR", 120},
+				{"2024-07-03", "income", "Rahul", "", "", "INR", 15000},
+				{"2024-08-03", "expense", "Cash withdrawal", "", "", "INR", 2000},
+				{"2024-09-03", "expense", "Cash withdrawal", "", "", "INR", 500},
+			},
+		},
+		{
+			file: "wallet.qif", format: "qif", opts: StatementOptions{DateFormat: "MM/DD/YYYY"},
+			rows: []wantRow{
+				{"2024-03-05", "expense", "Swiggy", "", "", "INR", 450},
+				{"2024-03-06", "expense", "Metro Card", "qif:000123", "", "INR", 120},
+				{"2024-03-07", "income", "Rahul", "", "", "INR", 15000},
+				{"2024-03-08", "expense", "Cash withdrawal", "", "", "INR", 2000},
+				{"2024-03-09", "expense", "Cash withdrawal", "", "", "INR", 500},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file+tt.opts.DateFormat, func(t *testing.T) {
+			data := readFixture(t, tt.file)
+			if got := DetectFormat(tt.file, data); got != tt.format {
+				t.Errorf("DetectFormat = %q, want %q", got, tt.format)
+			}
+			st, err := ParseStatement(tt.format, data, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if st.AccountRef != tt.accountRef {
+				t.Errorf("AccountRef = %q, want %q", st.AccountRef, tt.accountRef)
+			}
+			checkRows(t, st.Rows, tt.rows)
+		})
+	}
+}
+
+func TestParseQIFCategories(t *testing.T) {
+	st, err := ParseQIF(readFixture(t, "wallet.qif"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := [][2]string{}
+	for _, r := range st.Rows {
+		got = append(got, [2]string{r.Entry.Category, r.Entry.SubCategory})
+	}
+	// "[Savings]" is a transfer, not a category.
+	want := [][2]string{{"Food", "Delivery"}, {"Travel", ""}, {"", ""}}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d: category %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+// N holds labels such as ATM as well as cheque numbers; only the numbers
+// identify a transaction, so two withdrawals marked ATM both stay distinct.
+func TestParseQIFReferenceNumbers(t *testing.T) {
+	st, err := ParseQIF(readFixture(t, "wallet.qif"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	atm1, atm2 := st.Rows[3].Entry, st.Rows[4].Entry
+	if atm1.ExternalID != "" || atm2.ExternalID != "" {
+		t.Errorf("ATM rows got external IDs %q, %q", atm1.ExternalID, atm2.ExternalID)
+	}
+	if DedupeKey(&atm1, time.UTC) == DedupeKey(&atm2, time.UTC) {
+		t.Error("different ATM withdrawals share a dedupe key")
+	}
+
+	data := []byte("!Account\nNHDFC Savings\nTBank\n^\n!Type:Bank\nD05/03/2024\nT-10\nPTea\nN0042\n^\nD06/03/2024\nT-20\nPCoffee\nNEFT\n^\n")
+	st, err = ParseQIF(data, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRows(t, st.Rows, []wantRow{
+		{"2024-03-05", "expense", "Tea", "qif:HDFC Savings:0042", "HDFC Savings", "INR", 10},
+		{"2024-03-06", "expense", "Coffee", "", "HDFC Savings", "INR", 20},
+	})
+	if st.AccountRef != "HDFC Savings" {
+		t.Errorf("AccountRef = %q", st.AccountRef)
+	}
+}
+
+func TestParseQIFDayAboveTwelve(t *testing.T) {
+	data := []byte("!Type:Bank\nD12/31/2024\nT-10\nPTea\n^\nD01/15/2024\nT-20\nPCoffee\n^\n")
+	st, err := ParseQIF(data, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := st.Rows[0].Entry.Date; d != "2024-12-31" {
+		t.Errorf("date = %q, want 2024-12-31", d)
+	}
+}
+
+func TestLayoutFromPattern(t *testing.T) {
+	tests := []struct {
+		in, want string
+		err      bool
+	}{
+		{in: "DD/MM/YYYY", want: "02/01/2006"},
+		{in: "MMM D, YYYY", want: "Jan 2, 2006"},
+		{in: "YYYY-MM-DD HH:mm", want: "2006-01-02 15:04"},
+		{in: "02/01/2006", want: "02/01/2006"},
+		{in: "", want: ""},
+		{in: "Mon", err: true},
+		{in: "DD-Mon-YY", err: true},
+	}
+	for _, tt := range tests {
+		got, err := LayoutFromPattern(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("LayoutFromPattern(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package merchants
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"finance-parser-go/internal/models"
+)
+
+// Builtin covers merchants common enough that every user benefits from them.
+// A user's own directory always takes precedence.
+var Builtin = []models.Merchant{
+	{Name: "Swiggy", Aliases: models.StringArray{"swiggy instamart", "instamart", "bundl technologies"}, DefaultCategory: "Food", Icon: "food-takeout-box-outline"},
+	{Name: "Zomato", Aliases: models.StringArray{"zomato ltd", "blinkit"}, DefaultCategory: "Food", Icon: "food-outline"},
+	{Name: "Uber", Aliases: models.StringArray{"uber india", "uber trip", "uber rides"}, DefaultCategory: "Travel", Icon: "car"},
+	{Name: "Ola", Aliases: models.StringArray{"ola cabs", "ani technologies"}, DefaultCategory: "Travel", Icon: "taxi"},
+	{Name: "Rapido", DefaultCategory: "Travel", Icon: "motorbike"},
+	{Name: "Amazon", Aliases: models.StringArray{"amazon pay", "amazon in", "amzn", "amazon seller services"}, DefaultCategory: "Shopping", Icon: "shopping-outline"},
+	{Name: "Flipkart", DefaultCategory: "Shopping", Icon: "cart-outline"},
+	{Name: "Myntra", DefaultCategory: "Shopping", Icon: "tshirt-crew-outline"},
+	{Name: "BigBasket", Aliases: models.StringArray{"big basket", "bb now"}, DefaultCategory: "Food", Icon: "basket-outline"},
+	{Name: "Zepto", DefaultCategory: "Food", Icon: "basket-outline"},
+	{Name: "IRCTC", Aliases: models.StringArray{"irctc e ticketing"}, DefaultCategory: "Travel", Icon: "train"},
+	{Name: "Netflix", DefaultCategory: "Bills", Icon: "netflix"},
+	{Name: "Airtel", Aliases: models.StringArray{"bharti airtel"}, DefaultCategory: "Bills", Icon: "cellphone"},
+	{Name: "Jio", Aliases: models.StringArray{"reliance jio"}, DefaultCategory: "Bills", Icon: "cellphone"},
+	{Name: "Starbucks", Aliases: models.StringArray{"tata starbucks"}, DefaultCategory: "Food", Icon: "coffee-outline"},
+}
+
+var builtin = Compile(Builtin)
+
+var nonAlnum = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Directory is a merchant list with its patterns compiled. Build it once per
+// request with Compile rather than matching against the raw list.
+type Directory struct {
+	Merchants []models.Merchant
+	patterns  [][]*regexp.Regexp
+}
+
+// Compile prepares list for matching. Patterns that don't compile are
+// skipped; saving a merchant rejects them up front (see Validate).
+func Compile(list []models.Merchant) *Directory {
+	d := &Directory{Merchants: list, patterns: make([][]*regexp.Regexp, len(list))}
+	for i, m := range list {
+		for _, p := range m.Patterns {
+			if re, err := compilePattern(p); err == nil {
+				d.patterns[i] = append(d.patterns[i], re)
+			}
+		}
+	}
+	return d
+}
+
+// Validate reports the first pattern of m that is not a valid regular
+// expression.
+func Validate(m models.Merchant) error {
+	for _, p := range m.Patterns {
+		if _, err := compilePattern(p); err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", p, err)
+		}
+	}
+	return nil
+}
+
+func compilePattern(p string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + p)
+}
+
+// Key reduces a raw merchant string to a comparison key: lower-cased, with
+// payment-gateway suffixes ("SWIGGY*INSTAMART"), UPI handles
+// ("swiggy@axis") and punctuation removed.
+func Key(raw string) string {
+	s := strings.ToLower(strings.TrimSpace(raw))
+	if i := strings.IndexAny(s, "*@"); i > 0 {
+		s = s[:i]
+	}
+	s = nonAlnum.ReplaceAllString(s, " ")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Resolve finds the directory entry for a raw merchant string, checking the
+// user's directory (which may be nil) before the built-in one. It returns nil
+// when nothing matches.
+func Resolve(directory *Directory, raw string) *models.Merchant {
+	key := Key(raw)
+	if key == "" {
+		return nil
+	}
+	if m := directory.resolve(raw, key); m != nil {
+		return m
+	}
+	return builtin.resolve(raw, key)
+}
+
+func (d *Directory) resolve(raw, key string) *models.Merchant {
+	if d == nil {
+		return nil
+	}
+	for i := range d.Merchants {
+		m := &d.Merchants[i]
+		if Key(m.Name) == key {
+			return m
+		}
+		for _, a := range m.Aliases {
+			if Key(a) == key {
+				return m
+			}
+		}
+	}
+	for i, res := range d.patterns {
+		for _, re := range res {
+			if re.MatchString(raw) {
+				return &d.Merchants[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Canonical returns the directory name for raw, or a tidied version of raw
+// itself when the merchant is unknown.
+func Canonical(directory *Directory, raw string) string {
+	if m := Resolve(directory, raw); m != nil {
+		return m.Name
+	}
+	return Tidy(raw)
+}
+
+// Tidy trims whitespace and title-cases shouty all-caps names like "DMART".
+func Tidy(raw string) string {
+	raw = strings.Join(strings.Fields(raw), " ")
+	hasLower := strings.IndexFunc(raw, unicode.IsLower) >= 0
+	if hasLower || len(raw) <= 3 {
+		return raw
+	}
+	words := strings.Fields(strings.ToLower(raw))
+	for i, w := range words {
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		words[i] = string(r)
+	}
+	return strings.Join(words, " ")
+}
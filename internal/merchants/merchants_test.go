@@ -0,0 +1,86 @@
+package merchants
+
+import (
+	"testing"
+
+	"finance-parser-go/internal/models"
+)
+
+func TestKey(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Swiggy", "swiggy"},
+		{"  SWIGGY*INSTAMART ", "swiggy"},
+		{"swiggy@axis", "swiggy"},
+		{"Tata-Starbucks, Pvt.", "tata starbucks pvt"},
+		{"*ORDER", "order"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Key(tt.in); got != tt.want {
+			t.Errorf("Key(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTidy(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"DMART", "Dmart"},
+		{"  BIG   BAZAAR ", "Big Bazaar"},
+		{"McDonald's", "McDonald's"},
+		{"KFC", "KFC"},
+	}
+	for _, tt := range tests {
+		if got := Tidy(tt.in); got != tt.want {
+			t.Errorf("Tidy(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	directory := Compile([]models.Merchant{
+		{Name: "Corner Bakery", Aliases: models.StringArray{"cb store"}, DefaultCategory: "Food"},
+		{Name: "Metro", Patterns: models.StringArray{`^dmrc\b`, `(`}},
+		// The user's own entry wins over the built-in one.
+		{Name: "Swiggy Food", Aliases: models.StringArray{"swiggy"}},
+	})
+	tests := []struct {
+		directory *Directory
+		raw, want string
+	}{
+		{directory, "corner bakery", "Corner Bakery"},
+		{directory, "CB-STORE", "Corner Bakery"},
+		{directory, "DMRC recharge 1234", "Metro"},
+		{directory, "SWIGGY*ORDER", "Swiggy Food"},
+		{nil, "swiggy@axis", "Swiggy"},
+		{nil, "Reliance Jio", "Jio"},
+		{nil, "Unknown Shop", ""},
+		{directory, "   ", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if m := Resolve(tt.directory, tt.raw); m != nil {
+			got = m.Name
+		}
+		if got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	if got := Canonical(nil, "AMZN"); got != "Amazon" {
+		t.Errorf("Canonical(AMZN) = %q", got)
+	}
+	if got := Canonical(nil, "RAJ GENERAL STORE"); got != "Raj General Store" {
+		t.Errorf("Canonical(RAJ GENERAL STORE) = %q", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(models.Merchant{Patterns: models.StringArray{`^uber\b`}}); err != nil {
+		t.Errorf("valid pattern: %v", err)
+	}
+	if err := Validate(models.Merchant{Patterns: models.StringArray{`(`}}); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+type Category struct {
+	ID            uint          `gorm:"primaryKey" json:"id"`
+	UserID        uint          `gorm:"index" json:"user_id"`
+	Name          string        `json:"name"`
+	Icon          string        `json:"icon"`
+	Color         string        `json:"color"`
+	SortOrder     int           `json:"sort_order"`
+	SubCategories []SubCategory `json:"sub_categories,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+type SubCategory struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"index" json:"user_id"`
+	CategoryID uint      `gorm:"index" json:"category_id"`
+	Name       string    `json:"name"`
+	Icon       string    `json:"icon"`
+	Color      string    `json:"color"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// DefaultCategories are seeded for a user the first time their categories are
+// read. The names match what the parser prompt used to hard-code.
+var DefaultCategories = []Category{
+	{Name: "Food", Icon: "food-outline", Color: "#F97316"},
+	{Name: "Travel", Icon: "car-outline", Color: "#3B82F6"},
+	{Name: "Shopping", Icon: "shopping-outline", Color: "#EC4899"},
+	{Name: "Bills", Icon: "receipt", Color: "#EAB308"},
+	{Name: "Family/Gifts", Icon: "gift-outline", Color: "#8B5CF6"},
+	{Name: "Misc", Icon: "dots-horizontal", Color: "#6B7280"},
+}
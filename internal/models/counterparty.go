@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Counterparty is a person (or business) the user lends money to or borrows from.
+type Counterparty struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	Name      string    `json:"name"`
+	Phone     string    `json:"phone"`
+	Notes     string    `json:"notes"`
+	Balance   float64   `json:"balance"` // positive: they owe the user, negative: the user owes them
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -16,6 +17,7 @@ type Entry struct {
 	Mode        string      `json:"mode"`
 	CardNetwork string      `json:"card_network"`
 	Category    string      `json:"category"`
+	SubCategory string      `json:"sub_category"`
 	Merchant    string      `json:"merchant"`
 	PurposeType string      `json:"purpose_type"`
 	Tag         string      `json:"tag"`
@@ -23,8 +25,15 @@ type Entry struct {
 	Notes       string      `json:"notes"`
 	Date        string      `json:"date"`
 	Time        string      `json:"time"`
+	OccurredAt  *time.Time  `gorm:"type:timestamptz;index" json:"occurred_at"` // Date+Time in the user's timezone
 	SourceText  string      `json:"source_text"`
 	Attachment  string      `json:"attachment"`
+	Draft       bool        `gorm:"default:false" json:"draft"` // created by ingestion, awaiting user review
+	AccountID   *uint       `gorm:"index" json:"account_id"`
+
+	ImportBatchID *uint  `gorm:"index" json:"import_batch_id,omitempty"`
+	ExternalID    string `gorm:"index" json:"external_id,omitempty"` // bank transaction ID (OFX FITID, CAMT reference)
+	ParseLogID    *uint  `gorm:"index" json:"parse_id,omitempty"`    // the parse whose draft this entry was saved from
 
 	UserID uint `json:"user_id"`
 	User   User `json:"-" gorm:"foreignKey:UserID"`
@@ -33,6 +42,21 @@ type Entry struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// ParseOccurredAt combines an entry's date (YYYY-MM-DD) and optional time
+// (HH:MM or HH:MM:SS) into an instant in loc.
+func ParseOccurredAt(date, clock string, loc *time.Location) (time.Time, error) {
+	date = strings.TrimSpace(date)
+	clock = strings.TrimSpace(clock)
+	if clock == "" {
+		return time.ParseInLocation("2006-01-02", date, loc)
+	}
+	layout := "2006-01-02 15:04"
+	if strings.Count(clock, ":") == 2 {
+		layout = "2006-01-02 15:04:05"
+	}
+	return time.ParseInLocation(layout, date+" "+clock, loc)
+}
+
 type StringArray []string
 
 func (sa StringArray) Value() (driver.Value, error) {
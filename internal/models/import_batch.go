@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ImportBatch groups the entries created by one committed import so the whole
+// import can be undone.
+type ImportBatch struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	Source    string    `json:"source"` // csv, ofx, qif, camt
+	Filename  string    `json:"filename"`
+	Rows      int       `json:"rows"`
+	Imported  int       `json:"imported"`
+	Skipped   int       `json:"skipped"`
+	Status    string    `json:"status"` // committed, undone
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
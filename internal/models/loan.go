@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+type Loan struct {
+	ID             uint            `gorm:"primaryKey" json:"id"`
+	UserID         uint            `gorm:"index" json:"user_id"`
+	CounterpartyID uint            `gorm:"index" json:"counterparty_id"`
+	EntryID        *uint           `json:"entry_id,omitempty"` // entry for the money given or received
+	Direction      string          `json:"direction"`          // lent, borrowed
+	Amount         float64         `json:"amount"`
+	Repaid         float64         `json:"repaid"`
+	Status         string          `json:"status"` // open, settled
+	Date           string          `json:"date"`
+	Notes          string          `json:"notes"`
+	Repayments     []LoanRepayment `json:"repayments,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// Outstanding is the amount still to be repaid on the loan.
+func (l *Loan) Outstanding() float64 {
+	return l.Amount - l.Repaid
+}
+
+type LoanRepayment struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	LoanID    uint      `gorm:"index" json:"loan_id"`
+	EntryID   *uint     `json:"entry_id,omitempty"` // entry for the repayment itself
+	Amount    float64   `json:"amount"`
+	Date      string    `json:"date"`
+	Notes     string    `json:"notes"`
+	CreatedAt time.Time `json:"created_at"`
+}
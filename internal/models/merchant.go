@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Merchant is a canonical merchant in the user's directory. Raw merchant
+// strings are mapped onto it through its aliases (exact, case-insensitive) and
+// patterns (regular expressions).
+type Merchant struct {
+	ID              uint        `gorm:"primaryKey" json:"id"`
+	UserID          uint        `gorm:"index" json:"user_id"`
+	Name            string      `json:"name"`
+	Aliases         StringArray `gorm:"type:jsonb" json:"aliases"`
+	Patterns        StringArray `gorm:"type:jsonb" json:"patterns"`
+	DefaultCategory string      `json:"default_category"`
+	Icon            string      `json:"icon"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// Migration records a one-time data migration that has already run, so it
+// is not repeated on the next start.
+type Migration struct {
+	Name  string    `gorm:"primaryKey" json:"name"`
+	RanAt time.Time `json:"ran_at"`
+}
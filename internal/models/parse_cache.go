@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// ParseCacheEntry holds a parser reply for a transcript so the same words
+// sent again the same day skip the LLM. Key hashes the user, normalised
+// transcript, local date and prompt version.
+type ParseCacheEntry struct {
+	Key       string       `gorm:"primaryKey;size:64" json:"key"`
+	UserID    uint         `gorm:"index" json:"user_id"`
+	Response  JSONDocument `gorm:"type:jsonb" json:"response"`
+	ExpiresAt time.Time    `gorm:"index" json:"expires_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// ParseFieldResult compares one field of a saved entry with the draft it
+// came from. Corrected means the user changed the parser's value.
+type ParseFieldResult struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ParseLogID uint      `gorm:"index" json:"parse_log_id"`
+	EntryID    uint      `gorm:"index" json:"entry_id"`
+	UserID     uint      `gorm:"index" json:"user_id"`
+	Field      string    `gorm:"size:32" json:"field"`
+	Corrected  bool      `json:"corrected"`
+	Parsed     string    `json:"parsed"`
+	Saved      string    `json:"saved"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// ParseLog records one parser call: what went in, what the model returned
+// and how long it took. Drafts carry its ID as parse_id so entries saved
+// from them can be compared with the model's output (see ParseFieldResult).
+type ParseLog struct {
+	ID               uint         `gorm:"primaryKey" json:"id"`
+	UserID           uint         `gorm:"index" json:"user_id"`
+	Source           string       `gorm:"size:16" json:"source"` // text, audio, stream, image, answer, email
+	Transcript       string       `json:"transcript"`            // the image URL for image parses
+	Provider         string       `gorm:"size:32" json:"provider"`
+	Model            string       `json:"model"`
+	PromptVersion    string       `gorm:"size:32;index" json:"prompt_version"`
+	CacheHit         bool         `json:"cache_hit"`
+	RawOutput        JSONDocument `gorm:"type:jsonb" json:"raw_output"` // null when the parser failed
+	Drafts           JSONDocument `gorm:"type:jsonb" json:"drafts"`     // after server-side clean-up
+	ValidationErrors StringArray  `gorm:"type:jsonb" json:"validation_errors"`
+	Error            string       `json:"error,omitempty"`
+	LatencyMs        int64        `json:"latency_ms"`
+	AudioMs          int64        `json:"audio_ms"` // length of the voice clip; 0 for text or when unknown
+	CreatedAt        time.Time    `gorm:"index" json:"created_at"`
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ParseSession keeps the drafts from one /v1/parse call so the user can
+// answer the parser's clarification questions. Sessions expire after
+// PARSE_SESSION_TTL_MINUTES without an answer.
+type ParseSession struct {
+	ID            string       `gorm:"primaryKey;size:36" json:"id"`
+	UserID        uint         `gorm:"index" json:"user_id"`
+	Transcript    string       `json:"transcript"`
+	Timezone      string       `json:"timezone"`
+	PromptVersion string       `gorm:"size:32" json:"prompt_version"` // parse prompt the drafts came from
+	Drafts        JSONDocument `gorm:"type:jsonb" json:"drafts"`
+	History       ParseTurns   `gorm:"type:jsonb" json:"history"`
+	ExpiresAt     time.Time    `gorm:"index" json:"expires_at"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+// ParseTurn records one answer and the questions it replied to.
+type ParseTurn struct {
+	Index     *int      `json:"index,omitempty"` // draft answered; nil means every pending draft
+	Questions []string  `json:"questions"`
+	Answer    string    `json:"answer"`
+	At        time.Time `json:"at"`
+}
+
+type ParseTurns []ParseTurn
+
+func (t ParseTurns) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(t)
+}
+
+func (t *ParseTurns) Scan(value interface{}) error {
+	data, err := scanJSON(value)
+	if err != nil || len(data) == 0 {
+		*t = nil
+		return err
+	}
+	return json.Unmarshal(data, t)
+}
+
+// JSONDocument stores an arbitrary JSON value as jsonb.
+type JSONDocument json.RawMessage
+
+func (d JSONDocument) Value() (driver.Value, error) {
+	if len(d) == 0 {
+		return []byte("null"), nil
+	}
+	return []byte(d), nil
+}
+
+func (d *JSONDocument) Scan(value interface{}) error {
+	data, err := scanJSON(value)
+	if err != nil {
+		return err
+	}
+	*d = append((*d)[:0], data...)
+	return nil
+}
+
+func (d JSONDocument) MarshalJSON() ([]byte, error) {
+	if len(d) == 0 {
+		return []byte("null"), nil
+	}
+	return d, nil
+}
+
+func (d *JSONDocument) UnmarshalJSON(b []byte) error {
+	*d = append((*d)[:0], b...)
+	return nil
+}
+
+func scanJSON(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+	return nil, fmt.Errorf("unsupported type for JSON column: %T", value)
+}
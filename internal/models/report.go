@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Report is a generated monthly statement kept on disk for later download.
+type Report struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	Month     string    `json:"month"`  // YYYY-MM
+	Format    string    `json:"format"` // html, pdf
+	Filename  string    `json:"filename"`
+	Path      string    `json:"-"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
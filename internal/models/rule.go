@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Rule auto-categorises entries. All non-empty conditions must match; the
+// actions are then applied in order of ascending Priority.
+type Rule struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	UserID   uint   `gorm:"index" json:"user_id"`
+	Name     string `json:"name"`
+	Priority int    `json:"priority"` // lower runs first
+	Enabled  bool   `json:"enabled"`  // saveRule defaults it to true
+	Stop     bool   `json:"stop"`     // skip lower-priority rules once this one matches
+
+	// Conditions
+	MerchantContains string   `json:"merchant_contains"`
+	TitleContains    string   `json:"title_contains"`
+	MinAmount        *float64 `json:"min_amount"`
+	MaxAmount        *float64 `json:"max_amount"`
+	Mode             string   `json:"mode"`
+	AccountID        *uint    `json:"account_id"`
+
+	// Actions
+	SetCategory    string      `json:"set_category"`
+	AddTags        StringArray `gorm:"type:jsonb" json:"add_tags"`
+	SetMerchant    string      `json:"set_merchant"`
+	SetAccountID   *uint       `json:"set_account_id"`
+	SetPurposeType string      `json:"set_purpose_type"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// UsageDay tallies one user's parser and speech-to-text use for one day in
+// their timezone. Daily quotas are checked against it.
+type UsageDay struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	UserID           uint      `gorm:"uniqueIndex:idx_usage_user_day" json:"user_id"`
+	Day              string    `gorm:"size:10;uniqueIndex:idx_usage_user_day" json:"day"` // YYYY-MM-DD
+	Parses           int       `json:"parses"`
+	Transcriptions   int       `json:"transcriptions"`
+	CacheHits        int       `json:"cache_hits"`
+	LLMCalls         int       `json:"llm_calls"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
@@ -15,6 +15,8 @@ type User struct {
 	IsGuest           bool      `gorm:"default:false" json:"is_guest"`
 	Username          string    `gorm:"uniqueIndex" json:"username"` // Unique username
 	ProfileImage      string    `json:"profile_image"`
+	Timezone          string    `json:"timezone"`               // IANA name, e.g. Asia/Kolkata
+	CategoriesSeeded  bool      `gorm:"default:false" json:"-"` // default categories were created once
 	CreatedAt         time.Time `json:"created_at"`
 	UpdatedAt         time.Time `json:"updated_at"`
 	HasPin            bool      `gorm:"-" json:"has_pin"`
@@ -0,0 +1,346 @@
+// Package numwords rewrites spoken amounts in English, Hindi and Hinglish
+// transcripts as digits, e.g. "kal do sau pachas ka petrol" becomes "kal
+// 250 ka petrol" and "1.5 lakh salary" becomes "150000 salary". Devanagari
+// numerals and words are handled too, so Whisper output in either script
+// reaches the parser in the same shape.
+package numwords
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Text is a normalised transcript that remembers what it was rewritten from.
+type Text struct {
+	Original string
+	Text     string
+	edits    []edit
+}
+
+// edit is one rewritten span: Original[from:to] became Text[at:end].
+type edit struct{ from, to, at, end int }
+
+// Changed reports whether any amount was rewritten.
+func (t *Text) Changed() bool { return len(t.edits) > 0 }
+
+// Source returns the words of the original transcript that s, a part of
+// Text, was made from, so a draft's source_text can quote what the user
+// actually said. s is returned unchanged when it isn't part of Text.
+func (t *Text) Source(s string) string {
+	if len(t.edits) == 0 || s == "" {
+		return s
+	}
+	i := strings.Index(t.Text, s)
+	if i < 0 {
+		return s
+	}
+	from, to := t.toOriginal(i, true), t.toOriginal(i+len(s), false)
+	if from >= to {
+		return s
+	}
+	return strings.TrimSpace(t.Original[from:to])
+}
+
+// toOriginal maps an offset in Text to one in Original. Offsets inside a
+// rewritten number snap to its start or end.
+func (t *Text) toOriginal(p int, start bool) int {
+	delta := 0
+	for _, e := range t.edits {
+		if p <= e.at {
+			break
+		}
+		if p < e.end {
+			if start {
+				return e.from
+			}
+			return e.to
+		}
+		delta += (e.to - e.from) - (e.end - e.at)
+	}
+	return p + delta
+}
+
+type kind int
+
+const (
+	value      kind = iota // a number: "pachas", "fifty", "50"
+	multiplier             // "sau", "hazaar", "lakh", "k"
+	prefix                 // "saade", "sawa", "paune": adjusts the next value
+	connector              // "and"/"aur" between a multiplier and a value
+)
+
+type word struct {
+	kind kind
+	n    float64
+	// ambiguous words are also ordinary words ("do", "saath" = with) and
+	// only count inside a longer number.
+	ambiguous bool
+	// bare multipliers may stand alone: "sau rupaye" is 100.
+	bare bool
+}
+
+var words = map[string]word{}
+
+func add(k kind, n float64, names ...string) {
+	for _, name := range names {
+		words[name] = word{kind: k, n: n}
+	}
+}
+
+func init() {
+	add(value, 0, "zero", "shunya", "शून्य")
+	add(value, 1, "ek", "एक")
+	add(value, 2, "two", "दो")
+	add(value, 3, "three", "तीन")
+	add(value, 4, "chaar", "four", "चार")
+	add(value, 5, "paanch", "panch", "five", "पांच", "पाँच")
+	add(value, 6, "chhah", "six", "छह", "छः")
+	add(value, 7, "saat", "seven", "सात")
+	add(value, 8, "aath", "eight", "आठ")
+	add(value, 9, "nine", "नौ")
+	add(value, 10, "das", "ten", "दस")
+	add(value, 11, "gyarah", "gyara", "eleven", "ग्यारह")
+	add(value, 12, "barah", "twelve", "बारह")
+	add(value, 13, "terah", "thirteen", "तेरह")
+	add(value, 14, "chaudah", "fourteen", "चौदह")
+	add(value, 15, "pandrah", "fifteen", "पंद्रह", "पन्द्रह")
+	add(value, 16, "solah", "sixteen", "सोलह")
+	add(value, 17, "satrah", "seventeen", "सत्रह")
+	add(value, 18, "atharah", "athara", "eighteen", "अठारह")
+	add(value, 19, "unnis", "unees", "nineteen", "उन्नीस")
+	add(value, 20, "twenty", "बीस")
+	add(value, 30, "thirty")
+	add(value, 40, "forty")
+	add(value, 50, "fifty")
+	add(value, 60, "sixty")
+	add(value, 70, "seventy")
+	add(value, 80, "eighty")
+	add(value, 90, "ninety")
+	// Hindi has a word for every number up to 99.
+	for n, names := range hindi {
+		add(value, float64(n), strings.Fields(names)...)
+	}
+	add(value, 1.5, "dedh", "डेढ")
+	add(value, 2.5, "dhai", "dhaai", "adhai", "ढाई")
+
+	for name, n := range map[string]float64{"one": 1, "do": 2, "teen": 3, "char": 4, "chhe": 6, "che": 6, "nau": 9, "bees": 20, "tees": 30, "saath": 60} {
+		words[name] = word{kind: value, n: n, ambiguous: true}
+	}
+
+	add(prefix, 0.5, "saade", "sade", "saadhe", "साढे")
+	add(prefix, 0.25, "sawa", "sava", "सवा")
+	add(prefix, -0.25, "paune", "pone", "पौने")
+
+	add(multiplier, 1e5, "lakh", "lakhs", "lac", "lacs", "laakh", "लाख")
+	add(multiplier, 1e6, "million")
+	add(multiplier, 1e7, "crore", "crores", "karod", "karor", "करोड")
+	for _, name := range []string{"sau", "hundred", "सौ"} {
+		words[name] = word{kind: multiplier, n: 100, bare: true}
+	}
+	for _, name := range []string{"hazaar", "hazar", "hajar", "hajaar", "thousand", "हजार"} {
+		words[name] = word{kind: multiplier, n: 1000, bare: true}
+	}
+	// Abbreviations only count after a number: "5 k", "2 cr".
+	words["k"] = word{kind: multiplier, n: 1000, ambiguous: true}
+	words["cr"] = word{kind: multiplier, n: 1e7, ambiguous: true}
+
+	add(connector, 0, "and", "aur", "और")
+}
+
+// hindi spells 21 to 99 in Hinglish and Devanagari (without nukta, see
+// lookup). 30 and 60 are among the ambiguous words.
+var hindi = [100]string{
+	21: "ikkis ikkees इक्कीस", 22: "bais baees बाईस", 23: "teis teees तेईस", 24: "chaubis chaubees चौबीस",
+	25: "pachis pachchis pachees पच्चीस", 26: "chhabbis chabbis छब्बीस", 27: "sattais sattaees सत्ताईस",
+	28: "athais atthais अट्ठाईस", 29: "untis unattis उनतीस", 30: "तीस",
+	31: "iktis ikattis इकतीस", 32: "battis बत्तीस", 33: "tetis taintis तैंतीस", 34: "chautis chauntis चौंतीस",
+	35: "paintis पैंतीस", 36: "chhattis chattis छत्तीस", 37: "saintis सैंतीस", 38: "adtis artis अडतीस",
+	39: "untalis उनतालीस", 40: "chalis chaalis चालीस",
+	41: "iktalis इकतालीस", 42: "bayalis बयालीस", 43: "taintalis तैंतालीस", 44: "chavalis chauvalis चवालीस",
+	45: "paintalis पैंतालीस", 46: "chhiyalis छियालीस", 47: "saintalis सैंतालीस", 48: "adtalis artalis अडतालीस",
+	49: "unchas उनचास", 50: "pachas pachaas पचास",
+	51: "ikyavan ikyawan इक्यावन", 52: "bavan baawan बावन", 53: "tirepan tirpan तिरेपन", 54: "chauvan chauwan चौवन",
+	55: "pachpan पचपन", 56: "chhappan chappan छप्पन", 57: "sattavan sattawan सत्तावन", 58: "atthavan athawan अट्ठावन",
+	59: "unsath उनसठ", 60: "साठ",
+	61: "iksath इकसठ", 62: "basath baasath बासठ", 63: "tirsath tiresath तिरेसठ", 64: "chaunsath चौंसठ",
+	65: "painsath पैंसठ", 66: "chhiyasath छियासठ", 67: "sadsath सडसठ", 68: "adsath अडसठ",
+	69: "unhattar उनहत्तर", 70: "sattar सत्तर",
+	71: "ikhattar इकहत्तर", 72: "bahattar बहत्तर", 73: "tihattar तिहत्तर", 74: "chauhattar चौहत्तर",
+	75: "pachhattar pachattar पचहत्तर", 76: "chhihattar छिहत्तर", 77: "satattar sathattar सतहत्तर", 78: "athattar अठहत्तर",
+	79: "unasi unnasi उन्यासी", 80: "assi assee अस्सी",
+	81: "ikyasi इक्यासी", 82: "bayasi बयासी", 83: "tirasi तिरासी", 84: "chaurasi चौरासी",
+	85: "pachasi पचासी", 86: "chhiyasi छियासी", 87: "sattasi सत्तासी", 88: "atthasi athasi अट्ठासी",
+	89: "navasi नवासी", 90: "nabbe nabbey नब्बे",
+	91: "ikyanve इक्यानवे", 92: "baanve banve बानवे", 93: "tiranve तिरानवे", 94: "chauranve चौरानवे",
+	95: "pachanve पचानवे", 96: "chhiyanve छियानवे", 97: "sattanve सत्तानवे", 98: "atthanve अट्ठानवे",
+	99: "ninyanve निन्यानवे",
+}
+
+var (
+	tokenRe     = regexp.MustCompile(`[0-9०-९]+(?:[.,][0-9०-९]+)*(?:[kK]\b)?|[\p{L}\p{M}]+`)
+	devanagari  = strings.NewReplacer("०", "0", "१", "1", "२", "2", "३", "3", "४", "4", "५", "5", "६", "6", "७", "7", "८", "8", "९", "9")
+	nukta       = strings.NewReplacer("़", "", "ज़", "ज", "ड़", "ड", "ढ़", "ढ")
+	joinerRe    = regexp.MustCompile(`^[\s-]*$`)
+	asciiNumber = regexp.MustCompile(`^[0-9.,]+$`)
+	// numberLike matches endings of Hindi number words, to spot misspelt
+	// ones that are missing from the table ("chhatis", "paintees").
+	numberLike = regexp.MustCompile(`(?i)^[a-z]{3,}(?:is|ees|iis|tis|lis|van|wan|pan|sath|sat|hattar|attar|asi|ase|anve|anwe|nave)$|(?:ीस|ालीस|ावन|पन|सठ|हत्तर|ासी|ानवे)$`)
+)
+
+type token struct {
+	start, end int
+	raw        string
+	w          word
+	digits     bool
+}
+
+// Normalize rewrites the spoken amounts in s as digits.
+func Normalize(s string) *Text {
+	var toks []token
+	for _, m := range tokenRe.FindAllStringIndex(s, -1) {
+		raw := s[m[0]:m[1]]
+		if t, ok := lookup(raw); ok {
+			t.start, t.end, t.raw = m[0], m[1], raw
+			toks = append(toks, t)
+		} else {
+			toks = append(toks, token{start: m[0], end: m[1], raw: raw, w: word{kind: -1}})
+		}
+	}
+
+	out := &Text{Original: s}
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(toks); {
+		n, j, ok := readNumber(s, toks, i)
+		if !ok {
+			i++
+			continue
+		}
+		// "chhatis hazaar" is not "chhatis 1000": next to a number word
+		// we don't know, the whole phrase is left as spoken.
+		if unknownNumberWord(s, toks, i-1, i) || unknownNumberWord(s, toks, j, j-1) {
+			i = j
+			continue
+		}
+		from, to := toks[i].start, toks[j-1].end
+		digits := strconv.FormatFloat(n, 'f', -1, 64)
+		b.WriteString(s[last:from])
+		at := b.Len()
+		b.WriteString(digits)
+		out.edits = append(out.edits, edit{from: from, to: to, at: at, end: b.Len()})
+		last = to
+		i = j
+	}
+	b.WriteString(s[last:])
+	out.Text = b.String()
+	return out
+}
+
+// unknownNumberWord reports whether toks[k] is an unrecognised word that
+// looks like a number word and is joined to toks[next], its neighbour.
+func unknownNumberWord(s string, toks []token, k, next int) bool {
+	if k < 0 || k >= len(toks) || toks[k].w.kind >= 0 {
+		return false
+	}
+	from, to := toks[k].end, toks[next].start
+	if k > next {
+		from, to = toks[next].end, toks[k].start
+	}
+	return joinerRe.MatchString(s[from:to]) && numberLike.MatchString(toks[k].raw)
+}
+
+func lookup(raw string) (token, bool) {
+	lower := strings.ToLower(raw)
+	if lower[0] >= '0' && lower[0] <= '9' || strings.ContainsAny(raw, "०१२३४५६७८९") {
+		n, ok := parseDigits(lower)
+		return token{w: word{kind: value, n: n}, digits: true}, ok
+	}
+	if w, ok := words[nukta.Replace(lower)]; ok {
+		return token{w: w}, true
+	}
+	return token{}, false
+}
+
+func parseDigits(s string) (float64, bool) {
+	mult := 1.0
+	if strings.HasSuffix(s, "k") {
+		mult, s = 1000, strings.TrimSuffix(s, "k")
+	}
+	s = strings.ReplaceAll(devanagari.Replace(s), ",", "")
+	n, err := strconv.ParseFloat(s, 64)
+	return n * mult, err == nil
+}
+
+// readNumber reads the longest number starting at toks[i] and returns its
+// value and the index after it. ok is false when there is nothing worth
+// rewriting there: plain digits, or a lone word that is also ordinary
+// speech. Ambiguous words never combine with digits: in "saath 5 samose"
+// saath is "with", not 60.
+func readNumber(s string, toks []token, i int) (float64, int, bool) {
+	var total, current, adjust, lastValue, n float64
+	last := kind(-1)
+	end, spoken, rewrite := i, 0, false
+	digits, ambiguous := false, false
+
+loop:
+	for j := i; j < len(toks); j++ {
+		t := toks[j]
+		if t.w.kind < 0 || (j > i && !joinerRe.MatchString(s[toks[j-1].end:t.start])) {
+			break
+		}
+		switch t.w.kind {
+		case value:
+			if t.digits && ambiguous || t.w.ambiguous && digits {
+				break loop
+			}
+			digits, ambiguous = digits || t.digits, ambiguous || t.w.ambiguous
+			v := t.w.n + adjust
+			// "twenty five" is one number; "200 do" is not.
+			if last == value && !(lastValue >= 20 && lastValue < 100 && int(lastValue)%10 == 0 && v < 10) {
+				break loop
+			}
+			current += v
+			adjust, lastValue = 0, v
+		case multiplier:
+			if last != value && !(j == i && t.w.bare) {
+				break loop
+			}
+			if current == 0 {
+				current = 1
+			}
+			if t.w.n == 100 {
+				current *= 100
+			} else {
+				total += current * t.w.n
+				current = 0
+			}
+		case prefix:
+			if last == prefix {
+				break loop
+			}
+			adjust = t.w.n
+		case connector:
+			if last != multiplier {
+				break loop
+			}
+		}
+		last = t.w.kind
+
+		switch {
+		case !t.digits:
+			spoken++
+			rewrite = rewrite || !t.w.ambiguous
+		case !asciiNumber.MatchString(t.raw):
+			rewrite = true // Devanagari digits or a "k" suffix
+		}
+		if last == value || last == multiplier {
+			end, n = j+1, total+current
+		}
+	}
+
+	if end == i || !(rewrite || spoken > 0 && end-i > 1) {
+		return 0, i, false
+	}
+	return n, end, true
+}
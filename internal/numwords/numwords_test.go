@@ -0,0 +1,57 @@
+package numwords
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"kal do sau pachas ka petrol", "kal 250 ka petrol"},
+		{"1.5 lakh salary", "150000 salary"},
+		{"sau rupaye chai", "100 rupaye chai"},
+		{"saade teen hazaar rent", "3500 rent"},
+		{"dhai hazaar", "2500"},
+		{"twenty five for tea", "25 for tea"},
+		{"5 k to Rahul", "5000 to Rahul"},
+		{"२५० का पेट्रोल", "250 का पेट्रोल"},
+		{"दो सौ पचास", "250"},
+
+		// Every Hindi number up to 99 is known.
+		{"ikkis sau ka bill", "2100 ka bill"},
+		{"chhattis hazaar", "36000"},
+		{"pachpan", "55"},
+		{"ek sau ikkis", "121"},
+		{"paanch sau bais", "522"},
+		{"निन्यानवे रुपये", "99 रुपये"},
+
+		// Next to a number word that isn't known, nothing is rewritten.
+		{"chhatis hazaar", "chhatis hazaar"},
+		{"do sau chhatis", "do sau chhatis"},
+
+		// Ambiguous words stay words on their own and next to digits.
+		{"dost ke saath 5 samose", "dost ke saath 5 samose"},
+		{"do log saath me", "do log saath me"},
+		{"200 do", "200 do"},
+		{"paid 450", "paid 450"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in).Text; got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSource(t *testing.T) {
+	n := Normalize("kal do sau pachas ka petrol aur dhai hazaar rent")
+	if !n.Changed() {
+		t.Fatal("nothing rewritten")
+	}
+	tests := []struct{ in, want string }{
+		{"250 ka petrol", "do sau pachas ka petrol"},
+		{"2500 rent", "dhai hazaar rent"},
+		{"not in the text", "not in the text"},
+	}
+	for _, tt := range tests {
+		if got := n.Source(tt.in); got != tt.want {
+			t.Errorf("Source(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package report
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+)
+
+// The PDF is written by hand using the standard Helvetica fonts, which every
+// viewer ships, so no font files or cgo libraries are needed. Text is limited
+// to WinAnsi; the rupee sign becomes "Rs.".
+
+const (
+	pageWidth  = 595.0 // A4 in points
+	pageHeight = 842.0
+	margin     = 40.0
+)
+
+// helveticaWidths holds glyph widths (per 1000 em) for ASCII 32..126 from the
+// Helvetica AFM. Bold is close enough for layout when scaled by boldFactor.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+const boldFactor = 1.06
+
+func textWidth(s string, size float64, bold bool) float64 {
+	w := 0
+	for _, b := range []byte(winAnsi(s)) {
+		if b >= 32 && b <= 126 {
+			w += helveticaWidths[b-32]
+		} else {
+			w += 556
+		}
+	}
+	f := float64(w) * size / 1000
+	if bold {
+		f *= boldFactor
+	}
+	return f
+}
+
+// winAnsi maps text onto the single-byte encoding of the standard fonts.
+func winAnsi(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '₹':
+			b.WriteString("Rs.")
+		case r == '\n' || r == '\t':
+			b.WriteByte(' ')
+		case r >= 32 && r <= 126, r >= 160 && r <= 255:
+			b.WriteByte(byte(r))
+		case r == '–' || r == '—':
+			b.WriteByte('-')
+		case r == '‘' || r == '’':
+			b.WriteByte('\'')
+		case r == '“' || r == '”':
+			b.WriteByte('"')
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+func escapePDF(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`).Replace(winAnsi(s))
+}
+
+// fit shortens s with an ellipsis so it fits in width points.
+func fit(s string, size, width float64, bold bool) string {
+	if textWidth(s, size, bold) <= width {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && textWidth(string(r)+"...", size, bold) > width {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "..."
+}
+
+// pdfDoc collects page content streams and assembles the file on Bytes.
+type pdfDoc struct {
+	pages []*bytes.Buffer
+	cur   *bytes.Buffer
+	y     float64
+}
+
+func (p *pdfDoc) newPage() {
+	p.cur = &bytes.Buffer{}
+	p.pages = append(p.pages, p.cur)
+	p.y = pageHeight - margin
+}
+
+// need starts a new page when fewer than h points are left.
+func (p *pdfDoc) need(h float64) bool {
+	if p.y-h < margin {
+		p.newPage()
+		return true
+	}
+	return false
+}
+
+func (p *pdfDoc) text(x, y float64, s string, size float64, bold bool, gray float64) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(p.cur, "%.3f g BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", gray, font, size, x, y, escapePDF(s))
+}
+
+func (p *pdfDoc) rect(x, y, w, h, gray float64) {
+	fmt.Fprintf(p.cur, "%.3f g %.2f %.2f %.2f %.2f re f\n", gray, x, y, w, h)
+}
+
+func (p *pdfDoc) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(p.cur, "0.85 G 0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+// column describes one table column; right-aligned columns hold amounts.
+type column struct {
+	header string
+	width  float64
+	right  bool
+}
+
+const (
+	rowHeight = 16.0
+	cellPad   = 4.0
+	bodySize  = 9.0
+)
+
+func (p *pdfDoc) heading(s string) {
+	p.need(40)
+	p.y -= 22
+	p.text(margin, p.y, s, 13, true, 0.1)
+	p.y -= 8
+}
+
+func (p *pdfDoc) tableHeader(cols []column) {
+	p.y -= rowHeight
+	p.rect(margin, p.y-4, pageWidth-2*margin, rowHeight, 0.93)
+	p.row(cols, headers(cols), true)
+}
+
+func headers(cols []column) []string {
+	out := make([]string, len(cols))
+	for i, c := range cols {
+		out[i] = c.header
+	}
+	return out
+}
+
+func (p *pdfDoc) row(cols []column, cells []string, bold bool) {
+	x := margin
+	for i, c := range cols {
+		s := fit(cells[i], bodySize, c.width-2*cellPad, bold)
+		tx := x + cellPad
+		if c.right {
+			tx = x + c.width - cellPad - textWidth(s, bodySize, bold)
+		}
+		p.text(tx, p.y, s, bodySize, bold, 0.12)
+		x += c.width
+	}
+}
+
+// table draws a titled table, repeating the header row on each new page.
+func (p *pdfDoc) table(title string, cols []column, rows [][]string, empty string) {
+	p.heading(title)
+	p.tableHeader(cols)
+	if len(rows) == 0 {
+		rows = [][]string{append([]string{empty}, make([]string, len(cols)-1)...)}
+	}
+	for _, r := range rows {
+		if p.need(rowHeight + 4) {
+			p.tableHeader(cols)
+		}
+		p.y -= rowHeight
+		p.row(cols, r, false)
+		p.line(margin, p.y-5, pageWidth-margin, p.y-5)
+	}
+}
+
+// PDF renders the statement as an A4 document.
+func PDF(d *Data) ([]byte, error) {
+	p := &pdfDoc{}
+	p.newPage()
+
+	p.y -= 10
+	p.text(margin, p.y, d.Title(), 20, true, 0.1)
+	p.y -= 16
+	meta := "Generated " + d.GeneratedAt.Format("02 Jan 2006 15:04 MST")
+	if d.UserName != "" {
+		meta = d.UserName + "  -  " + meta
+	}
+	p.text(margin, p.y, meta, 9, false, 0.45)
+
+	// Summary cards.
+	p.y -= 58
+	cards := []struct{ label, value string }{
+		{"INCOME", "Rs. " + money(d.Income)},
+		{"SPENT", "Rs. " + money(d.Spent)},
+		{"SAVINGS", "Rs. " + money(d.Savings)},
+		{"SAVINGS RATE", percent(d.SavingsRate)},
+	}
+	cardW := (pageWidth - 2*margin - 3*10) / 4
+	for i, card := range cards {
+		x := margin + float64(i)*(cardW+10)
+		p.rect(x, p.y, cardW, 46, 0.95)
+		p.text(x+8, p.y+30, card.label, 7.5, false, 0.45)
+		p.text(x+8, p.y+12, fit(card.value, 12, cardW-16, true), 12, true, 0.1)
+	}
+
+	var rows [][]string
+	for _, c := range d.Categories {
+		rows = append(rows, []string{c.Label, "Rs. " + money(c.Amount), percent(c.Percent)})
+	}
+	p.table("Spending by category", []column{{"Category", 315, false}, {"Amount", 120, true}, {"Share", 80, true}}, rows, "No spending this month.")
+
+	rows = nil
+	for _, m := range d.Merchants {
+		rows = append(rows, []string{m.Label, fmt.Sprint(m.Count), "Rs. " + money(m.Amount)})
+	}
+	p.table("Top merchants", []column{{"Merchant", 315, false}, {"Transactions", 80, true}, {"Amount", 120, true}}, rows, "No merchant spending this month.")
+
+	rows = nil
+	for _, a := range d.Accounts {
+		rows = append(rows, []string{a.Name, fmt.Sprint(a.Count), "Rs. " + money(a.Income), "Rs. " + money(a.Spent)})
+	}
+	p.table("Accounts", []column{{"Account", 215, false}, {"Transactions", 80, true}, {"Income", 110, true}, {"Spent", 110, true}}, rows, "No transactions this month.")
+
+	rows = nil
+	for _, t := range d.Transactions {
+		desc := t.Title
+		if t.Merchant != "" && t.Merchant != t.Title {
+			desc += " (" + t.Merchant + ")"
+		}
+		amount := "Rs. " + money(t.Amount)
+		if strings.EqualFold(t.Type, "expense") {
+			amount = "-" + amount
+		}
+		rows = append(rows, []string{displayDate(t.Date), desc, t.Category, t.Account, amount})
+	}
+	p.table("Transactions", []column{{"Date", 62, false}, {"Description", 188, false}, {"Category", 90, false}, {"Account", 85, false}, {"Amount", 90, true}}, rows, "No transactions this month.")
+
+	return p.bytes()
+}
+
+// bytes writes the catalog, fonts and one page object plus compressed
+// content stream per page, followed by the cross-reference table.
+func (p *pdfDoc) bytes() ([]byte, error) {
+	var out bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1-4 are fixed; pages start at 5 as (page, content) pairs.
+	kids := make([]string, len(p.pages))
+	for i := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, content := range p.pages {
+		// Page footer.
+		fmt.Fprintf(content, "0.55 g BT /F1 8 Tf %.2f %.2f Td (Page %d of %d) Tj ET\n", pageWidth-margin-50, margin/2, i+1, len(p.pages))
+
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		if _, err := zw.Write(content.Bytes()); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 6+2*i))
+		obj(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", z.Len(), z.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes(), nil
+}
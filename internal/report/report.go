@@ -0,0 +1,88 @@
+// Package report renders a monthly statement as HTML or PDF. The caller
+// fills Data from the insights aggregation; this package only lays it out.
+package report
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"finance-parser-go/internal/export"
+)
+
+type Line struct {
+	Label   string  `json:"label"`
+	Amount  float64 `json:"amount"`
+	Percent float64 `json:"percent"`
+	Count   int     `json:"count"`
+}
+
+type AccountSummary struct {
+	Name   string  `json:"name"`
+	Type   string  `json:"type"`
+	Income float64 `json:"income"`
+	Spent  float64 `json:"spent"`
+	Count  int     `json:"count"`
+}
+
+type Transaction struct {
+	Date     string  `json:"date"`
+	Title    string  `json:"title"`
+	Category string  `json:"category"`
+	Merchant string  `json:"merchant"`
+	Account  string  `json:"account"`
+	Type     string  `json:"type"`
+	Amount   float64 `json:"amount"`
+}
+
+// Data is everything shown on one monthly statement.
+type Data struct {
+	UserName     string
+	Month        time.Time
+	GeneratedAt  time.Time
+	Income       float64
+	Spent        float64
+	Savings      float64
+	SavingsRate  float64
+	Categories   []Line
+	Merchants    []Line
+	Accounts     []AccountSummary
+	Transactions []Transaction
+}
+
+// Title is the heading used on both renderings.
+func (d *Data) Title() string {
+	return "Statement for " + d.Month.Format("January 2006")
+}
+
+// Amounts are written with Indian digit grouping to match the app.
+var amountLocale, _ = export.LookupLocale("en-IN")
+
+func money(v float64) string { return amountLocale.FormatAmount(v) }
+
+func percent(v float64) string { return fmt.Sprintf("%.1f%%", v) }
+
+func displayDate(date string) string { return amountLocale.FormatDate(date) }
+
+//go:embed statement.html.tmpl
+var statementTemplate string
+
+var htmlTemplate = template.Must(template.New("statement").Funcs(template.FuncMap{
+	"money":   money,
+	"percent": percent,
+	"date":    displayDate,
+	"lower":   strings.ToLower,
+}).Parse(statementTemplate))
+
+// HTML renders a self-contained page with inline styles so the file can be
+// mailed or opened offline.
+func HTML(d *Data) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, d); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
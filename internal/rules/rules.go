@@ -0,0 +1,129 @@
+package rules
+
+import (
+	"sort"
+	"strings"
+
+	"finance-parser-go/internal/models"
+)
+
+// Change is a single field rewritten by a rule.
+type Change struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// Sorted returns the enabled rules in the order they should run.
+func Sorted(rules []models.Rule) []models.Rule {
+	out := make([]models.Rule, 0, len(rules))
+	for _, r := range rules {
+		if r.Enabled {
+			out = append(out, r)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Priority != out[j].Priority {
+			return out[i].Priority < out[j].Priority
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Matches reports whether every condition set on r holds for e.
+func Matches(r models.Rule, e *models.Entry) bool {
+	if r.MerchantContains != "" && !containsFold(e.Merchant, r.MerchantContains) {
+		return false
+	}
+	if r.TitleContains != "" && !containsFold(e.Title, r.TitleContains) {
+		return false
+	}
+	if r.MinAmount != nil && e.Amount < *r.MinAmount {
+		return false
+	}
+	if r.MaxAmount != nil && e.Amount > *r.MaxAmount {
+		return false
+	}
+	if r.Mode != "" && !strings.EqualFold(e.Mode, r.Mode) {
+		return false
+	}
+	if r.AccountID != nil && (e.AccountID == nil || *e.AccountID != *r.AccountID) {
+		return false
+	}
+	return true
+}
+
+// Apply runs rules against e in priority order, mutating it, and returns the
+// IDs of the rules that matched.
+func Apply(rules []models.Rule, e *models.Entry) []uint {
+	var applied []uint
+	for _, r := range Sorted(rules) {
+		if !Matches(r, e) {
+			continue
+		}
+		if r.SetCategory != "" {
+			e.Category = r.SetCategory
+		}
+		if r.SetMerchant != "" {
+			e.Merchant = r.SetMerchant
+		}
+		if r.SetPurposeType != "" {
+			e.PurposeType = r.SetPurposeType
+		}
+		if r.SetAccountID != nil {
+			id := *r.SetAccountID
+			e.AccountID = &id
+		}
+		for _, t := range r.AddTags {
+			if !hasTag(e.Tags, t) {
+				e.Tags = append(e.Tags, t)
+			}
+		}
+		applied = append(applied, r.ID)
+		if r.Stop {
+			break
+		}
+	}
+	return applied
+}
+
+// Diff lists the rule-controlled fields that differ between before and after.
+func Diff(before, after *models.Entry) map[string]Change {
+	changes := map[string]Change{}
+	if before.Category != after.Category {
+		changes["category"] = Change{before.Category, after.Category}
+	}
+	if before.Merchant != after.Merchant {
+		changes["merchant"] = Change{before.Merchant, after.Merchant}
+	}
+	if before.PurposeType != after.PurposeType {
+		changes["purpose_type"] = Change{before.PurposeType, after.PurposeType}
+	}
+	if !sameAccount(before.AccountID, after.AccountID) {
+		changes["account_id"] = Change{before.AccountID, after.AccountID}
+	}
+	if len(before.Tags) != len(after.Tags) {
+		changes["tags"] = Change{before.Tags, after.Tags}
+	}
+	return changes
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameAccount(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,210 @@
+// Package sms extracts transactions from Indian bank, card and UPI alert SMSes
+// without calling out to an LLM. Rather than one template per bank, each
+// field (amount, direction, account, counterparty, reference, date) has its
+// own small pattern list, which copes with the wording drift between banks.
+// testdata/corpus.json holds sample messages with the fields expected from
+// Parse.
+package sms
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotTransaction is returned for OTPs, promotions and other messages that
+// do not describe money moving.
+var ErrNotTransaction = errors.New("not a transaction alert")
+
+type Message struct {
+	Amount       float64 `json:"amount"`
+	Currency     string  `json:"currency"`
+	Direction    string  `json:"direction"` // debit, credit
+	AccountLast4 string  `json:"account_last4,omitempty"`
+	Merchant     string  `json:"merchant,omitempty"`
+	VPA          string  `json:"vpa,omitempty"`
+	Reference    string  `json:"reference,omitempty"`
+	Date         string  `json:"date"` // YYYY-MM-DD
+	Mode         string  `json:"mode,omitempty"`
+	Balance      float64 `json:"balance,omitempty"`
+}
+
+var (
+	amountRe  = regexp.MustCompile(`(?i)(?:rs\.?|inr|₹)\s*([0-9][0-9,]*(?:\.[0-9]{1,2})?)`)
+	balanceRe = regexp.MustCompile(`(?i)(?:avl\.?|avail(?:able)?|total)\s*(?:bal(?:ance)?|lmt|limit)[^0-9]{0,12}([0-9][0-9,]*(?:\.[0-9]{1,2})?)`)
+
+	debitWords  = regexp.MustCompile(`(?i)\b(debited|spent|sent|paid|withdrawn|purchase|payment of|transferred|txn of|used)\b`)
+	creditWords = regexp.MustCompile(`(?i)\b(credited|received|deposited|refund(?:ed)?|reversed|cashback)\b`)
+	skipWords   = regexp.MustCompile(`(?i)\b(otp|one time password|verification code|do not share|offer|pre-approved|eligible for|apply now|will be debited|due on|is due|payment due|requested money|collect request)\b`)
+
+	accountRe = regexp.MustCompile(`(?i)(?:a/c|acct|account|ac|card)(?:\s*no\.?)?[\s:]*(?:ending(?:\s*with)?\s*|no\.?\s*)?[x*\-]*\s*([0-9]{3,6})\b`)
+	atmRe     = regexp.MustCompile(`(?i)\bATM\b`)
+	vpaRe     = regexp.MustCompile(`(?i)\b([a-z0-9][a-z0-9._\-]{1,}@[a-z][a-z0-9]{1,})\b`)
+	refRe     = regexp.MustCompile(`(?i)(?:upi\s*ref(?:\.|erence)?(?:\s*no\.?)?|ref(?:erence)?(?:\s*no\.?|\s*number)?|utr(?:\s*no\.?)?|rrn|txn\s*id|imps\s*ref|upi)[\s:#.-]*([0-9]{6,})`)
+
+	merchantRes = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\bto\s+vpa\s*\(([A-Za-z][A-Za-z0-9 &.'-]{1,40})\)`),
+		regexp.MustCompile(`(?i)\b(?:at|@)\s+([A-Za-z0-9][A-Za-z0-9 &*.'-]{1,40}?)(?:\s+on\b|\s+via\b|\s+from\b|\.\s|,|;|\s+avl|\s+ref|$)`),
+		regexp.MustCompile(`(?i);\s*([A-Za-z][A-Za-z0-9 &.'-]{1,40}?)\s+credited\b`),
+		regexp.MustCompile(`(?i)\b(?:info|towards)[:\s]+(?:upi/)?([A-Za-z][A-Za-z0-9 &.'-]{1,40}?)(?:/|\.|,|;|\s+on\b|$)`),
+		regexp.MustCompile(`(?i)\bto\s+([A-Za-z][A-Za-z &.'-]{1,40}?)(?:\s+on\b|\s+ref\b|\.|,|;|$)`),
+		regexp.MustCompile(`(?i)\b(?:from|by)\s+(?:neft|imps|rtgs|upi)?[\s-]*(?:from\s+)?([A-Za-z][A-Za-z &.'-]{1,40}?)(?:\s+on\b|\s+ref\b|\.|,|;|$)`),
+	}
+
+	dateRes = []struct {
+		re      *regexp.Regexp
+		layouts []string
+	}{
+		{regexp.MustCompile(`\b(\d{4}-\d{2}-\d{2})\b`), []string{"2006-01-02"}},
+		{regexp.MustCompile(`(?i)\b(\d{1,2}[-/ ][A-Za-z]{3}[-/ ]\d{2,4})\b`), []string{"02-Jan-06", "2-Jan-06", "02-Jan-2006", "2-Jan-2006", "02/Jan/06", "02 Jan 06", "02 Jan 2006", "2 Jan 2006"}},
+		{regexp.MustCompile(`\b(\d{1,2}[-/.]\d{1,2}[-/.]\d{2,4})\b`), []string{"02-01-06", "02-01-2006", "02/01/06", "02/01/2006", "2/1/06", "2/1/2006", "02.01.2006", "02.01.06"}},
+		{regexp.MustCompile(`(?i)\b(\d{1,2}[A-Za-z]{3}\d{2,4})\b`), []string{"02Jan06", "02Jan2006"}},
+	}
+
+	// Words that are never a merchant even if a pattern captures them.
+	notMerchant = map[string]bool{
+		"your": true, "you": true, "a/c": true, "ac": true, "account": true, "your a": true,
+		"avl bal": true, "the": true, "beneficiary": true, "mobile": true, "card": true, "vpa": true,
+	}
+)
+
+// Parse extracts a transaction from an alert SMS. received is used for the
+// date when the message does not carry one, and to reject dates that parse
+// into the future.
+func Parse(text string, received time.Time) (*Message, error) {
+	body := strings.Join(strings.Fields(text), " ")
+	if skipWords.MatchString(body) {
+		return nil, ErrNotTransaction
+	}
+
+	debitIdx := debitWords.FindStringIndex(body)
+	creditIdx := creditWords.FindStringIndex(body)
+	if debitIdx == nil && creditIdx == nil {
+		return nil, ErrNotTransaction
+	}
+
+	msg := &Message{Currency: "INR"}
+	// The first direction word decides: "debited ... ZOMATO credited" is a debit.
+	if debitIdx != nil && (creditIdx == nil || debitIdx[0] < creditIdx[0]) {
+		msg.Direction = "debit"
+	} else {
+		msg.Direction = "credit"
+	}
+
+	balanceSpans := balanceRe.FindAllStringSubmatchIndex(body, -1)
+	for _, m := range amountRe.FindAllStringSubmatchIndex(body, -1) {
+		if insideAny(m[0], balanceSpans) {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.ReplaceAll(body[m[2]:m[3]], ",", ""), 64)
+		if err == nil && v > 0 {
+			msg.Amount = v
+			break
+		}
+	}
+	if msg.Amount == 0 {
+		return nil, ErrNotTransaction
+	}
+	if len(balanceSpans) > 0 {
+		b := balanceSpans[0]
+		msg.Balance, _ = strconv.ParseFloat(strings.ReplaceAll(body[b[2]:b[3]], ",", ""), 64)
+	}
+
+	if m := accountRe.FindStringSubmatch(body); m != nil {
+		digits := m[1]
+		if len(digits) > 4 {
+			digits = digits[len(digits)-4:]
+		}
+		msg.AccountLast4 = digits
+	}
+
+	if m := vpaRe.FindStringSubmatch(body); m != nil && !strings.Contains(m[1], ".com") {
+		msg.VPA = strings.ToLower(m[1])
+	}
+
+	if m := refRe.FindStringSubmatch(body); m != nil {
+		msg.Reference = m[1]
+	}
+
+	// Drop UPI handles first so "from rahul.s@okicici" doesn't yield "rahul".
+	// Money paid to a handle usually went to a merchant named by it; money
+	// received from one came from a person, so the handle stays in VPA only.
+	msg.Merchant = findMerchant(vpaRe.ReplaceAllString(body, ""))
+	if msg.Merchant == "" && msg.VPA != "" && msg.Direction == "debit" {
+		msg.Merchant = msg.VPA[:strings.Index(msg.VPA, "@")]
+	}
+
+	msg.Mode = detectMode(body, msg)
+	msg.Date = findDate(body, received)
+	return msg, nil
+}
+
+func findMerchant(body string) string {
+	for _, re := range merchantRes {
+		m := re.FindStringSubmatch(body)
+		if m == nil {
+			continue
+		}
+		name := strings.Trim(strings.TrimSpace(m[1]), ".-*'")
+		lower := strings.ToLower(name)
+		if name == "" || notMerchant[lower] || strings.HasPrefix(lower, "your ") || strings.HasPrefix(lower, "a/c") {
+			continue
+		}
+		return name
+	}
+	return ""
+}
+
+// detectMode returns one of the entry schema's modes (Cash, UPI, Credit
+// Card, Wallets). An ATM withdrawal is cash in hand. The schema has no debit
+// card or bank transfer mode, so card and NEFT/IMPS/RTGS messages are left
+// without one rather than guessed.
+func detectMode(body string, msg *Message) string {
+	lower := strings.ToLower(body)
+	switch {
+	case msg.VPA != "" || strings.Contains(lower, "upi"):
+		return "UPI"
+	case atmRe.MatchString(body):
+		return "Cash"
+	case strings.Contains(lower, "credit card") || strings.Contains(lower, "avl lmt") || strings.Contains(lower, "avl limit"):
+		return "Credit Card"
+	case strings.Contains(lower, "wallet"):
+		return "Wallets"
+	}
+	return ""
+}
+
+func findDate(body string, received time.Time) string {
+	for _, d := range dateRes {
+		m := d.re.FindStringSubmatch(body)
+		if m == nil {
+			continue
+		}
+		raw := strings.NewReplacer("/", "-", " ", "-").Replace(m[1])
+		for _, layout := range d.layouts {
+			l := strings.NewReplacer("/", "-", " ", "-").Replace(layout)
+			if t, err := time.Parse(l, raw); err == nil {
+				// Guard against a mis-read day/month landing far in the future.
+				if !received.IsZero() && t.After(received.AddDate(0, 0, 2)) {
+					continue
+				}
+				return t.Format("2006-01-02")
+			}
+		}
+	}
+	if received.IsZero() {
+		received = time.Now()
+	}
+	return received.Format("2006-01-02")
+}
+
+func insideAny(pos int, spans [][]int) bool {
+	for _, s := range spans {
+		if pos >= s[0] && pos < s[1] {
+			return true
+		}
+	}
+	return false
+}
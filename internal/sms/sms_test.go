@@ -0,0 +1,70 @@
+package sms
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+type corpusCase struct {
+	Text       string    `json:"text"`
+	ReceivedAt time.Time `json:"received_at"`
+	Expected   *Message  `json:"expected"`
+}
+
+func TestParseCorpus(t *testing.T) {
+	data, err := os.ReadFile("testdata/corpus.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var corpus []corpusCase
+	if err := json.Unmarshal(data, &corpus); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tc := range corpus {
+		got, err := Parse(tc.Text, tc.ReceivedAt)
+		if tc.Expected == nil {
+			if !errors.Is(err, ErrNotTransaction) {
+				t.Errorf("case %d: got %+v, %v; want ErrNotTransaction\n%s", i, got, err, tc.Text)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %d: %v\n%s", i, err, tc.Text)
+			continue
+		}
+		want := *tc.Expected
+		if want.Currency == "" {
+			want.Currency = "INR"
+		}
+		if *got != want {
+			t.Errorf("case %d:\n got  %+v\n want %+v\n%s", i, *got, want, tc.Text)
+		}
+	}
+}
+
+func TestDetectModeInSchema(t *testing.T) {
+	// Channels the entry schema has no mode for come back empty.
+	for _, tt := range []struct{ text, mode string }{
+		{"Rs.2,000.00 withdrawn at ATM from your Debit Card ending 5678", "Cash"},
+		{"Rs 85,000.00 credited by NEFT from ACME", ""},
+		{"Rs 5,000.00 debited from A/c XX1234 by IMPS to RAHUL", ""},
+		{"Rs 100 paid from your Paytm wallet", "Wallets"},
+		{"Rs 100 spent on your card XX1234", ""},
+		{"Rs 100 spent on your Debit Card XX1234 at treatment centre", ""},
+		{"Rs 100 spent on your Credit Card XX1234 at DMART", "Credit Card"},
+		{"Rs 100 debited via UPI to DMART", "UPI"},
+	} {
+		msg, err := Parse(tt.text, time.Time{})
+		if err != nil {
+			t.Errorf("%q: %v", tt.text, err)
+			continue
+		}
+		if msg.Mode != tt.mode {
+			t.Errorf("%q: mode %q, want %q", tt.text, msg.Mode, tt.mode)
+		}
+	}
+}